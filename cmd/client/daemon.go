@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/milktea736/upload-http/pkg/client"
+)
+
+// daemonLabel identifies the background queue daemon to the host's
+// service manager.
+const daemonLabel = "com.milktea736.upload-http.client"
+
+// runDaemon implements `client daemon install|uninstall`, registering the
+// queue-draining daemon with the platform service manager (launchd on
+// macOS, a Windows service, a systemd user unit on Linux).
+func runDaemon(cfg client.ClientConfig, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: client daemon <install|uninstall>")
+	}
+	switch args[0] {
+	case "install":
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		logPath, err := daemonLogPath()
+		if err != nil {
+			return err
+		}
+		if err := installDaemon(exe, logPath); err != nil {
+			return err
+		}
+		fmt.Printf("installed %s (logs: %s)\n", daemonLabel, logPath)
+		return nil
+	case "uninstall":
+		if err := uninstallDaemon(); err != nil {
+			return err
+		}
+		fmt.Printf("uninstalled %s\n", daemonLabel)
+		return nil
+	default:
+		return fmt.Errorf("unknown daemon subcommand %q", args[0])
+	}
+}
+
+// daemonLogPath returns (and creates the directory for) the daemon's log
+// file location.
+func daemonLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".upload-http")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.log"), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// systemd user unit management for `client daemon install/uninstall`.
+
+func unitPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "systemd", "user", daemonLabel+".service"), nil
+}
+
+func installDaemon(exe, logPath string) error {
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	unit := fmt.Sprintf(`[Unit]
+Description=upload-http client daemon
+
+[Service]
+ExecStart=%s queue run
+Restart=always
+StandardOutput=append:%s
+StandardError=append:%s
+
+[Install]
+WantedBy=default.target
+`, exe, logPath, logPath)
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		return err
+	}
+	if out, err := exec.Command("systemctl", "--user", "enable", "--now", daemonLabel).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl enable: %v: %s", err, out)
+	}
+	return nil
+}
+
+func uninstallDaemon() error {
+	if out, err := exec.Command("systemctl", "--user", "disable", "--now", daemonLabel).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl disable: %v: %s", err, out)
+	}
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !windows && !linux
+
+package main
+
+import "fmt"
+
+func installDaemon(exe, logPath string) error {
+	return fmt.Errorf("daemon install is not supported on this platform")
+}
+
+func uninstallDaemon() error {
+	return fmt.Errorf("daemon uninstall is not supported on this platform")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Windows service management for `client daemon install/uninstall`,
+// implemented with sc.exe so no extra dependencies are needed.
+
+func installDaemon(exe, logPath string) error {
+	binPath := fmt.Sprintf(`"%s" queue run`, exe)
+	if out, err := exec.Command("sc", "create", daemonLabel,
+		"binPath=", binPath, "start=", "auto",
+		"DisplayName=", "upload-http client daemon").CombinedOutput(); err != nil {
+		return fmt.Errorf("sc create: %v: %s", err, out)
+	}
+	if out, err := exec.Command("sc", "start", daemonLabel).CombinedOutput(); err != nil {
+		return fmt.Errorf("sc start: %v: %s", err, out)
+	}
+	return nil
+}
+
+func uninstallDaemon() error {
+	// Stop failures are ignored: the service may simply not be running.
+	exec.Command("sc", "stop", daemonLabel).Run()
+	if out, err := exec.Command("sc", "delete", daemonLabel).CombinedOutput(); err != nil {
+		return fmt.Errorf("sc delete: %v: %s", err, out)
+	}
+	return nil
+}
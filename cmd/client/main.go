@@ -0,0 +1,1179 @@
+// Command client is the upload-http CLI.
+//
+// Remote arguments accept either a full server URL
+// (http://host:8080/remote/path) or an rclone-style profile spec
+// (profile:remote/path) resolved against the profiles in the config
+// file.
+//
+// Usage:
+//
+//	client upload <local-folder> <remote>
+//	client download <remote> <local-path>
+//	client list <remote> [remote-path]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/i18n"
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/client"
+	"github.com/milktea736/upload-http/pkg/config"
+)
+
+func main() {
+	i18n.Set(i18n.FromEnv())
+	flags := flag.NewFlagSet("client", flag.ExitOnError)
+	var (
+		configPath  = flags.String("config", defaultConfigPath(), "path to config file (.json, .yaml or .toml)")
+		debug       = flags.Bool("debug", false, "enable debug logging")
+		token       = flags.String("token", "", "bearer token for authenticated servers")
+		traceFile   = flags.String("trace", "", "append a redacted dump of every HTTP exchange to this file")
+		traceBodies = flags.Bool("trace-bodies", false, "include text bodies (capped) in -trace output")
+		localTime   = flags.Bool("local", false, "render timestamps in local time instead of UTC")
+		limitRate   = flags.String("limit-rate", "", "cap transfer bandwidth, e.g. 10MB/s")
+		progress    = flags.String("progress", "", "progress style: auto (redraw in place) or plain (line per interval)")
+		output      = flags.String("output", "", "output format: text (default), json, or ndjson")
+		profile     = flags.String("profile", "", "run against this named profile from the config")
+		timeout     = flags.String("timeout", "", "bound metadata calls (list, stat, rm...) by this duration, e.g. 10s")
+	)
+	flags.Usage = usage
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	// config only has its own flags and needs no loaded configuration;
+	// dispatch before the global parse so -generate is not mistaken for
+	// a global flag.
+	if command == "config" {
+		if err := runConfigCmd(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	flags.Parse(os.Args[2:])
+	args := flags.Args()
+
+	var cfg client.ClientConfig
+	if *configPath != "" {
+		if _, err := os.Stat(*configPath); err == nil {
+			if err := config.Load(*configPath, &cfg); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if *debug {
+		cfg.Debug = true
+	}
+	if *token != "" {
+		cfg.Token = *token
+	}
+	if *traceFile != "" {
+		cfg.TraceFile = *traceFile
+	}
+	if *traceBodies {
+		cfg.TraceBodies = true
+	}
+	if *localTime {
+		cfg.LocalTime = true
+	}
+	if *limitRate != "" {
+		cfg.LimitRate = *limitRate
+	}
+	if *progress != "" {
+		cfg.Progress = *progress
+	}
+	if *output != "" {
+		cfg.Output = *output
+	}
+	if *timeout != "" {
+		cfg.MetaTimeout = *timeout
+	}
+	if cfg.Locale != "" {
+		i18n.Set(cfg.Locale)
+	}
+	// -profile (or default_profile in the config) points the whole
+	// invocation at one named server; per-argument remote specs still
+	// override it.
+	if name := *profile; name != "" || cfg.DefaultProfile != "" {
+		if name == "" {
+			name = cfg.DefaultProfile
+		}
+		c, err := client.UseProfile(cfg, name)
+		if err != nil {
+			fatal(err)
+		}
+		cfg = c
+	}
+	// Folder uploads checkpoint to a journal by default so an
+	// interrupted run resumes instead of starting over; journal_dir
+	// "off" opts out.
+	if cfg.JournalDir == "" {
+		if dir, err := client.DefaultJournalDir(); err == nil {
+			cfg.JournalDir = dir
+		}
+	}
+
+	var err error
+	switch command {
+	case "upload":
+		err = runUpload(cfg, args)
+	case "download":
+		err = runDownload(cfg, args)
+	case "list":
+		err = runList(cfg, args)
+	case "cat":
+		err = runCat(cfg, args)
+	case "health":
+		err = runHealth(cfg, args)
+	case "quota":
+		err = runQuota(cfg, args)
+	case "versions":
+		err = runVersions(cfg, args)
+	case "restore":
+		err = runRestore(cfg, args)
+	case "queue":
+		err = runQueue(cfg, args)
+	case "sync":
+		err = runSync(cfg, args)
+	case "bisync":
+		err = runBiSync(cfg, args)
+	case "watch":
+		err = runWatch(cfg, args)
+	case "diff":
+		err = runDiff(cfg, args)
+	case "daemon":
+		err = runDaemon(cfg, args)
+	case "rm":
+		err = runRm(cfg, args)
+	case "delete":
+		err = runDelete(cfg, args)
+	case "mv", "cp":
+		err = runFileOp(cfg, command, args)
+	case "verify-receipt":
+		err = runVerifyReceipt(cfg, args)
+	case "transfers":
+		err = runTransfers(cfg, args)
+	case "cancel", "pause", "resume":
+		err = runTransferAction(cfg, command, args)
+	case "status":
+		err = runStatus(cfg, args)
+	case "admin":
+		err = runAdmin(cfg, args)
+	default:
+		fmt.Fprintf(os.Stderr, i18n.T("unknown command %q\n"), command)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// globList collects repeatable -include/-exclude pattern flags.
+type globList []string
+
+func (g *globList) String() string     { return fmt.Sprint([]string(*g)) }
+func (g *globList) Set(v string) error { *g = append(*g, v); return nil }
+
+func runUpload(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("upload", flag.ExitOnError)
+	var include, exclude globList
+	flags.Var(&include, "include", "only upload files matching this glob (repeatable)")
+	flags.Var(&exclude, "exclude", "skip files matching this glob (repeatable)")
+	replace := flags.Bool("replace", false, "replace the remote folder wholesale instead of merging into it")
+	yes := flags.Bool("yes", false, "skip the -replace confirmation prompt")
+	template := flags.String("template", "", "apply the named upload template from the config")
+	flags.Parse(args)
+	rest := flags.Args()
+	opts := client.SyncOptions{Include: include, Exclude: exclude}
+	if *template != "" {
+		t, err := cfg.Template(*template)
+		if err != nil {
+			return err
+		}
+		opts = t.Options(include, exclude)
+		if t.Replace {
+			*replace = true
+		}
+		// The template's remote is the default destination; an explicit
+		// second argument still wins.
+		if len(rest) == 1 && t.Remote != "" {
+			rest = append(rest, t.Remote)
+		}
+	}
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client upload [-template name] [-include glob] [-exclude glob] [-replace [-yes]] <local-path> <remote>")
+	}
+	local := rest[0]
+	cfg, prefix, err := client.ResolveRemote(cfg, rest[1])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(local)
+	if err != nil {
+		return err
+	}
+	remote := path.Join(prefix, filepath.Base(local))
+	if *replace {
+		if !fi.IsDir() {
+			return fmt.Errorf("-replace applies to folder uploads")
+		}
+		// Replacing deletes remote files not present locally, so an
+		// existing destination needs an explicit go-ahead.
+		if _, err := c.Stat(remote, false); err == nil && !*yes {
+			if !confirm(fmt.Sprintf(i18n.T("replace %s on the server, deleting files not in %s?"), remote, local)) {
+				return fmt.Errorf(i18n.T("aborted"))
+			}
+		}
+		return c.UploadFolderReplace(local, remote, opts)
+	}
+	if fi.IsDir() {
+		return c.UploadFolder(local, remote, opts)
+	}
+	_, err = c.UploadFile(local, remote)
+	return err
+}
+
+// confirm asks an interactive yes/no question and defaults to no.
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, i18n.T("%s [y/N]: "), prompt)
+	var answer string
+	fmt.Scanln(&answer)
+	return answer == "y" || answer == "Y" || answer == "yes"
+}
+
+func runDownload(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("download", flag.ExitOnError)
+	connections := flags.Int("connections", 0, "fetch large files in this many concurrent ranged segments")
+	perFile := flags.Bool("files", false, "download a folder file by file (resumable) instead of one archive stream")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client download [-connections n] [-files] <remote> <local-path>")
+	}
+	args = rest
+	if *connections > 0 {
+		cfg.Connections = *connections
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	// Try the folder form first; a plain file download is the fallback
+	// because directories are served as archives.
+	if *perFile {
+		if err := c.DownloadFolderFiles(remote, args[1]); err == nil {
+			return nil
+		}
+	} else if err := c.DownloadFolder(remote, args[1]); err == nil {
+		return nil
+	}
+	return c.DownloadFile(remote, filepath.Join(args[1], filepath.Base(remote)))
+}
+
+func runList(cfg client.ClientConfig, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: client list <remote> [remote-path]")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		remote = args[1]
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	files, err := c.ListFiles(remote)
+	if err != nil {
+		return err
+	}
+	if cfg.Output == "json" || cfg.Output == "ndjson" {
+		return emitJSON(cfg.Output, files)
+	}
+	for _, f := range files {
+		kind := "file"
+		if f.IsDir {
+			kind = "dir "
+		}
+		fmt.Printf("%s %10d  %s  %s\n", kind, f.Size, formatTime(cfg, f.ModTime), f.Path)
+	}
+	return nil
+}
+
+func runCat(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("cat", flag.ExitOnError)
+	head := flags.Int64("head", 0, "print only the first N bytes")
+	tail := flags.Int64("tail", 0, "print only the last N bytes")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: client cat [-head N | -tail N] <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	return c.Cat(remote, os.Stdout, *head, *tail)
+}
+
+// runVersions lists the archived versions of a remote file.
+func runVersions(cfg client.ClientConfig, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: client versions <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	versions, err := c.Versions(remote)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Printf(i18n.T("no archived versions of %s\n"), remote)
+		return nil
+	}
+	for _, v := range versions {
+		fmt.Printf("v%-4d %10d  %s\n", v.Version, v.Size, formatTime(cfg, v.ModTime))
+	}
+	return nil
+}
+
+// runRestore puts an archived version back as a remote file's current
+// content.
+func runRestore(cfg client.ClientConfig, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: client restore <remote> <version>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(args[1], "v"))
+	if err != nil {
+		return fmt.Errorf("invalid version %q", args[1])
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	return c.RestoreVersion(remote, version)
+}
+
+// runQuota prints usage against the quota of the remote path's
+// top-level directory; unlimited dimensions print as such.
+func runHealth(cfg client.ClientConfig, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: client health [remote]")
+	}
+	if len(args) == 1 {
+		var err error
+		cfg, _, err = client.ResolveRemote(cfg, args[0])
+		if err != nil {
+			return err
+		}
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	hi, err := c.Health()
+	if err != nil {
+		return err
+	}
+	if hi.FreeBytes > 0 {
+		fmt.Printf("%s: %s, %d bytes free\n", cfg.ServerURL, hi.Status, hi.FreeBytes)
+	} else {
+		fmt.Printf("%s: %s\n", cfg.ServerURL, hi.Status)
+	}
+	return nil
+}
+
+func runQuota(cfg client.ClientConfig, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: client quota <remote> [remote-path]")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		remote = args[1]
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	st, err := c.Quota(remote)
+	if err != nil {
+		return err
+	}
+	byteLimit, fileLimit := "unlimited", "unlimited"
+	if st.MaxBytes > 0 {
+		byteLimit = fmt.Sprintf("%d", st.MaxBytes)
+	}
+	if st.MaxFiles > 0 {
+		fileLimit = fmt.Sprintf("%d", st.MaxFiles)
+	}
+	fmt.Printf("%s: %d of %s bytes, %d of %s files\n", st.Path, st.Bytes, byteLimit, st.Files, fileLimit)
+	return nil
+}
+
+func runRm(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ExitOnError)
+	recursive := flags.Bool("r", false, "delete directories recursively")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: client rm [-r] <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	return c.Remove(remote, *recursive)
+}
+
+// bulkDeleteConfirmThreshold is the match count above which `client
+// delete` demands the count be typed back instead of a plain y/N, so a
+// pattern that matches far more than expected cannot be waved through.
+const bulkDeleteConfirmThreshold = 10
+
+// runDelete deletes every remote path matching a glob pattern in one
+// server-side batch. -dry-run prints the matches and stops; a real run
+// above the confirmation threshold requires typing the match count.
+func runDelete(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	recursive := flags.Bool("r", false, "delete matched directories recursively")
+	dryRun := flags.Bool("dry-run", false, "print what would be deleted without deleting")
+	yes := flags.Bool("yes", false, "skip the confirmation prompt")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: client delete [-r] [-dry-run] [-yes] <remote-pattern>")
+	}
+	cfg, pattern, err := client.ResolveRemote(cfg, rest[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	// The first call is always a dry run: it is both the -dry-run output
+	// and the match list the confirmation shows.
+	br, err := c.BulkDelete(pattern, *recursive, true)
+	if err != nil {
+		return err
+	}
+	if len(br.Matched) == 0 {
+		fmt.Printf(i18n.T("nothing matches %s\n"), pattern)
+		return nil
+	}
+	for _, m := range br.Matched {
+		fmt.Println(m)
+	}
+	if *dryRun {
+		return nil
+	}
+	if !*yes {
+		n := len(br.Matched)
+		if n > bulkDeleteConfirmThreshold {
+			fmt.Fprintf(os.Stderr, i18n.T("about to delete %d matches; type the number to confirm: "), n)
+			var answer string
+			fmt.Scanln(&answer)
+			if answer != strconv.Itoa(n) {
+				return fmt.Errorf(i18n.T("aborted"))
+			}
+		} else if !confirm(fmt.Sprintf(i18n.T("delete these %d matches of %s?"), n, pattern)) {
+			return fmt.Errorf(i18n.T("aborted"))
+		}
+	}
+	br, err = c.BulkDelete(pattern, *recursive, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.T("deleted %d of %d matches\n"), br.Deleted, len(br.Matched))
+	return nil
+}
+
+// runFileOp handles mv and cp, which run entirely on the server: the
+// first argument names the source remote, the second is the destination
+// path on the same server.
+func runFileOp(cfg client.ClientConfig, op string, args []string) error {
+	flags := flag.NewFlagSet(op, flag.ExitOnError)
+	recursive := flags.Bool("r", false, "copy directories recursively (cp)")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client %s <remote-from> <to-path>", op)
+	}
+	cfg, from, err := client.ResolveRemote(cfg, rest[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	if op == "mv" {
+		return c.Move(from, rest[1])
+	}
+	return c.Copy(from, rest[1], *recursive)
+}
+
+func runSync(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	del := flags.Bool("delete", false, "remove remote files that no longer exist locally")
+	all := flags.Bool("all", false, "run every sync mapping from the config file")
+	flags.Parse(args)
+	rest := flags.Args()
+	if *all {
+		if len(cfg.SyncMappings) == 0 {
+			return fmt.Errorf("no sync_mappings configured")
+		}
+		for _, m := range cfg.SyncMappings {
+			if err := runSyncMapping(cfg, m); err != nil {
+				return fmt.Errorf("mapping %s: %w", m.Name, err)
+			}
+		}
+		return nil
+	}
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client sync [-delete] <local-dir> <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[1])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	stats, err := c.Sync(rest[0], remote, client.SyncOptions{Delete: *del})
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.T("sync complete: %d uploaded, %d deleted, %d unchanged\n"),
+		stats.Uploaded, stats.Deleted, stats.Skipped)
+	return nil
+}
+
+// runBiSync reconciles a local directory and a remote one in both
+// directions, using the state file from the previous run to tell which
+// side changed.
+func runBiSync(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("bisync", flag.ExitOnError)
+	var include, exclude globList
+	flags.Var(&include, "include", "only sync files matching this glob (repeatable)")
+	flags.Var(&exclude, "exclude", "skip files matching this glob (repeatable)")
+	conflict := flags.String("conflict", "", "conflict strategy: newest (default), keep-both or abort")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client bisync [-conflict newest|keep-both|abort] <local-dir> <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[1])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	stats, err := c.BiSync(rest[0], remote, client.BiSyncOptions{
+		Include:  include,
+		Exclude:  exclude,
+		Conflict: *conflict,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.T("bisync complete: %d uploaded, %d downloaded, %d deleted, %d conflicts, %d unchanged\n"),
+		stats.Uploaded, stats.Downloaded, stats.Deleted, stats.Conflicts, stats.Skipped)
+	return nil
+}
+
+// runWatch keeps a directory mirrored onto the server until
+// interrupted, uploading files as they appear or change.
+func runWatch(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	var include, exclude globList
+	flags.Var(&include, "include", "only watch files matching this glob (repeatable)")
+	flags.Var(&exclude, "exclude", "skip files matching this glob (repeatable)")
+	interval := flags.Duration("interval", 0, "how often to rescan the tree (default 2s)")
+	debounce := flags.Duration("debounce", 0, "quiet period before a changed file uploads (default one interval)")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client watch [-include glob] [-exclude glob] <local-dir> <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[1])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = c.Watch(ctx, rest[0], remote, client.WatchOptions{
+		Include:  include,
+		Exclude:  exclude,
+		Interval: *interval,
+		Debounce: *debounce,
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// runDiff prints what sync would transfer: one line per difference,
+// prefixed with + (local only), - (remote only) or ~ (changed).
+func runDiff(cfg client.ClientConfig, args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	var include, exclude globList
+	flags.Var(&include, "include", "only compare files matching this glob (repeatable)")
+	flags.Var(&exclude, "exclude", "skip files matching this glob (repeatable)")
+	content := flags.Bool("content", false, "show unified diffs for changed text files")
+	flags.Parse(args)
+	rest := flags.Args()
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: client diff [-content] [-include glob] [-exclude glob] <local-dir> <remote>")
+	}
+	cfg, remote, err := client.ResolveRemote(cfg, rest[1])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	diffs, err := c.Diff(rest[0], remote, client.SyncOptions{Include: include, Exclude: exclude})
+	if err != nil {
+		return err
+	}
+	marks := map[string]string{
+		client.DiffAdded:   "+",
+		client.DiffRemoved: "-",
+		client.DiffChanged: "~",
+	}
+	for _, d := range diffs {
+		fmt.Printf("%s %s\n", marks[d.State], d.Path)
+		if *content && d.State == client.DiffChanged {
+			text, err := c.ContentDiff(rest[0], remote, d.Path)
+			if err != nil {
+				return err
+			}
+			fmt.Print(text)
+		}
+	}
+	return nil
+}
+
+// runSyncMapping executes one configured sync mapping in its declared
+// direction.
+func runSyncMapping(cfg client.ClientConfig, m client.SyncMapping) error {
+	if m.Server != "" {
+		cfg.ServerURL = m.Server
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	var stats *client.SyncStats
+	switch m.Direction {
+	case "", "upload":
+		stats, err = c.Sync(m.LocalPath, m.RemotePath, m.Options())
+	case "download":
+		stats, err = c.SyncDown(m.RemotePath, m.LocalPath, m.Options())
+	default:
+		return fmt.Errorf("unknown direction %q", m.Direction)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.T("%s: %d transferred, %d deleted, %d unchanged\n"),
+		m.Name, stats.Uploaded, stats.Deleted, stats.Skipped)
+	return nil
+}
+
+// runVerifyReceipt checks a stored upload receipt's signature, and with
+// a remote given also cross-checks the signing key against the server's
+// published one.
+func runVerifyReceipt(cfg client.ClientConfig, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: client verify-receipt <receipt.json> [<remote>]")
+	}
+	r, err := client.LoadReceipt(args[0])
+	if err != nil {
+		return err
+	}
+	if err := r.Verify(); err != nil {
+		return fmt.Errorf("receipt INVALID: %w", err)
+	}
+	if len(args) == 2 {
+		cfg, _, err := client.ResolveRemote(cfg, args[1])
+		if err != nil {
+			return err
+		}
+		c, err := client.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		key, err := c.ServerKey()
+		if err != nil {
+			return err
+		}
+		if key.KeyID != r.KeyID || key.PublicKey != r.PublicKey {
+			return fmt.Errorf("receipt signed by key %s, server currently uses %s", r.KeyID, key.KeyID)
+		}
+	}
+	fmt.Printf("receipt OK: %s (%d bytes, %s %s) signed by key %s at %s\n",
+		r.Path, r.Size, "sha256", r.Hash, r.KeyID, formatTime(cfg, r.Time))
+	return nil
+}
+
+func runTransfers(cfg client.ClientConfig, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: client transfers <remote>")
+	}
+	cfg, _, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	list, err := c.ListTransfers()
+	if err != nil {
+		return err
+	}
+	for _, t := range list {
+		line := fmt.Sprintf("%s  %-9s  %d/%d files  started %s",
+			t.ID, t.State, t.CompletedFiles, t.TotalFiles,
+			formatTime(cfg, t.StartedAt))
+		if t.Error != "" {
+			line += "  (" + t.Error + ")"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func runTransferAction(cfg client.ClientConfig, action string, args []string) error {
+	// `resume` with a single argument resumes an interrupted folder
+	// upload from its checkpoint journal instead of a server transfer.
+	if action == "resume" && len(args) == 1 {
+		return runResumeJournal(cfg, args[0])
+	}
+	if len(args) != 2 {
+		if action == "resume" {
+			return fmt.Errorf("usage: client resume <remote> <transfer-id> | client resume <journal-id>")
+		}
+		return fmt.Errorf("usage: client %s <remote> <transfer-id>", action)
+	}
+	cfg, _, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "cancel":
+		return c.CancelTransfer(args[1])
+	case "pause":
+		return c.PauseTransfer(args[1])
+	default:
+		return c.ResumeTransfer(args[1])
+	}
+}
+
+// runResumeJournal reruns the folder upload recorded in a checkpoint
+// journal; the journal makes the rerun skip every file an earlier run
+// already confirmed.
+func runResumeJournal(cfg client.ClientConfig, id string) error {
+	if cfg.JournalDir == "" || cfg.JournalDir == "off" {
+		return fmt.Errorf("upload journaling is disabled")
+	}
+	meta, err := client.LoadUploadJournal(cfg.JournalDir, id)
+	if err != nil {
+		return err
+	}
+	cfg.ServerURL = meta.Server
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	return c.UploadFolder(meta.LocalDir, meta.RemotePrefix, client.SyncOptions{})
+}
+
+// runStatus lists folder uploads that were interrupted locally, from
+// their checkpoint journals. A completed upload removes its journal, so
+// anything shown here can be picked up with `client resume`.
+func runStatus(cfg client.ClientConfig, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: client status")
+	}
+	if cfg.JournalDir == "" || cfg.JournalDir == "off" {
+		return fmt.Errorf("upload journaling is disabled")
+	}
+	list, err := client.ListUploadJournals(cfg.JournalDir)
+	if err != nil {
+		return err
+	}
+	if cfg.Output == "json" || cfg.Output == "ndjson" {
+		if list == nil {
+			list = []client.UploadJournalStatus{} // an empty array, not null
+		}
+		return emitJSON(cfg.Output, list)
+	}
+	if len(list) == 0 {
+		fmt.Println(i18n.T("no interrupted uploads"))
+		return nil
+	}
+	for _, st := range list {
+		// Journals written before totals were recorded only know what
+		// finished, not how much was left.
+		progress := fmt.Sprintf("%d files done", st.DoneFiles)
+		if st.TotalFiles > 0 {
+			progress = fmt.Sprintf("%d/%d files", st.DoneFiles, st.TotalFiles)
+		}
+		fmt.Printf("%s  %s -> %s  %s  started %s\n",
+			st.ID, st.LocalDir, st.RemotePrefix, progress, formatTime(cfg, st.StartedAt))
+	}
+	fmt.Println(i18n.T("resume one with: client resume <journal-id>"))
+	return nil
+}
+
+func runQueue(cfg client.ClientConfig, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: client queue <add|run|list|rm> ...")
+	}
+	dir, err := client.DefaultQueueDir()
+	if err != nil {
+		return err
+	}
+	q, err := client.OpenQueue(dir)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "add":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: client queue add <local-path> <remote>")
+		}
+		rcfg, remote, err := client.ResolveRemote(cfg, args[2])
+		if err != nil {
+			return err
+		}
+		if remote == "" {
+			remote = filepath.Base(args[1])
+		}
+		item, err := q.Add(args[1], remote, rcfg.ServerURL)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("queued %s (%s -> %s)\n", item.ID, item.LocalPath, args[2])
+		return nil
+	case "run":
+		once := len(args) > 1 && args[1] == "-once"
+		return q.Run(cfg, once)
+	case "list":
+		items, err := q.List()
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			line := fmt.Sprintf("%s  %-7s  attempts=%d  %s -> %s", it.ID, it.State, it.Attempts, it.LocalPath, it.RemotePath)
+			if it.LastError != "" {
+				line += "  (" + it.LastError + ")"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	case "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: client queue rm <id>")
+		}
+		return q.Remove(args[1])
+	default:
+		return fmt.Errorf("unknown queue subcommand %q", args[0])
+	}
+}
+
+// runAdmin drives the server's /admin surface: effective config,
+// transfer list/cancel, out-of-schedule job runs, read-only mode and
+// storage stats. Needs an admin token on servers with users configured.
+func runAdmin(cfg client.ClientConfig, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: client admin <remote> <config|transfers|cancel|run-job|readonly|stats> ...")
+	}
+	cfg, _, err := client.ResolveRemote(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	switch op, rest := args[1], args[2:]; op {
+	case "config":
+		raw, err := c.AdminConfig()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(raw)
+		return err
+	case "transfers":
+		list, err := c.AdminTransfers()
+		if err != nil {
+			return err
+		}
+		if cfg.Output == "json" || cfg.Output == "ndjson" {
+			return emitJSON(cfg.Output, list)
+		}
+		for _, t := range list {
+			line := fmt.Sprintf("%s  %-9s  %d/%d files  started %s",
+				t.ID, t.State, t.CompletedFiles, t.TotalFiles,
+				formatTime(cfg, t.StartedAt))
+			if t.Error != "" {
+				line += "  (" + t.Error + ")"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	case "cancel":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: client admin <remote> cancel <transfer-id>")
+		}
+		return c.AdminCancelTransfer(rest[0])
+	case "run-job":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: client admin <remote> run-job <name>")
+		}
+		st, err := c.AdminRunJob(rest[0])
+		if err != nil {
+			return err
+		}
+		if st.LastError != "" {
+			return fmt.Errorf("job %s failed: %s", st.Name, st.LastError)
+		}
+		fmt.Printf("job %s ran at %s (%d runs total)\n", st.Name, formatTime(cfg, st.LastRun), st.Runs)
+		return nil
+	case "readonly":
+		switch {
+		case len(rest) == 0:
+			ro, err := c.AdminReadOnly()
+			if err != nil {
+				return err
+			}
+			fmt.Println(readOnlyWord(ro))
+			return nil
+		case len(rest) == 1 && (rest[0] == "on" || rest[0] == "off"):
+			if err := c.AdminSetReadOnly(rest[0] == "on"); err != nil {
+				return err
+			}
+			fmt.Printf("read-only mode is now %s\n", rest[0])
+			return nil
+		default:
+			return fmt.Errorf("usage: client admin <remote> readonly [on|off]")
+		}
+	case "stats":
+		st, err := c.AdminStats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d files, %d bytes stored, %d bytes free, %d active transfers\n",
+			st.Files, st.Bytes, st.FreeBytes, st.ActiveTransfers)
+		return nil
+	default:
+		return fmt.Errorf("unknown admin subcommand %q", op)
+	}
+}
+
+func readOnlyWord(ro bool) string {
+	if ro {
+		return "read-only mode is on"
+	}
+	return "read-only mode is off"
+}
+
+// runConfigCmd generates a sample client config or validates an
+// existing one. For -generate, the format (json, yaml or toml) decides
+// the extension the file should be saved under; -validate reparses the
+// config strictly, so unknown keys and out-of-range values error
+// instead of silently falling back to defaults.
+func runConfigCmd(args []string) error {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	generate := flags.String("generate", "", "print a sample config: json, yaml or toml")
+	validate := flags.Bool("validate", false, "check the config file and exit")
+	configPath := flags.String("config", defaultConfigPath(), "path to config file (.json, .yaml or .toml)")
+	flags.Parse(args)
+	if *validate {
+		var cfg client.ClientConfig
+		if err := config.LoadStrict(*configPath, &cfg); err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", *configPath, err)
+		}
+		fmt.Printf("%s: %s\n", *configPath, i18n.T("configuration is valid"))
+		return nil
+	}
+	if *generate == "" || flags.NArg() != 0 {
+		return fmt.Errorf("usage: client config -generate <json|yaml|toml> | client config -validate [-config path]")
+	}
+	sample := client.ClientConfig{
+		ServerURL:       "http://localhost:8080",
+		ChunkSize:       client.DefaultChunkSize,
+		ParallelUploads: client.DefaultParallelUploads,
+		Retries:         client.DefaultRetries,
+		Profiles: map[string]client.Profile{
+			"prod": {ServerURL: "https://files.example.com", Token: "change-me"},
+		},
+	}
+	out, err := config.Generate(sample, *generate)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// emitJSON prints items machine-readably on stdout: mode "json" is one
+// indented JSON array, "ndjson" one compact object per line.
+func emitJSON[T any](mode string, items []T) error {
+	enc := json.NewEncoder(os.Stdout)
+	if mode == "json" {
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+	for _, it := range items {
+		if err := enc.Encode(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTime renders an API timestamp for display, in UTC unless -local
+// (or local_time in the config) was given.
+func formatTime(cfg client.ClientConfig, t time.Time) string {
+	if cfg.LocalTime {
+		return t.Local().Format("2006-01-02 15:04:05 MST")
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// fatal prints err and exits with a code reflecting the error class:
+// 1 for generic failures, 3 not found, 4 too large, 5 checksum
+// mismatch. Usage errors exit 2 via the flag package. Scripts can
+// branch on the class without parsing stderr.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "client:", err)
+	switch {
+	case errors.Is(err, api.ErrNotFound):
+		os.Exit(3)
+	case errors.Is(err, api.ErrTooLarge):
+		os.Exit(4)
+	case errors.Is(err, api.ErrChecksumMismatch):
+		os.Exit(5)
+	}
+	os.Exit(1)
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".upload-http-config.json")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, i18n.T(`usage: client <command> [flags] [args]
+
+Commands:
+  upload    upload a file or folder:  client upload [-template name] [-include glob] [-exclude glob] [-replace [-yes]] <local-path> <server-url>
+  download  download a file or folder: client download [-connections n] [-files] <server-url/remote-path> <local-path>
+  list      list remote files:        client list <server-url> [remote-path]
+  cat       print a remote file:      client cat [-head N | -tail N] <remote>
+  health    check server reachability: client health [remote]
+  quota     show usage vs. quota:     client quota <remote> [remote-path]
+  versions  list archived versions:   client versions <remote>
+  restore   restore a version:        client restore <remote> <version>
+  rm        delete remote files:      client rm [-r] <remote>
+  delete    bulk delete by pattern:   client delete [-r] [-dry-run] [-yes] '<remote-pattern>'
+  mv        rename on the server:     client mv <remote-from> <to-path>
+  cp        copy on the server:       client cp [-r] <remote-from> <to-path>
+  sync      one-way mirror:           client sync [-delete] <local-dir> <server-url/remote-dir>
+  bisync    two-way sync:             client bisync [-conflict newest|keep-both|abort] <local-dir> <remote>
+  watch     auto-upload on change:    client watch [-include glob] [-exclude glob] <local-dir> <remote>
+  diff      compare trees:            client diff <local-dir> <remote>
+  queue     durable upload queue:     client queue <add|run|list|rm> ...
+  verify-receipt  check a stored upload receipt: client verify-receipt <receipt.json> [<remote>]
+  daemon    manage background daemon: client daemon <install|uninstall>
+  transfers list server transfers:    client transfers <server-url>
+  cancel    cancel a transfer:        client cancel <server-url> <transfer-id>
+  pause     pause a transfer:         client pause <server-url> <transfer-id>
+  resume    resume a transfer:        client resume <server-url> <transfer-id> | <journal-id>
+  status    show interrupted uploads: client status
+  admin     server operations:        client admin <server-url> <config|transfers|cancel|run-job|readonly|stats> ...
+  config    generate or check config: client config -generate <json|yaml|toml> | -validate
+
+Flags:
+  -config path   config file (default %s)
+  -debug         enable debug logging
+  -token value   bearer token for authenticated servers
+  -trace path    append a redacted dump of every HTTP exchange to path
+  -trace-bodies  include text bodies (capped) in -trace output
+  -local         render timestamps in local time instead of UTC
+  -limit-rate r  cap transfer bandwidth, e.g. 10MB/s
+  -progress m    progress style: auto (redraw in place) or plain (line per interval)
+  -output m      output format: text (default), json, or ndjson
+  -profile p     run against this named profile from the config
+  -timeout d     bound metadata calls (list, stat, rm...) by this duration, e.g. 10s
+`), defaultConfigPath())
+}
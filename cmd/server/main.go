@@ -0,0 +1,96 @@
+// Command server runs the upload-http HTTP server.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/milktea736/upload-http/pkg/config"
+	"github.com/milktea736/upload-http/pkg/server"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "", "path to config file (.json, .yaml or .toml)")
+		port       = flag.Int("port", 0, "port to listen on (overrides config)")
+		dir        = flag.String("dir", "", "upload storage directory (overrides config)")
+		maxSize    = flag.String("max-size", "", "max upload file size, e.g. 100MB or 2GiB (overrides config)")
+		debug      = flag.Bool("debug", false, "enable debug logging")
+		generate   = flag.String("generate", "", "print a sample config in this format (json, yaml or toml) and exit")
+		validate   = flag.Bool("validate-config", false, "check the config file (types, ranges, unknown keys) and exit")
+	)
+	flag.Parse()
+
+	if *validate {
+		if *configPath == "" {
+			fatal(fmt.Errorf("-validate-config needs -config"))
+		}
+		var cfg server.ServerConfig
+		if err := config.LoadStrict(*configPath, &cfg); err != nil {
+			fatal(err)
+		}
+		if err := cfg.Validate(); err != nil {
+			fatal(fmt.Errorf("%s: %w", *configPath, err))
+		}
+		fmt.Println(*configPath, "is valid")
+		return
+	}
+
+	if *generate != "" {
+		sample := server.ServerConfig{
+			Port:        8080,
+			UploadDir:   "./uploads",
+			MaxFileSize: 100 << 20,
+		}
+		out, err := config.Generate(sample, *generate)
+		if err != nil {
+			fatal(err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	var cfg server.ServerConfig
+	if *configPath != "" {
+		if err := config.Load(*configPath, &cfg); err != nil {
+			fatal(err)
+		}
+	}
+	if *port != 0 {
+		cfg.Port = *port
+	}
+	if *dir != "" {
+		cfg.UploadDir = *dir
+	}
+	if *maxSize != "" {
+		n, err := config.ParseSize(*maxSize)
+		if err != nil {
+			fatal(err)
+		}
+		cfg.MaxFileSize = config.Bytes(n)
+	}
+	if *debug {
+		cfg.Debug = true
+	}
+
+	srv, err := server.NewServer(cfg)
+	if err != nil {
+		fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		var se *server.StartError
+		if errors.As(err, &se) {
+			fmt.Fprintln(os.Stderr, "server:", se)
+			fmt.Fprintln(os.Stderr, "hint:", se.Hint)
+			os.Exit(1)
+		}
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "server:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,3 @@
+module github.com/milktea736/upload-http
+
+go 1.21
@@ -0,0 +1,111 @@
+package i18n
+
+// catalogs holds the translations per locale, keyed by the English
+// source text. Only user-facing CLI strings belong here; log lines and
+// wire errors stay English so they remain searchable.
+var catalogs = map[string]map[string]string{
+	"zh-TW": {
+		"%s / %s  %s/s  ETA %s  %d/%d files":                                                    "%s / %s  %s/s  預計剩餘 %s  %d/%d 個檔案",
+		"%d%% done, %d/%d files, %s/s":                                                          "完成 %d%%，%d/%d 個檔案，%s/s",
+		"sync complete: %d uploaded, %d deleted, %d unchanged\n":                                "同步完成：上傳 %d 個，刪除 %d 個，未變更 %d 個\n",
+		"bisync complete: %d uploaded, %d downloaded, %d deleted, %d conflicts, %d unchanged\n": "雙向同步完成：上傳 %d 個，下載 %d 個，刪除 %d 個，衝突 %d 個，未變更 %d 個\n",
+		"%s: %d transferred, %d deleted, %d unchanged\n":                                        "%s：傳輸 %d 個，刪除 %d 個，未變更 %d 個\n",
+		"no archived versions of %s\n":                                                          "%s 沒有封存的版本\n",
+		"unknown command %q\n":                                                                  "未知的指令 %q\n",
+		"replace %s on the server, deleting files not in %s?":                                   "要取代伺服器上的 %s 並刪除 %s 中不存在的檔案嗎？",
+		"%s [y/N]: ":           "%s [y/N]：",
+		"aborted":              "已中止",
+		"nothing matches %s\n": "沒有符合 %s 的項目\n",
+		"about to delete %d matches; type the number to confirm: ": "即將刪除 %d 個符合項目；請輸入該數字以確認：",
+		"delete these %d matches of %s?":                           "要刪除這 %d 個符合 %s 的項目嗎？",
+		"deleted %d of %d matches\n":                               "已刪除 %d 個（共 %d 個符合項目）\n",
+		"no interrupted uploads":                                   "沒有中斷的上傳",
+		"configuration is valid":                                   "設定檔有效",
+		"resume one with: client resume <journal-id>":              "以 client resume <日誌編號> 繼續其中一項",
+		`usage: client <command> [flags] [args]
+
+Commands:
+  upload    upload a file or folder:  client upload [-template name] [-include glob] [-exclude glob] [-replace [-yes]] <local-path> <server-url>
+  download  download a file or folder: client download [-connections n] [-files] <server-url/remote-path> <local-path>
+  list      list remote files:        client list <server-url> [remote-path]
+  cat       print a remote file:      client cat [-head N | -tail N] <remote>
+  health    check server reachability: client health [remote]
+  quota     show usage vs. quota:     client quota <remote> [remote-path]
+  versions  list archived versions:   client versions <remote>
+  restore   restore a version:        client restore <remote> <version>
+  rm        delete remote files:      client rm [-r] <remote>
+  delete    bulk delete by pattern:   client delete [-r] [-dry-run] [-yes] '<remote-pattern>'
+  mv        rename on the server:     client mv <remote-from> <to-path>
+  cp        copy on the server:       client cp [-r] <remote-from> <to-path>
+  sync      one-way mirror:           client sync [-delete] <local-dir> <server-url/remote-dir>
+  bisync    two-way sync:             client bisync [-conflict newest|keep-both|abort] <local-dir> <remote>
+  watch     auto-upload on change:    client watch [-include glob] [-exclude glob] <local-dir> <remote>
+  diff      compare trees:            client diff <local-dir> <remote>
+  queue     durable upload queue:     client queue <add|run|list|rm> ...
+  verify-receipt  check a stored upload receipt: client verify-receipt <receipt.json> [<remote>]
+  daemon    manage background daemon: client daemon <install|uninstall>
+  transfers list server transfers:    client transfers <server-url>
+  cancel    cancel a transfer:        client cancel <server-url> <transfer-id>
+  pause     pause a transfer:         client pause <server-url> <transfer-id>
+  resume    resume a transfer:        client resume <server-url> <transfer-id> | <journal-id>
+  status    show interrupted uploads: client status
+  admin     server operations:        client admin <server-url> <config|transfers|cancel|run-job|readonly|stats> ...
+  config    generate or check config: client config -generate <json|yaml|toml> | -validate
+
+Flags:
+  -config path   config file (default %s)
+  -debug         enable debug logging
+  -token value   bearer token for authenticated servers
+  -trace path    append a redacted dump of every HTTP exchange to path
+  -trace-bodies  include text bodies (capped) in -trace output
+  -local         render timestamps in local time instead of UTC
+  -limit-rate r  cap transfer bandwidth, e.g. 10MB/s
+  -progress m    progress style: auto (redraw in place) or plain (line per interval)
+  -output m      output format: text (default), json, or ndjson
+  -profile p     run against this named profile from the config
+  -timeout d     bound metadata calls (list, stat, rm...) by this duration, e.g. 10s
+`: `用法：client <指令> [旗標] [參數]
+
+指令：
+  upload    上傳檔案或資料夾：  client upload [-template name] [-include glob] [-exclude glob] [-replace [-yes]] <本機路徑> <伺服器網址>
+  download  下載檔案或資料夾：  client download [-connections n] [-files] <伺服器網址/遠端路徑> <本機路徑>
+  list      列出遠端檔案：      client list <伺服器網址> [遠端路徑]
+  cat       顯示遠端檔案內容：  client cat [-head N | -tail N] <遠端路徑>
+  health    檢查伺服器連線：    client health [遠端]
+  quota     顯示用量與配額：    client quota <遠端> [遠端路徑]
+  versions  列出封存版本：      client versions <遠端>
+  restore   還原版本：          client restore <遠端> <版本>
+  rm        刪除遠端檔案：      client rm [-r] <遠端>
+  delete    依樣式批次刪除：    client delete [-r] [-dry-run] [-yes] '<遠端樣式>'
+  mv        在伺服器上改名：    client mv <遠端來源> <目的路徑>
+  cp        在伺服器上複製：    client cp [-r] <遠端來源> <目的路徑>
+  sync      單向鏡像：          client sync [-delete] <本機目錄> <伺服器網址/遠端目錄>
+  bisync    雙向同步：          client bisync [-conflict newest|keep-both|abort] <本機目錄> <遠端>
+  watch     變更時自動上傳：    client watch [-include glob] [-exclude glob] <本機目錄> <遠端>
+  diff      比較目錄樹：        client diff <本機目錄> <遠端>
+  queue     永續上傳佇列：      client queue <add|run|list|rm> ...
+  verify-receipt  驗證上傳收據：client verify-receipt <receipt.json> [<遠端>]
+  daemon    管理背景常駐程式：  client daemon <install|uninstall>
+  transfers 列出伺服器傳輸：    client transfers <伺服器網址>
+  cancel    取消傳輸：          client cancel <伺服器網址> <傳輸編號>
+  pause     暫停傳輸：          client pause <伺服器網址> <傳輸編號>
+  resume    繼續傳輸：          client resume <伺服器網址> <傳輸編號> | <日誌編號>
+  status    顯示中斷的上傳：    client status
+  admin     伺服器管理操作：    client admin <server-url> <config|transfers|cancel|run-job|readonly|stats> ...
+  config    產生或檢查設定檔：  client config -generate <json|yaml|toml> | -validate
+
+旗標：
+  -config path   設定檔（預設 %s）
+  -debug         啟用除錯記錄
+  -token value   認證伺服器用的 bearer token
+  -trace path    將每次 HTTP 往來的遮蔽紀錄附加到 path
+  -trace-bodies  在 -trace 輸出中納入文字內容（有上限）
+  -local         以本地時區而非 UTC 顯示時間
+  -limit-rate r  限制傳輸頻寬，例如 10MB/s
+  -progress m    進度樣式：auto（原地重繪）或 plain（每隔一段時間輸出一行）
+  -output m      輸出格式：text（預設）、json 或 ndjson
+  -profile p     以設定檔中此命名 profile 執行
+  -timeout d     以此時限限制中繼資料操作（list、stat、rm 等），例如 10s
+`,
+	},
+}
@@ -0,0 +1,55 @@
+// Package i18n translates the CLI's user-facing strings. The English
+// text doubles as the catalog key, so untranslated strings fall back to
+// themselves and call sites stay readable.
+package i18n
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// locale is the active catalog name; "en" means pass-through.
+var locale atomic.Value
+
+func init() { locale.Store("en") }
+
+// Set activates the named locale, e.g. "zh-TW" or "zh_TW.UTF-8";
+// unknown locales fall back to English.
+func Set(l string) { locale.Store(normalize(l)) }
+
+// FromEnv returns the locale the environment asks for, honouring the
+// usual LC_ALL > LC_MESSAGES > LANG precedence.
+func FromEnv() string {
+	for _, k := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// T returns the active locale's translation of msg, or msg itself when
+// there is none. Format verbs survive translation, so the result feeds
+// straight into fmt.
+func T(msg string) string {
+	l := locale.Load().(string)
+	if l == "en" {
+		return msg
+	}
+	if t, ok := catalogs[l][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// normalize maps environment-style locale names onto catalog names:
+// "zh_TW.UTF-8" selects zh-TW, anything without a catalog selects en.
+func normalize(l string) string {
+	l = strings.SplitN(l, ".", 2)[0]
+	l = strings.ReplaceAll(l, "_", "-")
+	if _, ok := catalogs[l]; ok {
+		return l
+	}
+	return "en"
+}
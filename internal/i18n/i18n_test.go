@@ -0,0 +1,33 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranslationAndFallback(t *testing.T) {
+	defer Set("en")
+
+	Set("zh_TW.UTF-8") // environment-style spelling selects zh-TW
+	if got := T("aborted"); got != "已中止" {
+		t.Errorf("zh-TW aborted = %q", got)
+	}
+	if got := T("not in any catalog"); got != "not in any catalog" {
+		t.Errorf("missing key = %q, want pass-through", got)
+	}
+
+	Set("fr") // no catalog falls back to English
+	if got := T("aborted"); got != "aborted" {
+		t.Errorf("unknown locale = %q, want English", got)
+	}
+}
+
+func TestFormatVerbsSurviveTranslation(t *testing.T) {
+	defer Set("en")
+	Set("zh-TW")
+	if got := T("no archived versions of %s\n"); got == "no archived versions of %s\n" {
+		t.Fatal("key not translated")
+	} else if !strings.Contains(got, "%s") {
+		t.Errorf("translation %q lost its format verb", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Package utils holds small helpers shared by the server and client that
+// are not part of the public API.
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Log formats accepted by NewLogger and the log_format config options.
+const (
+	LogText = "text"
+	LogJSON = "json"
+)
+
+// Logger is a thin leveled wrapper around log/slog. The *f methods keep
+// printf-style call sites working; With and the non-f methods carry
+// structured fields. Debug output is suppressed unless the logger was
+// created with debug enabled.
+type Logger struct {
+	sl    *slog.Logger
+	debug bool
+}
+
+// NewLogger returns a Logger writing to out in the given format (LogText
+// unless format is LogJSON). When debug is true, debug messages are
+// emitted as well.
+func NewLogger(out io.Writer, format string, debug bool) *Logger {
+	if out == nil {
+		out = os.Stderr
+	}
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if format == LogJSON {
+		h = slog.NewJSONHandler(out, opts)
+	} else {
+		h = slog.NewTextHandler(out, opts)
+	}
+	return &Logger{sl: slog.New(h), debug: debug}
+}
+
+// With returns a Logger that adds the given key/value pairs to every
+// record, for per-request or per-transfer context.
+func (lg *Logger) With(args ...any) *Logger {
+	return &Logger{sl: lg.sl.With(args...), debug: lg.debug}
+}
+
+// Info logs a message with structured key/value pairs.
+func (lg *Logger) Info(msg string, args ...any) { lg.sl.Info(msg, args...) }
+
+// Error logs an error message with structured key/value pairs.
+func (lg *Logger) Error(msg string, args ...any) { lg.sl.Error(msg, args...) }
+
+// Debug logs a debug message with structured key/value pairs.
+func (lg *Logger) Debug(msg string, args ...any) { lg.sl.Debug(msg, args...) }
+
+// Infof logs an informational message.
+func (lg *Logger) Infof(format string, args ...any) {
+	lg.sl.Info(fmt.Sprintf(format, args...))
+}
+
+// Errorf logs an error message.
+func (lg *Logger) Errorf(format string, args ...any) {
+	lg.sl.Error(fmt.Sprintf(format, args...))
+}
+
+// Debugf logs a debug message when debug logging is enabled.
+func (lg *Logger) Debugf(format string, args ...any) {
+	lg.sl.Debug(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token-bucket byte-rate limiter. The bucket holds up
+// to one second's worth of tokens so short bursts are not penalised,
+// and refills continuously at the configured rate. A nil *RateLimiter
+// never blocks, so callers can thread an optional limiter without
+// branching.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens (bytes) added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter capped at bytesPerSec, or nil when
+// bytesPerSec is not positive (unlimited).
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	r := float64(bytesPerSec)
+	return &RateLimiter{rate: r, burst: r, tokens: r, last: time.Now()}
+}
+
+// SetRate changes the limit at runtime; non-positive rates are
+// ignored. The burst follows the rate so the bucket keeps holding
+// about one second's worth of bytes.
+func (l *RateLimiter) SetRate(bytesPerSec int64) {
+	if l == nil || bytesPerSec <= 0 {
+		return
+	}
+	l.mu.Lock()
+	r := float64(bytesPerSec)
+	l.rate = r
+	l.burst = r
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.mu.Unlock()
+}
+
+// Rate returns the current limit in bytes per second; 0 for a nil
+// (unlimited) limiter.
+func (l *RateLimiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// Wait blocks until n bytes may pass. Requests larger than the bucket
+// are admitted once the bucket is full, accruing a deficit, so a single
+// oversized chunk cannot stall forever.
+func (l *RateLimiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// Reader wraps r so reads are throttled by the limiter. Safe to call on
+// a nil limiter, in which case r is returned unchanged.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+// Writer wraps w so writes are throttled by the limiter. Safe to call
+// on a nil limiter, in which case w is returned unchanged.
+func (l *RateLimiter) Writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &limitedWriter{w: w, l: l}
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *RateLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.l.Wait(n)
+	return n, err
+}
+
+type limitedWriter struct {
+	w io.Writer
+	l *RateLimiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.l.Wait(n)
+	return n, err
+}
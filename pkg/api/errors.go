@@ -0,0 +1,65 @@
+package api
+
+import "errors"
+
+// Error codes carried in ErrorResponse.Code. They classify failures
+// independently of the HTTP status so clients can react to the class
+// (retry, skip, abort) without parsing message text.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeUnauthorized        = "unauthorized"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodeUnsupportedMedia    = "unsupported_media"
+	CodeChecksumMismatch    = "checksum_mismatch"
+	CodeTooLarge            = "too_large"
+	CodeBusy                = "busy"
+	CodeInsufficientStorage = "insufficient_storage"
+	CodeQuotaExceeded       = "quota_exceeded"
+	CodeInternal            = "internal"
+)
+
+// Sentinel errors matching the error classes above. ErrorResponse
+// unwraps to them, so callers anywhere in a wrapped chain can test with
+// errors.Is regardless of how the response was transported.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrTooLarge         = errors.New("too large")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// ErrorResponse is the JSON body of every non-2xx API response: a
+// stable machine-readable code plus a human-readable message. Details
+// carries request-specific context such as the offending path. When the
+// failure is a configured limit being hit, Limit names the config key
+// (e.g. "max_file_size") and Value is its configured value, so tooling
+// can adapt instead of parsing the message.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+	Limit   string `json:"limit,omitempty"`
+	Value   int64  `json:"value,omitempty"`
+}
+
+func (e *ErrorResponse) Error() string {
+	if e.Details != "" {
+		return e.Message + ": " + e.Details
+	}
+	return e.Message
+}
+
+// Unwrap maps the code to its sentinel so errors.Is works on errors
+// built from a decoded response.
+func (e *ErrorResponse) Unwrap() error {
+	switch e.Code {
+	case CodeNotFound:
+		return ErrNotFound
+	case CodeTooLarge:
+		return ErrTooLarge
+	case CodeChecksumMismatch:
+		return ErrChecksumMismatch
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+// Package api defines the JSON types exchanged between the upload-http
+// server and its clients.
+//
+// All timestamps on the wire are RFC 3339 in UTC with nanosecond
+// precision (Go's time.Time JSON encoding); rendering in local time is a
+// client-side display concern only.
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// FileInfo describes one file or directory in a listing. Downloads and
+// LastAccess report how often and how recently the file was fetched,
+// for retention decisions; both are zero for never-downloaded files.
+type FileInfo struct {
+	Path       string    `json:"path"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+	IsDir      bool      `json:"is_dir"`
+	Hash       string    `json:"hash,omitempty"`
+	Downloads  int64     `json:"downloads,omitempty"`
+	LastAccess time.Time `json:"last_access,omitempty"`
+}
+
+// UploadResult describes the outcome for one file part of an upload.
+// Path is the caller's view of the file; StoredPath is the canonical
+// server-side path after cleaning and user namespacing, usable to
+// reference the file unambiguously later. Error is set when that part
+// failed to store; batched uploads continue past failed parts so
+// clients can retry only those, not the whole batch.
+type UploadResult struct {
+	Path       string   `json:"path"`
+	StoredPath string   `json:"stored_path,omitempty"`
+	Size       int64    `json:"size"`
+	Hash       string   `json:"hash,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Receipt    *Receipt `json:"receipt,omitempty"`
+}
+
+// UploadResponse is returned by POST /upload.
+type UploadResponse struct {
+	TransferID string         `json:"transfer_id"`
+	Files      []UploadResult `json:"files"`
+}
+
+// Trailers sent on /upload responses confirming what the server actually
+// committed to disk. Clients compare them against the bytes they sent so
+// that only confirmed data counts as transferred after a mid-stream
+// failure. CommitHashTrailer is only set for single-file uploads.
+const (
+	CommitSizeTrailer = "X-Commit-Size"
+	CommitHashTrailer = "X-Commit-Hash"
+)
+
+// FileHashHeader is set on each multipart file part with the sender's
+// checksum of that file. The server verifies the streamed content
+// against it and rejects the upload with 422 on mismatch. It is also
+// returned on single-file downloads so clients can verify what they
+// received.
+const FileHashHeader = "X-File-Hash"
+
+// ArchiveManifestName is the last member of a directory-download tar
+// stream: a JSON object mapping each file's slash-separated path to its
+// checksum, written after the files it describes so the server can hash
+// while streaming. Clients verify extracted files against it instead of
+// writing it to disk, giving folder downloads the same integrity check
+// as the FileHashHeader on single files.
+const ArchiveManifestName = ".upload-http.manifest.json"
+
+// ExpiresAfterHeader, set on an upload request (or the equivalent ttl
+// query parameter), gives every file it stores a time-to-live — a Go
+// duration string such as "90m" or plain seconds — after which the
+// server removes it, for drop-box style temporary sharing.
+const ExpiresAfterHeader = "X-Expires-After"
+
+// FileMtimeHeader is set on each multipart file part with the file's
+// original modification time in RFC 3339 format. The server applies it
+// to the stored file so round-tripped trees keep their timestamps;
+// downloads return the stored mtime via the standard Last-Modified
+// header.
+const FileMtimeHeader = "X-File-Mtime"
+
+// Receipt is a signed statement from the server that it stored exactly
+// the named bytes: an Ed25519 signature over the payload fields,
+// base64-encoded. PublicKey allows offline verification; GET /api/key
+// serves the server's current key for cross-checking the key ID.
+type Receipt struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Hash      string    `json:"hash"`
+	Time      time.Time `json:"time"`
+	KeyID     string    `json:"key_id"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
+}
+
+// Payload returns the canonical byte string the signature covers.
+func (r Receipt) Payload() []byte {
+	return []byte(fmt.Sprintf("%s\n%d\n%s\n%s\n%s",
+		r.Path, r.Size, r.Hash, r.Time.UTC().Format(time.RFC3339Nano), r.KeyID))
+}
+
+// Verify checks the receipt's signature against its embedded public
+// key. Trust in the key itself is established out of band (or via
+// GET /api/key).
+func (r Receipt) Verify() error {
+	pub, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), r.Payload(), sig) {
+		return fmt.Errorf("signature does not match receipt")
+	}
+	return nil
+}
+
+// KeyInfo is returned by GET /api/key.
+type KeyInfo struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// VersionInfo describes one archived version of a stored file, created
+// when versioning is enabled and the file was overwritten. ModTime is
+// when the version was superseded, not when its content was written.
+type VersionInfo struct {
+	Version int       `json:"version"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// TrashEntry describes one deleted file or directory held in the
+// server's trash, identified by an opaque id used to restore it.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	IsDir     bool      `json:"is_dir,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// QuotaStatus is returned by GET /api/quota: the current usage of one
+// quota scope (a top-level directory) alongside its configured limits.
+// Zero limits mean unlimited.
+type QuotaStatus struct {
+	Path     string `json:"path"`
+	Bytes    int64  `json:"bytes"`
+	Files    int64  `json:"files"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+	MaxFiles int64  `json:"max_files,omitempty"`
+}
+
+// Capabilities is returned by GET /api/capabilities: the server-side
+// limits a client should tune itself against before starting a bulk
+// transfer. Zero values mean unlimited. Clients clamp their own
+// parallelism to MaxParallelTransfers so a misconfigured setting
+// degrades gracefully instead of drawing 429s.
+type Capabilities struct {
+	MaxFileSize          int64 `json:"max_file_size,omitempty"`
+	MaxParallelTransfers int   `json:"max_parallel_transfers,omitempty"`
+	MaxRequestsPerSecond int   `json:"max_requests_per_second,omitempty"`
+}
+
+// JobStatus describes one background maintenance job, returned by
+// GET /api/jobs: how often it runs, when it last ran and whether that
+// run failed.
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Interval  string    `json:"interval"`
+	Runs      int64     `json:"runs"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	NextRun   time.Time `json:"next_run,omitempty"`
+}
+
+// StorageStats is the server-wide usage summary served by GET
+// /admin/stats: what is stored across the client-visible tree, what
+// disk remains and how many transfers are in flight.
+type StorageStats struct {
+	Files           int   `json:"files"`
+	Bytes           int64 `json:"bytes"`
+	FreeBytes       int64 `json:"free_bytes,omitempty"`
+	ActiveTransfers int   `json:"active_transfers"`
+}
+
+// CorruptFile is one file the bitrot scrubber flagged: its content no
+// longer hashes to the recorded checksum even though size and mtime are
+// unchanged. Quarantined is set when the file was moved out of the
+// storage tree.
+type CorruptFile struct {
+	Path         string    `json:"path"`
+	ExpectedHash string    `json:"expected_hash"`
+	ActualHash   string    `json:"actual_hash"`
+	DetectedAt   time.Time `json:"detected_at"`
+	Quarantined  bool      `json:"quarantined,omitempty"`
+}
+
+// ScrubReport is returned by GET /api/scrub: the outcome of the most
+// recent bitrot scrub.
+type ScrubReport struct {
+	LastRun time.Time     `json:"last_run,omitempty"`
+	Checked int64         `json:"checked"`
+	Corrupt []CorruptFile `json:"corrupt"`
+}
+
+// GrepMatch is one matching line from GET /api/grep.
+type GrepMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// GrepResult is returned by GET /api/grep. Truncated reports that the
+// server's match cap was hit and more matches may exist.
+type GrepResult struct {
+	Query     string      `json:"query"`
+	Matches   []GrepMatch `json:"matches"`
+	Scanned   int         `json:"scanned_files"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// FileOpRequest is the body of POST /api/files/move and
+// /api/files/copy. Recursive must be set to copy a directory.
+type FileOpRequest struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+// BatchDeleteRequest is the body of POST /api/files/delete-batch:
+// delete every path matching the glob pattern (path.Match semantics, so
+// "*" does not cross "/"). Matched directories need Recursive; DryRun
+// only reports what would match.
+type BatchDeleteRequest struct {
+	Pattern   string `json:"pattern"`
+	Recursive bool   `json:"recursive,omitempty"`
+	DryRun    bool   `json:"dry_run,omitempty"`
+}
+
+// BatchDeleteResponse lists what the pattern matched, sorted. Deleted is
+// how many matches were actually removed: zero on a dry run, and less
+// than the match count when some deletions failed.
+type BatchDeleteResponse struct {
+	Matched []string `json:"matched"`
+	Deleted int      `json:"deleted"`
+	DryRun  bool     `json:"dry_run,omitempty"`
+}
+
+// WebhookEvent is the JSON body POSTed to configured webhook endpoints.
+// Event is the journal operation for mutations ("create", "modify",
+// "delete"), "download" and "upload_failed" for transfer outcomes, or
+// "auth_ban" when an address is banned for repeated authentication
+// failures (Path then holds the client address). Error is only set on
+// failure events.
+type WebhookEvent struct {
+	Event string    `json:"event"`
+	Path  string    `json:"path,omitempty"`
+	Size  int64     `json:"size,omitempty"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Operations recorded in the change journal.
+const (
+	ChangeCreate = "create"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+)
+
+// ChangeEvent is one entry in the server's sequential change journal.
+type ChangeEvent struct {
+	Seq  uint64    `json:"seq"`
+	Op   string    `json:"op"`
+	Path string    `json:"path"`
+	Size int64     `json:"size,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// ChangeList is returned by GET /api/changes?cursor=: the events after
+// the cursor plus the cursor to resume from.
+type ChangeList struct {
+	Cursor uint64        `json:"cursor"`
+	Events []ChangeEvent `json:"events"`
+}
+
+// Transfer states reported in TransferStatus.
+const (
+	TransferRunning   = "running"
+	TransferPaused    = "paused"
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+	TransferCanceled  = "canceled"
+)
+
+// TransferStatus describes the progress of one upload or download handled
+// by the server, exposed via GET /api/status/{id}.
+type TransferStatus struct {
+	ID             string `json:"id"`
+	State          string `json:"state"`
+	TotalFiles     int    `json:"total_files"`
+	CompletedFiles int    `json:"completed_files"`
+	TotalBytes     int64  `json:"total_bytes"`
+	Bytes          int64  `json:"bytes"`
+	// WireBytes is the compressed size received on the wire for uploads
+	// sent with a Content-Encoding; zero for identity bodies.
+	WireBytes  int64     `json:"wire_bytes,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TransferSummary is the finalized record of one finished transfer,
+// served by GET /api/status/{id}/summary. Unlike the live status it
+// never changes once written: the totals, duration and per-file
+// results (paths, sizes, hashes, per-file errors) are frozen at the
+// moment the transfer ended, which makes it safe to archive in
+// external systems. A running transfer has no summary yet.
+type TransferSummary struct {
+	ID          string         `json:"id"`
+	State       string         `json:"state"`
+	Files       int            `json:"files"`
+	Bytes       int64          `json:"bytes"`
+	WireBytes   int64          `json:"wire_bytes,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  time.Time      `json:"finished_at"`
+	DurationMS  int64          `json:"duration_ms"`
+	FileResults []UploadResult `json:"file_results,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
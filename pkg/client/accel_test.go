@@ -0,0 +1,83 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRangedDownloadReassemblesFile(t *testing.T) {
+	var ranged int32
+	h := serverForTest(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/download/") && r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranged, 1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Connections: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Big enough for at least two segments, with varied content so a
+	// misplaced segment cannot slip past the hash check.
+	data := make([]byte, 9<<20)
+	for i := range data {
+		data[i] = byte(i*31 + i>>11)
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.bin")
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(src, "accel/big.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "out.bin")
+	if err := c.DownloadFile("accel/big.bin", dst); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("reassembled file differs from the original")
+	}
+	if n := atomic.LoadInt32(&ranged); n < 2 {
+		t.Errorf("saw %d ranged segment requests, want at least 2", n)
+	}
+}
+
+func TestRangedDownloadFallsBackForSmallFiles(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Connections: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "small.txt")
+	if err := os.WriteFile(src, []byte("one stream is plenty"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(src, "accel/small.txt"); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "small-out.txt")
+	if err := c.DownloadFile("accel/small.txt", dst); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "one stream is plenty" {
+		t.Errorf("downloaded %q", got)
+	}
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// The Admin* methods drive the server's /admin surface. They need an
+// admin token when the server has users configured; an open server
+// accepts them from anyone.
+
+// AdminConfig fetches the server's effective configuration with
+// credentials redacted, as raw JSON since the config schema belongs to
+// the server.
+func (c *Client) AdminConfig() (json.RawMessage, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/admin/config"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("admin config: %w", err)
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// AdminTransfers returns every transfer known to the server, newest
+// first.
+func (c *Client) AdminTransfers() ([]api.TransferStatus, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/admin/transfers"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("admin transfers: %w", err)
+	}
+	defer resp.Body.Close()
+	var list []api.TransferStatus
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode transfer list: %w", err)
+	}
+	return list, nil
+}
+
+// AdminCancelTransfer cancels the running transfer with the given ID.
+func (c *Client) AdminCancelTransfer(id string) error {
+	return c.transferAction(http.MethodDelete, "/admin/transfers/"+id)
+}
+
+// AdminRunJob runs the named maintenance job immediately and returns
+// its status including how the run went.
+func (c *Client) AdminRunJob(name string) (*api.JobStatus, error) {
+	v := url.Values{"name": {name}}
+	req, err := http.NewRequest(http.MethodPost, c.url("/admin/jobs/run")+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("run job %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	st := &api.JobStatus{}
+	return st, json.NewDecoder(resp.Body).Decode(st)
+}
+
+// AdminReadOnly reports whether the server is in read-only mode.
+func (c *Client) AdminReadOnly() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/admin/readonly"), nil)
+	if err != nil {
+		return false, err
+	}
+	return c.readOnlyResponse(req)
+}
+
+// AdminSetReadOnly turns read-only mode on or off.
+func (c *Client) AdminSetReadOnly(on bool) error {
+	body, err := json.Marshal(map[string]bool{"readonly": on})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url("/admin/readonly"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = c.readOnlyResponse(req)
+	return err
+}
+
+func (c *Client) readOnlyResponse(req *http.Request) (bool, error) {
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return false, fmt.Errorf("admin readonly: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		ReadOnly bool `json:"readonly"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, err
+	}
+	return body.ReadOnly, nil
+}
+
+// AdminStats returns the server-wide storage usage summary.
+func (c *Client) AdminStats() (*api.StorageStats, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/admin/stats"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("admin stats: %w", err)
+	}
+	defer resp.Body.Close()
+	st := &api.StorageStats{}
+	return st, json.NewDecoder(resp.Body).Decode(st)
+}
@@ -0,0 +1,335 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// syncStateFile is written in the root of a bidirectionally synced local
+// directory and records how every file looked after the last BiSync, so
+// the next run can tell which side changed. It is never synced itself.
+const syncStateFile = ".uploadsync.json"
+
+// Conflict strategies for BiSync: what to do when a file changed on both
+// sides since the last sync.
+const (
+	// ConflictNewest keeps whichever side has the later modification time.
+	ConflictNewest = "newest"
+	// ConflictKeepBoth keeps the local version under the original name
+	// and saves the remote version next to it as a conflict copy.
+	ConflictKeepBoth = "keep-both"
+	// ConflictAbort stops the sync with an error naming the file.
+	ConflictAbort = "abort"
+)
+
+// BiSyncOptions control Client.BiSync.
+type BiSyncOptions struct {
+	// Include and Exclude filter files as in SyncOptions.
+	Include []string
+	Exclude []string
+
+	// Conflict selects the conflict strategy; empty means ConflictNewest.
+	Conflict string
+}
+
+// BiSyncStats summarizes what a bidirectional sync did.
+type BiSyncStats struct {
+	Uploaded   int
+	Downloaded int
+	Deleted    int // deletions propagated, to either side
+	Conflicts  int
+	Skipped    int
+}
+
+// syncStateEntry is the recorded last-synced shape of one file, keyed in
+// the state file by slash-separated relative path.
+type syncStateEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// loadSyncState reads the state file in localDir; a missing file means a
+// first sync and yields an empty state.
+func loadSyncState(localDir string) (map[string]syncStateEntry, error) {
+	state := make(map[string]syncStateEntry)
+	data, err := os.ReadFile(filepath.Join(localDir, syncStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("corrupt sync state %s: %w", syncStateFile, err)
+	}
+	return state, nil
+}
+
+// saveSyncState writes the state file atomically.
+func saveSyncState(localDir string, state map[string]syncStateEntry) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(localDir, syncStateFile)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// BiSync synchronizes localDir and remoteDir in both directions. Changes
+// are detected against the state recorded by the previous run: a file
+// changed on one side only is copied to the other, a file deleted on one
+// side and untouched on the other is deleted there too, and a file
+// changed on both sides is a conflict resolved per opts.Conflict. The
+// first run, with no state yet, treats every file as new on its side.
+func (c *Client) BiSync(localDir, remoteDir string, opts BiSyncOptions) (*BiSyncStats, error) {
+	switch opts.Conflict {
+	case "", ConflictNewest, ConflictKeepBoth, ConflictAbort:
+	default:
+		return nil, fmt.Errorf("invalid conflict strategy %q (want newest, keep-both or abort)", opts.Conflict)
+	}
+
+	ignored, err := loadUploadIgnore(localDir)
+	if err != nil {
+		return nil, err
+	}
+	sel := SyncOptions{Include: opts.Include, Exclude: append(ignored, opts.Exclude...)}
+
+	state, err := loadSyncState(localDir)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := c.listRecursive(remoteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]os.FileInfo)
+	err = filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == syncStateFile || !sel.selected(rel) {
+			return nil
+		}
+		local[rel] = fi
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &BiSyncStats{}
+	for _, rel := range biSyncOrder(local, remote, state) {
+		if rf, ok := remote[rel]; ok && rf.IsDir {
+			continue
+		}
+		if !sel.selected(rel) {
+			continue
+		}
+		if err := c.biSyncFile(localDir, remoteDir, rel, local, remote, state, opts, stats); err != nil {
+			// Keep what this run already reconciled, even on abort.
+			saveSyncState(localDir, state)
+			return nil, err
+		}
+	}
+	if err := saveSyncState(localDir, state); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// biSyncFile reconciles one relative path between the two sides and
+// updates the state map in place.
+func (c *Client) biSyncFile(localDir, remoteDir, rel string, local map[string]os.FileInfo, remote map[string]api.FileInfo, state map[string]syncStateEntry, opts BiSyncOptions, stats *BiSyncStats) error {
+	localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+	remotePath := path.Join(remoteDir, rel)
+	fi, haveLocal := local[rel]
+	rf, haveRemote := remote[rel]
+	prev, known := state[rel]
+
+	switch {
+	case haveLocal && haveRemote:
+		localChanged := !known || c.localSideChanged(prev, localPath, fi)
+		remoteChanged := !known || remoteSideChanged(prev, rf)
+		switch {
+		case !localChanged && !remoteChanged:
+			stats.Skipped++
+			return nil
+		case localChanged && !remoteChanged:
+			if _, err := c.UploadFile(localPath, remotePath); err != nil {
+				return err
+			}
+			stats.Uploaded++
+		case remoteChanged && !localChanged:
+			if err := c.DownloadFile(remotePath, localPath); err != nil {
+				return err
+			}
+			stats.Downloaded++
+		default:
+			// Both sides changed. Identical content is no conflict, just
+			// a state entry to refresh.
+			if sameContent(localPath, rf) {
+				stats.Skipped++
+				break
+			}
+			if err := c.resolveConflict(localPath, remotePath, rel, fi, rf, opts, stats); err != nil {
+				return err
+			}
+		}
+		return recordSyncState(state, rel, localPath)
+
+	case haveLocal:
+		// Gone remotely. An unchanged local copy means the remote side
+		// deleted it; a changed one means the edit wins and re-uploads.
+		if known && !c.localSideChanged(prev, localPath, fi) {
+			if err := os.Remove(localPath); err != nil {
+				return err
+			}
+			delete(state, rel)
+			stats.Deleted++
+			return nil
+		}
+		if _, err := c.UploadFile(localPath, remotePath); err != nil {
+			return err
+		}
+		stats.Uploaded++
+		return recordSyncState(state, rel, localPath)
+
+	case haveRemote:
+		if known && !remoteSideChanged(prev, rf) {
+			if err := c.DeleteFile(remotePath); err != nil {
+				return err
+			}
+			delete(state, rel)
+			stats.Deleted++
+			return nil
+		}
+		if err := c.DownloadFile(remotePath, localPath); err != nil {
+			return err
+		}
+		stats.Downloaded++
+		return recordSyncState(state, rel, localPath)
+
+	default:
+		// Deleted on both sides since the last sync.
+		delete(state, rel)
+		return nil
+	}
+}
+
+// resolveConflict applies the configured strategy to a file changed on
+// both sides.
+func (c *Client) resolveConflict(localPath, remotePath, rel string, fi os.FileInfo, rf api.FileInfo, opts BiSyncOptions, stats *BiSyncStats) error {
+	stats.Conflicts++
+	switch opts.Conflict {
+	case ConflictAbort:
+		return fmt.Errorf("conflict: %s changed both locally and remotely", rel)
+	case ConflictKeepBoth:
+		// Park the remote version next to the local one; it uploads as a
+		// new file on the next run.
+		copyPath := localPath + ".conflict-" + rf.ModTime.UTC().Format("20060102-150405")
+		if err := c.DownloadFile(remotePath, copyPath); err != nil {
+			return err
+		}
+		stats.Downloaded++
+		if _, err := c.UploadFile(localPath, remotePath); err != nil {
+			return err
+		}
+		stats.Uploaded++
+		c.logger.Infof("conflict on %s: kept local version, saved remote as %s", rel, filepath.Base(copyPath))
+		return nil
+	default: // ConflictNewest
+		if fi.ModTime().After(rf.ModTime) {
+			if _, err := c.UploadFile(localPath, remotePath); err != nil {
+				return err
+			}
+			stats.Uploaded++
+			c.logger.Infof("conflict on %s: local version is newer, uploaded", rel)
+			return nil
+		}
+		if err := c.DownloadFile(remotePath, localPath); err != nil {
+			return err
+		}
+		stats.Downloaded++
+		c.logger.Infof("conflict on %s: remote version is newer, downloaded", rel)
+		return nil
+	}
+}
+
+// localSideChanged reports whether the local file differs from its
+// recorded state. Size and an exact mtime match settle it cheaply; a
+// moved timestamp falls back to the content hash so touch alone does not
+// count as an edit.
+func (c *Client) localSideChanged(prev syncStateEntry, localPath string, fi os.FileInfo) bool {
+	if fi.Size() != prev.Size {
+		return true
+	}
+	if fi.ModTime().Equal(prev.ModTime) {
+		return false
+	}
+	sum, err := hash.HashFile(localPath)
+	return err != nil || sum != prev.Hash
+}
+
+// remoteSideChanged reports whether the remote file differs from its
+// recorded state, by hash when the listing carries one.
+func remoteSideChanged(prev syncStateEntry, rf api.FileInfo) bool {
+	if rf.Hash != "" && prev.Hash != "" {
+		return rf.Hash != prev.Hash
+	}
+	return rf.Size != prev.Size
+}
+
+// recordSyncState stamps the state entry for rel from the local file as
+// it stands now, both sides having just been brought level.
+func recordSyncState(state map[string]syncStateEntry, rel, localPath string) error {
+	fi, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	sum, err := hash.HashFile(localPath)
+	if err != nil {
+		return err
+	}
+	state[rel] = syncStateEntry{Size: fi.Size(), ModTime: fi.ModTime(), Hash: sum}
+	return nil
+}
+
+// biSyncOrder returns every path known to either side or the state, in a
+// stable order.
+func biSyncOrder(local map[string]os.FileInfo, remote map[string]api.FileInfo, state map[string]syncStateEntry) []string {
+	seen := make(map[string]bool, len(local)+len(remote)+len(state))
+	for rel := range local {
+		seen[rel] = true
+	}
+	for rel := range remote {
+		seen[rel] = true
+	}
+	for rel := range state {
+		seen[rel] = true
+	}
+	order := make([]string, 0, len(seen))
+	for rel := range seen {
+		order = append(order, rel)
+	}
+	sort.Strings(order)
+	return order
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// remoteContent fetches a remote file's content through the API.
+func remoteContent(t *testing.T, c *Client, remotePath string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := c.Cat(remotePath, &out, 0, 0); err != nil {
+		t.Fatalf("cat %s: %v", remotePath, err)
+	}
+	return out.String()
+}
+
+// seedRemote uploads content to remotePath, stamping the source file
+// with mtime so the server records it.
+func seedRemote(t *testing.T, c *Client, remotePath, content string, mtime time.Time) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "seed")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(p, remotePath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBiSyncPropagatesBothDirections(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"a.txt": "local a"})
+	stats, err := c.BiSync(local, "two", BiSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 1 {
+		t.Errorf("first bisync uploaded %d, want 1", stats.Uploaded)
+	}
+
+	// Another machine adds a file; this one edits a.txt.
+	seedRemote(t, c, "two/b.txt", "remote b", time.Now())
+	writeTree(t, local, map[string]string{"a.txt": "local a, edited"})
+
+	stats, err = c.BiSync(local, "two", BiSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 1 || stats.Downloaded != 1 {
+		t.Errorf("second bisync = %+v, want 1 uploaded / 1 downloaded", stats)
+	}
+	if got, err := os.ReadFile(filepath.Join(local, "b.txt")); err != nil || string(got) != "remote b" {
+		t.Errorf("local b.txt = %q, %v; want \"remote b\"", got, err)
+	}
+	if got := remoteContent(t, c, "two/a.txt"); got != "local a, edited" {
+		t.Errorf("remote a.txt = %q, want the edited content", got)
+	}
+
+	// A local deletion propagates to the server.
+	if err := os.Remove(filepath.Join(local, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = c.BiSync(local, "two", BiSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Deleted != 1 {
+		t.Errorf("third bisync deleted %d, want 1", stats.Deleted)
+	}
+	files, err := c.listRecursive("two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["b.txt"]; ok {
+		t.Error("remote tree after delete still holds b.txt")
+	}
+}
+
+func TestBiSyncConflictNewestWins(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"doc.txt": "v1"})
+	if _, err := c.BiSync(local, "newest", BiSyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both sides edit; the remote edit carries the later timestamp.
+	writeTree(t, local, map[string]string{"doc.txt": "local edit"})
+	seedRemote(t, c, "newest/doc.txt", "remote edit, newer", time.Now().Add(time.Hour))
+
+	stats, err := c.BiSync(local, "newest", BiSyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Conflicts != 1 || stats.Downloaded != 1 {
+		t.Errorf("bisync = %+v, want 1 conflict resolved by download", stats)
+	}
+	if got, _ := os.ReadFile(filepath.Join(local, "doc.txt")); string(got) != "remote edit, newer" {
+		t.Errorf("local doc.txt = %q, want the newer remote edit", got)
+	}
+}
+
+func TestBiSyncConflictKeepBoth(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"doc.txt": "v1"})
+	if _, err := c.BiSync(local, "both", BiSyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTree(t, local, map[string]string{"doc.txt": "local edit"})
+	seedRemote(t, c, "both/doc.txt", "remote edit", time.Now())
+
+	stats, err := c.BiSync(local, "both", BiSyncOptions{Conflict: ConflictKeepBoth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Conflicts != 1 {
+		t.Errorf("bisync = %+v, want 1 conflict", stats)
+	}
+	if got := remoteContent(t, c, "both/doc.txt"); got != "local edit" {
+		t.Errorf("remote doc.txt = %q, want the local edit", got)
+	}
+	entries, err := filepath.Glob(filepath.Join(local, "doc.txt.conflict-*"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("conflict copies = %v, %v; want exactly one", entries, err)
+	}
+	if got, _ := os.ReadFile(entries[0]); string(got) != "remote edit" {
+		t.Errorf("conflict copy = %q, want the remote edit", got)
+	}
+}
+
+func TestBiSyncConflictAbort(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"doc.txt": "v1"})
+	if _, err := c.BiSync(local, "abort", BiSyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, local, map[string]string{"doc.txt": "local edit"})
+	seedRemote(t, c, "abort/doc.txt", "remote edit", time.Now())
+
+	_, err = c.BiSync(local, "abort", BiSyncOptions{Conflict: ConflictAbort})
+	if err == nil || !strings.Contains(err.Error(), "conflict: doc.txt") {
+		t.Errorf("bisync error = %v, want a conflict error naming doc.txt", err)
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Capabilities fetches the server's advertised limits. Servers predating
+// the endpoint return an error; callers treat that as "no limits known".
+func (c *Client) Capabilities() (*api.Capabilities, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/api/capabilities"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities: %w", err)
+	}
+	defer resp.Body.Close()
+	caps := &api.Capabilities{}
+	return caps, json.NewDecoder(resp.Body).Decode(caps)
+}
+
+// parallelUploads returns the configured worker count, clamped to what
+// the server advertises so misconfigured clients tune themselves down
+// instead of being throttled.
+func (c *Client) parallelUploads() int {
+	workers := c.cfg.ParallelUploads
+	if workers <= 1 {
+		return workers
+	}
+	caps, err := c.Capabilities()
+	if err != nil {
+		return workers
+	}
+	if caps.MaxParallelTransfers > 0 && workers > caps.MaxParallelTransfers {
+		c.logger.Infof("server allows %d parallel transfers; clamping parallel_uploads from %d",
+			caps.MaxParallelTransfers, workers)
+		workers = caps.MaxParallelTransfers
+	}
+	return workers
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/server"
+)
+
+func TestCapabilitiesReportsServerLimits(t *testing.T) {
+	s, err := server.NewServer(server.ServerConfig{
+		UploadDir:              t.TempDir(),
+		MaxFileSize:            1 << 20,
+		MaxConcurrentTransfers: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps, err := c.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.MaxFileSize != 1<<20 || caps.MaxParallelTransfers != 3 {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
+
+func TestParallelUploadsClampedToServerLimit(t *testing.T) {
+	s, err := server.NewServer(server.ServerConfig{
+		UploadDir:              t.TempDir(),
+		MaxConcurrentTransfers: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, ParallelUploads: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.parallelUploads(); got != 2 {
+		t.Errorf("parallelUploads() = %d, want 2", got)
+	}
+}
+
+func TestParallelUploadsUnclampedWithoutServerLimit(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, ParallelUploads: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.parallelUploads(); got != 8 {
+		t.Errorf("parallelUploads() = %d, want 8", got)
+	}
+}
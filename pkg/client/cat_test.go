@@ -0,0 +1,54 @@
+package client
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCatStreamsHeadAndTail(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(local, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(local, "logs/log.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := c.Cat("logs/log.txt", &out, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "0123456789" {
+		t.Errorf("cat = %q, want full content", out.String())
+	}
+
+	out.Reset()
+	if err := c.Cat("logs/log.txt", &out, 4, 0); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "0123" {
+		t.Errorf("cat -head 4 = %q, want %q", out.String(), "0123")
+	}
+
+	out.Reset()
+	if err := c.Cat("logs/log.txt", &out, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "789" {
+		t.Errorf("cat -tail 3 = %q, want %q", out.String(), "789")
+	}
+
+	if err := c.Cat("logs/log.txt", &out, 1, 1); err == nil {
+		t.Error("head and tail together did not error")
+	}
+}
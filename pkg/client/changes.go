@@ -0,0 +1,37 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Changes returns the server's journal events after cursor. With a
+// positive wait the server long-polls up to that duration for new
+// events, so mirrors can follow changes without tight polling loops.
+func (c *Client) Changes(cursor uint64, wait time.Duration) (*api.ChangeList, error) {
+	v := url.Values{"cursor": {strconv.FormatUint(cursor, 10)}}
+	if wait > 0 {
+		v.Set("wait", wait.String())
+	}
+	req, err := http.NewRequest(http.MethodGet, c.url("/api/changes")+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("changes after %d: %w", cursor, err)
+	}
+	defer resp.Body.Close()
+
+	var list api.ChangeList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode change list: %w", err)
+	}
+	return &list, nil
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Chunk size bounds for the adaptive tuner. Transfers start small so the
+// first chunks double as a bandwidth probe, then grow toward maxChunkSize
+// on fast links and shrink again when chunks take too long or error.
+const (
+	minChunkSize = 64 << 10  // 64 KiB
+	maxChunkSize = 16 << 20  // 16 MiB
+	startChunk   = 256 << 10 // 256 KiB
+
+	// targetChunkTime is how long one chunk should ideally take; it keeps
+	// progress updates and error granularity responsive on slow links.
+	targetChunkTime = 500 * time.Millisecond
+)
+
+// chunkTuner picks transfer chunk sizes dynamically from observed
+// throughput and errors, in the spirit of TCP slow start: grow while
+// chunks complete quickly, back off multiplicatively on errors or when a
+// chunk takes much longer than targetChunkTime.
+type chunkTuner struct {
+	mu   sync.Mutex
+	size int64
+	max  int64
+}
+
+// newChunkTuner returns a tuner starting at startChunk. max bounds growth;
+// zero or negative means maxChunkSize.
+func newChunkTuner(max int64) *chunkTuner {
+	if max <= 0 || max > maxChunkSize {
+		max = maxChunkSize
+	}
+	size := int64(startChunk)
+	if size > max {
+		size = max
+	}
+	return &chunkTuner{size: size, max: max}
+}
+
+// Size returns the chunk size to use for the next read/write.
+func (t *chunkTuner) Size() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.size
+}
+
+// Observe records the outcome of transferring n bytes in d. Failed or
+// slow chunks halve the size; chunks finishing well under target double
+// it.
+func (t *chunkTuner) Observe(n int64, d time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case err != nil || d > 2*targetChunkTime:
+		t.size /= 2
+	case n >= t.size && d < targetChunkTime/2:
+		t.size *= 2
+	}
+	if t.size < minChunkSize {
+		t.size = minChunkSize
+	}
+	if t.size > t.max {
+		t.size = t.max
+	}
+}
+
+// copyTuned copies src to dst in tuner-sized chunks, feeding timing
+// observations back into the tuner.
+func copyTuned(dst io.Writer, src io.Reader, tuner *chunkTuner) (int64, error) {
+	var written int64
+	buf := make([]byte, maxChunkSize)
+	for {
+		chunk := buf[:tuner.Size()]
+		start := time.Now()
+		n, err := io.ReadFull(src, chunk)
+		if n > 0 {
+			wn, werr := dst.Write(chunk[:n])
+			written += int64(wn)
+			if werr == nil && wn < n {
+				werr = io.ErrShortWrite
+			}
+			tuner.Observe(int64(wn), time.Since(start), werr)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkTunerGrowsOnFastChunks(t *testing.T) {
+	tuner := newChunkTuner(0)
+	start := tuner.Size()
+	for i := 0; i < 4; i++ {
+		tuner.Observe(tuner.Size(), 10*time.Millisecond, nil)
+	}
+	if tuner.Size() <= start {
+		t.Errorf("size = %d, want growth beyond %d", tuner.Size(), start)
+	}
+}
+
+func TestChunkTunerShrinksOnErrorsAndRespectsMin(t *testing.T) {
+	tuner := newChunkTuner(0)
+	for i := 0; i < 20; i++ {
+		tuner.Observe(0, time.Second, errors.New("boom"))
+	}
+	if got := tuner.Size(); got != minChunkSize {
+		t.Errorf("size = %d, want floor %d", got, minChunkSize)
+	}
+}
+
+func TestChunkTunerHonorsConfiguredMax(t *testing.T) {
+	tuner := newChunkTuner(minChunkSize)
+	for i := 0; i < 10; i++ {
+		tuner.Observe(tuner.Size(), time.Millisecond, nil)
+	}
+	if got := tuner.Size(); got != minChunkSize {
+		t.Errorf("size = %d, want cap %d", got, minChunkSize)
+	}
+}
+
+func TestCopyTuned(t *testing.T) {
+	src := strings.Repeat("x", 3*minChunkSize+17)
+	var dst bytes.Buffer
+	n, err := copyTuned(&dst, strings.NewReader(src), newChunkTuner(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(src)) || dst.String() != src {
+		t.Errorf("copied %d bytes, want %d", n, len(src))
+	}
+}
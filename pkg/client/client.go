@@ -0,0 +1,443 @@
+// Package client implements the upload-http CLI client: folder uploads
+// and downloads, listings and progress tracking against a running server.
+package client
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/config"
+)
+
+// ClientConfig controls the client. Zero values are replaced by the
+// defaults below in NewClient.
+type ClientConfig struct {
+	ServerURL string `json:"default_server"`
+
+	// ChunkSize accepts a plain byte count or a human-readable size
+	// string such as "4MiB" in the config file.
+	ChunkSize       config.Bytes `json:"chunk_size"`
+	ParallelUploads int          `json:"parallel_uploads"`
+	Debug           bool         `json:"debug"`
+
+	// Connections is how many concurrent ranged segments a single file
+	// download is split into; 0 or 1 means one stream. Multiple
+	// connections can saturate high-latency links that a single TCP
+	// stream cannot. Files too small to split, and servers that ignore
+	// Range requests, fall back to one stream.
+	Connections int `json:"connections,omitempty"`
+
+	// Retries is how many times a failed upload, download or listing is
+	// attempted again when the failure looks transient; negative disables
+	// retries. RetryBackoff is the base delay before the first retry as a
+	// Go duration string such as "500ms"; it doubles per attempt with
+	// jitter.
+	Retries      int    `json:"retries"`
+	RetryBackoff string `json:"retry_backoff,omitempty"`
+
+	// MetaTimeout bounds quick metadata calls — listing, stat, delete,
+	// quota, health and the like — as a Go duration string such as
+	// "10s"; "0" disables the bound. Transfers never run under it, so a
+	// slow upload or download is not killed mid-stream. Default 30s.
+	MetaTimeout string `json:"meta_timeout,omitempty"`
+
+	// Token authenticates against servers with configured users; it is
+	// sent as a bearer token on every request.
+	Token string `json:"token,omitempty"`
+
+	// TLSCert and TLSKey, when both set, present the named PEM
+	// certificate and key during the TLS handshake, for servers that
+	// require mutual TLS instead of (or on top of) bearer tokens.
+	TLSCert string `json:"tls_cert,omitempty"`
+	TLSKey  string `json:"tls_key,omitempty"`
+
+	// LimitRate caps transfer bandwidth in both directions, as a rate
+	// such as "10MB/s" or "512KB/s" (see ParseRate); empty means
+	// unlimited.
+	LimitRate string `json:"limit_rate,omitempty"`
+
+	// LimitRateShared treats LimitRate as a machine-wide total: every
+	// running client registers a heartbeat under ~/.upload-http and
+	// takes an equal share, so three concurrent invocations with a
+	// 30MB/s limit get about 10MB/s each instead of 30MB/s each.
+	LimitRateShared bool `json:"limit_rate_shared,omitempty"`
+
+	// LogFormat selects "text" (default) or "json" log output.
+	LogFormat string `json:"log_format,omitempty"`
+
+	// Progress selects the progress style: "auto" (default) redraws a
+	// single line in place, "plain" prints one complete line every few
+	// seconds with no cursor control characters, which screen readers
+	// and log collectors can follow.
+	Progress string `json:"progress,omitempty"`
+
+	// Output selects machine-readable CLI output: "json" renders
+	// listings as one indented JSON document, "ndjson" emits one JSON
+	// object per line, including progress events scripts can follow.
+	// Empty (or "text") keeps the human-readable output.
+	Output string `json:"output,omitempty"`
+
+	// Locale selects the language of CLI messages ("en", "zh-TW");
+	// empty follows the LC_ALL/LC_MESSAGES/LANG environment.
+	Locale string `json:"locale,omitempty"`
+
+	// LocalTime renders timestamps in CLI output in the local time zone
+	// instead of UTC. The wire format is always RFC 3339 UTC.
+	LocalTime bool `json:"local_time,omitempty"`
+
+	// ClockSkewSeconds is how far the local and server clocks may
+	// disagree before a modification-time difference counts as a change
+	// during sync; zero means one second. When timestamps disagree beyond
+	// the tolerance, content hashes decide instead.
+	ClockSkewSeconds int `json:"clock_skew_seconds,omitempty"`
+
+	// DedupeWindowSeconds suppresses re-uploading a file whose remote
+	// path and content hash were already uploaded within the window;
+	// zero disables deduplication.
+	DedupeWindowSeconds int `json:"dedupe_window_seconds,omitempty"`
+
+	// SyncMappings are named local/remote directory pairs with selection
+	// rules, run together by `client sync -all`.
+	SyncMappings []SyncMapping `json:"sync_mappings,omitempty"`
+
+	// Profiles are named servers referenced by rclone-style remote specs
+	// such as "prod:releases/v1".
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// DefaultProfile routes every command through the named profile
+	// unless a remote spec or the -profile flag says otherwise, so users
+	// who mostly talk to one server never spell it out.
+	DefaultProfile string `json:"default_profile,omitempty"`
+
+	// Templates are named bundles of upload settings (destination
+	// remote, selection globs) applied with `client upload -template
+	// <name>`.
+	Templates map[string]UploadTemplate `json:"templates,omitempty"`
+
+	// ReceiptDir, when set, stores the signed receipt from every
+	// successful upload in the named directory for later verification
+	// with `client verify-receipt`.
+	ReceiptDir string `json:"receipt_dir,omitempty"`
+
+	// JournalDir overrides where folder-upload checkpoint journals are
+	// kept (default ~/.upload-http/journals). "off" disables
+	// journaling.
+	JournalDir string `json:"journal_dir,omitempty"`
+
+	// TraceFile, when set, appends a log of every HTTP exchange
+	// (connection events, redacted headers) to the named file.
+	// TraceBodies additionally records text bodies up to a size cap.
+	TraceFile   string `json:"trace_file,omitempty"`
+	TraceBodies bool   `json:"trace_bodies,omitempty"`
+}
+
+// Profile is a named server in the client config.
+type Profile struct {
+	ServerURL string `json:"server_url"`
+	Token     string `json:"token,omitempty"`
+}
+
+// Defaults applied by NewClient for unset config fields.
+const (
+	DefaultRetries         = 3
+	DefaultChunkSize       = 1 << 20 // 1 MiB
+	DefaultParallelUploads = 4
+	DefaultMetaTimeout     = 30 * time.Second
+)
+
+// Client talks to one upload-http server.
+type Client struct {
+	cfg    ClientConfig
+	http   *http.Client
+	logger *utils.Logger
+
+	// metaHTTP performs the quick metadata calls under meta_timeout;
+	// http has no deadline, for transfers of any length.
+	metaHTTP *http.Client
+
+	// chunks adapts transfer chunk sizes to the observed link speed; a
+	// configured chunk_size acts as the upper bound instead of a fixed
+	// size.
+	chunks *chunkTuner
+
+	// dedupe, when enabled, suppresses back-to-back uploads of identical
+	// content to the same remote path.
+	dedupe *dedupeWindow
+
+	// backoff is the parsed base retry delay.
+	backoff time.Duration
+
+	// bw throttles transfers when limit_rate is set; nil is unlimited.
+	bw *utils.RateLimiter
+
+	// share keeps bw at a fair fraction of the configured limit while
+	// other local clients run; nil when sharing is off.
+	share *rateShare
+
+	// now stamps upload journals. Tests swap it for deterministic
+	// output; production uses time.Now.
+	now func() time.Time
+}
+
+// checkServerURL rejects URLs that cannot name an upload-http server.
+func checkServerURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %q: %w", s, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid server URL %q: scheme must be http or https", s)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid server URL %q: missing host", s)
+	}
+	return nil
+}
+
+// Validate checks everything about cfg that can be wrong before a
+// request is made: URLs, mode names, rate and duration strings,
+// negative counts, profile servers. NewClient runs it, and the CLI's
+// `config -validate` runs it directly against a config file so
+// mistakes surface before the next transfer does. Unlike NewClient,
+// ServerURL may be empty here — remote specs and profiles can supply
+// one per invocation.
+func (cfg ClientConfig) Validate() error {
+	// Catch URL and token mistakes here, where the message can point at
+	// the config, instead of as an opaque network error mid-transfer.
+	if cfg.ServerURL != "" {
+		if err := checkServerURL(cfg.ServerURL); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(cfg.Token, " \t\r\n") {
+		return fmt.Errorf("token contains whitespace; check for copy-paste artifacts")
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+	switch cfg.Progress {
+	case "", "auto", "plain":
+	default:
+		return fmt.Errorf("invalid progress mode %q (want auto or plain)", cfg.Progress)
+	}
+	switch cfg.Output {
+	case "", "text", "json", "ndjson":
+	default:
+		return fmt.Errorf("invalid output mode %q (want text, json or ndjson)", cfg.Output)
+	}
+	if cfg.RetryBackoff != "" {
+		d, err := time.ParseDuration(cfg.RetryBackoff)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid retry_backoff %q", cfg.RetryBackoff)
+		}
+	}
+	if cfg.MetaTimeout != "" {
+		d, err := time.ParseDuration(cfg.MetaTimeout)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid meta_timeout %q", cfg.MetaTimeout)
+		}
+	}
+	if cfg.LimitRate != "" {
+		if _, err := ParseRate(cfg.LimitRate); err != nil {
+			return fmt.Errorf("invalid limit_rate: %w", err)
+		}
+	}
+	countFields := []struct {
+		name string
+		v    int
+	}{
+		{"parallel_uploads", cfg.ParallelUploads},
+		{"connections", cfg.Connections},
+		{"clock_skew_seconds", cfg.ClockSkewSeconds},
+		{"dedupe_window_seconds", cfg.DedupeWindowSeconds},
+	}
+	for _, f := range countFields {
+		if f.v < 0 {
+			return fmt.Errorf("%s must not be negative", f.name)
+		}
+	}
+	if cfg.ChunkSize < 0 {
+		return fmt.Errorf("chunk_size must not be negative")
+	}
+	for name, p := range cfg.Profiles {
+		if err := checkServerURL(p.ServerURL); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	if cfg.DefaultProfile != "" {
+		if _, ok := cfg.Profiles[cfg.DefaultProfile]; !ok {
+			return fmt.Errorf("default_profile: unknown profile %q", cfg.DefaultProfile)
+		}
+	}
+	return nil
+}
+
+// NewClient returns a Client for cfg with defaults applied.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if cfg.ServerURL == "" {
+		return nil, fmt.Errorf("server URL is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.ServerURL = strings.TrimRight(cfg.ServerURL, "/")
+	if cfg.Retries == 0 {
+		cfg.Retries = DefaultRetries
+	}
+	backoff := defaultRetryBackoff
+	if cfg.RetryBackoff != "" {
+		d, err := time.ParseDuration(cfg.RetryBackoff)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid retry_backoff %q", cfg.RetryBackoff)
+		}
+		backoff = d
+	}
+	// A chunk_size of zero means "tune automatically"; an explicit value
+	// caps how large the tuner may grow chunks.
+	chunks := newChunkTuner(int64(cfg.ChunkSize))
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = DefaultChunkSize
+	}
+	if cfg.ParallelUploads == 0 {
+		cfg.ParallelUploads = DefaultParallelUploads
+	}
+	metaTimeout := time.Duration(DefaultMetaTimeout)
+	if cfg.MetaTimeout != "" {
+		d, err := time.ParseDuration(cfg.MetaTimeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid meta_timeout %q", cfg.MetaTimeout)
+		}
+		metaTimeout = d
+	}
+	c := &Client{
+		cfg:      cfg,
+		http:     &http.Client{Timeout: 0},
+		metaHTTP: &http.Client{Timeout: metaTimeout},
+		logger:   utils.NewLogger(os.Stderr, cfg.LogFormat, cfg.Debug),
+		chunks:   chunks,
+		backoff:  backoff,
+		now:      time.Now,
+	}
+	if cfg.LimitRate != "" {
+		rate, err := ParseRate(cfg.LimitRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit_rate: %w", err)
+		}
+		c.bw = utils.NewRateLimiter(rate)
+		if cfg.LimitRateShared && c.bw != nil {
+			if dir, err := defaultRateShareDir(); err == nil {
+				c.share = startRateShare(dir, rate, c.bw, c.logger)
+			}
+		}
+	}
+	if cfg.DedupeWindowSeconds > 0 {
+		c.dedupe = newDedupeWindow(time.Duration(cfg.DedupeWindowSeconds) * time.Second)
+	}
+	if cfg.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("load tls_cert: %w", err)
+		}
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		tr.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		c.http.Transport = tr
+		c.metaHTTP.Transport = tr
+	}
+	if cfg.TraceFile != "" {
+		f, err := os.OpenFile(cfg.TraceFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("open trace file: %w", err)
+		}
+		c.http.Transport = newTraceTransport(c.http.Transport, f, cfg.TraceBodies)
+		c.metaHTTP.Transport = c.http.Transport
+	}
+	return c, nil
+}
+
+// url joins path onto the configured server URL.
+func (c *Client) url(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return c.cfg.ServerURL + path
+}
+
+// do performs req and turns non-2xx responses into errors. It has no
+// deadline, so transfers of any length run to completion.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	return c.send(c.http, req)
+}
+
+// doMeta is do for the quick metadata calls — listing, stat, delete and
+// the like — which run under meta_timeout so a hung server fails fast.
+func (c *Client) doMeta(req *http.Request) (*http.Response, error) {
+	return c.send(c.metaHTTP, req)
+}
+
+func (c *Client) send(h *http.Client, req *http.Request) (*http.Response, error) {
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	resp, err := h.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		se := &statusError{code: resp.StatusCode, status: resp.Status, path: req.URL.Path}
+		// The server sends a structured error envelope; decode it so
+		// callers can test the class with errors.Is instead of matching
+		// message text. Plain-text bodies (proxies, older servers) are
+		// ignored.
+		var apiErr api.ErrorResponse
+		if body, err := io.ReadAll(io.LimitReader(resp.Body, 4096)); err == nil {
+			if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+				se.api = &apiErr
+			}
+		}
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil && secs > 0 {
+				se.retryAfter = time.Duration(secs) * time.Second
+			}
+		}
+		return nil, se
+	}
+	return resp, nil
+}
+
+// statusError is a non-2xx server response; the code drives retry
+// decisions and the decoded envelope, when present, carries the error
+// class.
+type statusError struct {
+	code       int
+	status     string
+	path       string
+	retryAfter time.Duration      // from the Retry-After header, if any
+	api        *api.ErrorResponse // decoded error envelope, if any
+}
+
+func (e *statusError) Error() string {
+	if e.api != nil {
+		return fmt.Sprintf("server returned %s for %s: %s", e.status, e.path, e.api.Error())
+	}
+	return fmt.Sprintf("server returned %s for %s", e.status, e.path)
+}
+
+// Unwrap exposes the decoded envelope so errors.Is reaches the api
+// sentinels (api.ErrNotFound and friends).
+func (e *statusError) Unwrap() error {
+	if e.api != nil {
+		return e.api
+	}
+	return nil
+}
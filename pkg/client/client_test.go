@@ -0,0 +1,95 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/server"
+)
+
+// serverForTest returns a real upload-http server handler backed by a
+// temporary storage directory.
+func serverForTest(t *testing.T) http.Handler {
+	t.Helper()
+	s, err := server.NewServer(server.ServerConfig{UploadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	good := ClientConfig{
+		LimitRate:    "10MB/s",
+		RetryBackoff: "500ms",
+		Profiles: map[string]Profile{
+			"prod": {ServerURL: "https://files.example.com"},
+		},
+		DefaultProfile: "prod",
+	}
+	if err := good.Validate(); err != nil {
+		t.Errorf("good config: %v", err)
+	}
+
+	bad := []ClientConfig{
+		{ServerURL: "ftp://host"},
+		{Output: "xml"},
+		{RetryBackoff: "fast"},
+		{MetaTimeout: "soon"},
+		{TLSCert: "client.pem"},
+		{LimitRate: "lots"},
+		{ParallelUploads: -1},
+		{Profiles: map[string]Profile{"p": {ServerURL: "not-a-url"}}},
+		{DefaultProfile: "missing"},
+	}
+	for _, cfg := range bad {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("config %+v validated", cfg)
+		}
+	}
+}
+
+// countingHandler counts POST /upload requests reaching the handler.
+func countingHandler(next http.Handler, uploads *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/upload" {
+			uploads.Add(1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestClientCertificateLoadFailureSurfaces(t *testing.T) {
+	_, err := NewClient(ClientConfig{ServerURL: "https://host", TLSCert: "/no/such/cert.pem", TLSKey: "/no/such/key.pem"})
+	if err == nil || !strings.Contains(err.Error(), "tls_cert") {
+		t.Errorf("err = %v, want a tls_cert load failure", err)
+	}
+}
+
+func TestMetaTimeoutAppliesToMetadataOnly(t *testing.T) {
+	// Every response stalls longer than the metadata timeout.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "[]")
+	}))
+	defer slow.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: slow.URL, MetaTimeout: "30ms", Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListFiles(""); err == nil {
+		t.Error("slow listing finished under meta_timeout")
+	}
+	// Transfers carry no deadline, so the same slow server still serves
+	// a download.
+	if err := c.Cat("x", io.Discard, 0, 0); err != nil {
+		t.Errorf("download against slow server: %v", err)
+	}
+}
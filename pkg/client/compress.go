@@ -0,0 +1,55 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// incompressibleExts lists formats that are already compressed, where a
+// gzip pass over the archive stream costs CPU without shrinking
+// anything.
+var incompressibleExts = map[string]bool{
+	".7z": true, ".aac": true, ".avi": true, ".br": true, ".bz2": true,
+	".flac": true, ".gif": true, ".gz": true, ".heic": true, ".jpeg": true,
+	".jpg": true, ".mkv": true, ".mov": true, ".mp3": true, ".mp4": true,
+	".ogg": true, ".png": true, ".rar": true, ".tgz": true, ".webm": true,
+	".webp": true, ".xz": true, ".zip": true, ".zst": true,
+}
+
+// archiveCompression decides whether localDir's archive stream is worth
+// gzipping: "none" when more than half of the selected bytes sit in
+// already-compressed formats, "gzip" otherwise. The server sniffs the
+// gzip magic, so both stream forms are accepted.
+func archiveCompression(localDir string, opts SyncOptions) string {
+	var stored, compressible int64
+	filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			if rel != "." && matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || !opts.selected(rel) {
+			return nil
+		}
+		if incompressibleExts[strings.ToLower(filepath.Ext(rel))] {
+			stored += fi.Size()
+		} else {
+			compressible += fi.Size()
+		}
+		return nil
+	})
+	if stored > compressible {
+		return "none"
+	}
+	return "gzip"
+}
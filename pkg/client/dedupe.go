@@ -0,0 +1,48 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupeWindow remembers recently uploaded (remote path, hash) pairs so
+// overlapping sources of uploads (watch mode, manual runs, the queue
+// daemon) do not send identical content twice back-to-back.
+type dedupeWindow struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newDedupeWindow(window time.Duration) *dedupeWindow {
+	return &dedupeWindow{window: window, seen: make(map[string]time.Time)}
+}
+
+// Seen reports whether the same content was uploaded to remotePath
+// within the window.
+func (d *dedupeWindow) Seen(remotePath, hash string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+	_, ok := d.seen[remotePath+"\x00"+hash]
+	return ok
+}
+
+// Record marks the pair as just uploaded.
+func (d *dedupeWindow) Record(remotePath, hash string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune()
+	d.seen[remotePath+"\x00"+hash] = time.Now()
+}
+
+// prune drops entries older than the window. Caller holds d.mu.
+func (d *dedupeWindow) prune() {
+	cutoff := time.Now().Add(-d.window)
+	for k, at := range d.seen {
+		if at.Before(cutoff) {
+			delete(d.seen, k)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupeWindowExpires(t *testing.T) {
+	d := newDedupeWindow(10 * time.Millisecond)
+	d.Record("a.txt", "h1")
+	if !d.Seen("a.txt", "h1") {
+		t.Error("freshly recorded pair not seen")
+	}
+	if d.Seen("a.txt", "h2") || d.Seen("b.txt", "h1") {
+		t.Error("different hash or path reported as duplicate")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if d.Seen("a.txt", "h1") {
+		t.Error("pair still seen after window expired")
+	}
+}
+
+func TestUploadFileSkipsDuplicateWithinWindow(t *testing.T) {
+	var uploads atomic.Int32
+	inner := serverForTest(t)
+	ts := httptest.NewServer(countingHandler(inner, &uploads))
+	defer ts.Close()
+
+	local := filepath.Join(t.TempDir(), "f.txt")
+	os.WriteFile(local, []byte("same content"), 0o644)
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, DedupeWindowSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(local, "f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.UploadFile(local, "f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Path != "f.txt" {
+		t.Errorf("skipped result path = %q", res.Path)
+	}
+	if got := uploads.Load(); got != 1 {
+		t.Errorf("server saw %d uploads, want 1 (second should be deduped)", got)
+	}
+}
@@ -0,0 +1,247 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Diff states reported in DiffEntry.State.
+const (
+	DiffAdded   = "added"   // exists locally, missing remotely
+	DiffRemoved = "removed" // exists remotely, missing locally
+	DiffChanged = "changed" // exists on both sides with different content
+)
+
+// DiffEntry is one difference between a local and a remote tree.
+type DiffEntry struct {
+	Path  string
+	State string
+}
+
+// Diff compares localDir against remoteDir without transferring any
+// content: the remote side comes from one recursive listing with
+// hashes, and local files are only hashed when the size/mtime cascade
+// cannot settle the question. It is the read-only companion to Sync —
+// the added and changed entries are exactly what Sync would upload, and
+// the removed entries what Sync -delete would remove. Results are
+// sorted by path.
+func (c *Client) Diff(localDir, remoteDir string, opts SyncOptions) ([]DiffEntry, error) {
+	remote, err := c.listRecursive(remoteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []DiffEntry
+	seen := make(map[string]bool)
+	err = filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !opts.selected(rel) {
+			return nil
+		}
+		seen[rel] = true
+		rf, ok := remote[rel]
+		switch {
+		case !ok:
+			diffs = append(diffs, DiffEntry{Path: rel, State: DiffAdded})
+		case c.fileChanged(p, fi, rf):
+			diffs = append(diffs, DiffEntry{Path: rel, State: DiffChanged})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for rel, rf := range remote {
+		if rf.IsDir || seen[rel] || !opts.selected(rel) {
+			continue
+		}
+		diffs = append(diffs, DiffEntry{Path: rel, State: DiffRemoved})
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// maxContentDiffSize caps how much of each side ContentDiff will load;
+// larger files get a note instead of a diff, since byte-level drift
+// checks are meant for configs and other small text files.
+const maxContentDiffSize = 256 << 10
+
+// ContentDiff returns a unified diff of the changed file rel between
+// localDir and remoteDir, fetching the remote side over HTTP. Files
+// that are too large or look binary yield a one-line note instead.
+func (c *Client) ContentDiff(localDir, remoteDir, rel string) (string, error) {
+	localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+	fi, err := os.Stat(localPath)
+	if err != nil {
+		return "", err
+	}
+	if fi.Size() > maxContentDiffSize {
+		return fmt.Sprintf("%s: file too large to diff (%d bytes)\n", rel, fi.Size()), nil
+	}
+	local, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	var remote bytes.Buffer
+	if err := c.Cat(path.Join(remoteDir, rel), &remote, maxContentDiffSize+1, 0); err != nil {
+		return "", err
+	}
+	if remote.Len() > maxContentDiffSize {
+		return fmt.Sprintf("%s: remote file too large to diff\n", rel), nil
+	}
+	if bytes.IndexByte(local, 0) >= 0 || bytes.IndexByte(remote.Bytes(), 0) >= 0 {
+		return fmt.Sprintf("%s: binary files differ\n", rel), nil
+	}
+	return unifiedDiff("remote/"+rel, "local/"+rel, splitLines(remote.String()), splitLines(string(local))), nil
+}
+
+// splitLines splits text into lines keeping each line without its
+// trailing newline; a trailing empty element from a final newline is
+// dropped so it does not diff as a phantom line.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// unifiedDiff renders a minimal unified diff (three lines of context)
+// from a to b. The longest-common-subsequence table is quadratic, which
+// the size caps above keep affordable; pathological inputs past the
+// cell budget get a note instead.
+func unifiedDiff(aName, bName string, a, b []string) string {
+	// Hunks carry up to this many unchanged lines around each change.
+	const context = 3
+	// Trim the common prefix and suffix first so the quadratic part only
+	// covers the region that actually changed.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	// Give back up to one hunk's worth of trimmed lines so the hunks
+	// still carry their context.
+	pre -= min(context, pre)
+	suf -= min(context, suf)
+	am, bm := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if len(am)*len(bm) > 4<<20 {
+		return fmt.Sprintf("--- %s\n+++ %s\n(diff too large to display)\n", aName, bName)
+	}
+
+	// ops is the edit script over the trimmed middle: ' ' keep, '-'
+	// delete from a, '+' insert from b.
+	type op struct {
+		kind byte
+		line string
+	}
+	lcs := make([][]int, len(am)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bm)+1)
+	}
+	for i := len(am) - 1; i >= 0; i-- {
+		for j := len(bm) - 1; j >= 0; j-- {
+			if am[i] == bm[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []op
+	for i, j := 0, 0; i < len(am) || j < len(bm); {
+		switch {
+		case i < len(am) && j < len(bm) && am[i] == bm[j]:
+			ops = append(ops, op{' ', am[i]})
+			i++
+			j++
+		case j < len(bm) && (i == len(am) || lcs[i][j+1] >= lcs[i+1][j]):
+			ops = append(ops, op{'+', bm[j]})
+			j++
+		default:
+			ops = append(ops, op{'-', am[i]})
+			i++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	aLine, bLine := pre, pre
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			aLine++
+			bLine++
+			k++
+			continue
+		}
+		// Found a change: back up for leading context, then extend until
+		// a gap of more than 2*context keep lines.
+		start := k
+		lead := 0
+		for start > 0 && lead < context && ops[start-1].kind == ' ' {
+			start--
+			lead++
+		}
+		end, run := k, 0
+		for end < len(ops) {
+			if ops[end].kind == ' ' {
+				run++
+				if run > 2*context {
+					break
+				}
+			} else {
+				run = 0
+			}
+			end++
+		}
+		trail := 0
+		for end-trail > start && ops[end-1-trail].kind == ' ' {
+			trail++
+		}
+		if trail > context {
+			end -= trail - context
+		}
+		hunkA, hunkB := aLine-lead, bLine-lead
+		var aCount, bCount int
+		var body strings.Builder
+		for _, o := range ops[start:end] {
+			body.WriteByte(o.kind)
+			body.WriteString(o.line)
+			body.WriteByte('\n')
+			if o.kind != '+' {
+				aCount++
+			}
+			if o.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n%s", hunkA+1, aCount, hunkB+1, bCount, body.String())
+		for _, o := range ops[k:end] {
+			if o.kind != '+' {
+				aLine++
+			}
+			if o.kind != '-' {
+				bLine++
+			}
+		}
+		k = end
+	}
+	return out.String()
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffReportsAddedRemovedChanged(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"same.txt":    "unchanged",
+		"changed.txt": "old content",
+		"gone.txt":    "to be removed locally",
+	})
+	if err := c.UploadFolder(local, "proj", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the local tree drift from the remote one.
+	writeTree(t, local, map[string]string{
+		"changed.txt": "new content!",
+		"new.txt":     "local only",
+	})
+	if err := os.Remove(filepath.Join(local, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := c.Diff(local, "proj", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiffEntry{
+		{Path: "changed.txt", State: DiffChanged},
+		{Path: "gone.txt", State: DiffRemoved},
+		{Path: "new.txt", State: DiffAdded},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("diff = %+v, want %+v", diffs, want)
+	}
+	for i, d := range diffs {
+		if d != want[i] {
+			t.Errorf("diff[%d] = %+v, want %+v", i, d, want[i])
+		}
+	}
+}
+
+func TestContentDiffShowsChangedLines(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"app.conf": "port = 8080\nhost = prod\ntimeout = 30\n",
+	})
+	if err := c.UploadFolder(local, "cfg", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, local, map[string]string{
+		"app.conf": "port = 8080\nhost = staging\ntimeout = 30\n",
+	})
+
+	text, err := c.ContentDiff(local, "cfg", "app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--- remote/app.conf", "+++ local/app.conf", "-host = prod", "+host = staging", " port = 8080"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("content diff missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "-timeout") || strings.Contains(text, "+port") {
+		t.Errorf("unchanged lines marked as changes:\n%s", text)
+	}
+}
+
+func TestContentDiffSkipsBinary(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"blob.bin": "a\x00b"})
+	if err := c.UploadFolder(local, "bin", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, local, map[string]string{"blob.bin": "a\x00c"})
+
+	text, err := c.ContentDiff(local, "bin", "blob.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "binary files differ") {
+		t.Errorf("binary diff = %q, want binary note", text)
+	}
+}
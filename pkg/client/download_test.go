@@ -0,0 +1,107 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestDownloadFolderFilesFetchesAndResumes(t *testing.T) {
+	var fetches int32
+	h := serverForTest(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/download/") {
+			atomic.AddInt32(&fetches, 1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, ParallelUploads: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"a.txt":     "alpha",
+		"sub/b.txt": "bravo",
+		"sub/c.txt": "charlie",
+	})
+	if err := c.UploadFolder(src, "tree", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	if err := c.DownloadFolderFiles("tree", dst); err != nil {
+		t.Fatal(err)
+	}
+	for rel, want := range map[string]string{"a.txt": "alpha", "sub/b.txt": "bravo", "sub/c.txt": "charlie"} {
+		got, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(rel)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+
+	// A second run over a complete local tree transfers nothing.
+	atomic.StoreInt32(&fetches, 0)
+	if err := c.DownloadFolderFiles("tree", dst); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 0 {
+		t.Errorf("complete tree still fetched %d files", n)
+	}
+
+	// Only the locally missing file is re-fetched.
+	if err := os.Remove(filepath.Join(dst, "sub", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DownloadFolderFiles("tree", dst); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("resume fetched %d files, want 1", n)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "sub", "b.txt")); string(got) != "bravo" {
+		t.Errorf("restored b.txt = %q", got)
+	}
+}
+
+func TestDownloadFolderRejectsManifestMismatch(t *testing.T) {
+	// A server whose tar stream carries content that does not match its
+	// own manifest — what silent corruption in transit looks like.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		body := []byte("corrupted")
+		tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0o644, Size: int64(len(body))})
+		tw.Write(body)
+		manifest := []byte(`{"a.txt":"not-the-real-hash"}`)
+		tw.WriteHeader(&tar.Header{Name: api.ArchiveManifestName, Mode: 0o644, Size: int64(len(manifest))})
+		tw.Write(manifest)
+		tw.Close()
+		gz.Close()
+	}))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	err = c.DownloadFolder("proj", dst)
+	if err == nil || !strings.Contains(err.Error(), "manifest") {
+		t.Fatalf("corrupt stream err = %v, want manifest mismatch", err)
+	}
+	// The manifest itself must not be extracted alongside the files.
+	if _, err := os.Stat(filepath.Join(dst, api.ArchiveManifestName)); !os.IsNotExist(err) {
+		t.Errorf("manifest member was written to disk (err=%v)", err)
+	}
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestServerErrorsMapToSentinels(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stat("missing.txt", false)
+	if !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Stat error = %v, want api.ErrNotFound", err)
+	}
+	if errors.Is(err, api.ErrTooLarge) {
+		t.Errorf("Stat error %v also matches api.ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+)
+
+const (
+	// peerHeartbeat is how often a participant refreshes its heartbeat
+	// file and recounts its peers.
+	peerHeartbeat = 2 * time.Second
+
+	// peerStaleAfter is how old a heartbeat may be before the process
+	// behind it counts as dead; crashed processes cannot remove their
+	// own file, so survivors clean up for them.
+	peerStaleAfter = 10 * time.Second
+)
+
+// rateShare divides a configured total rate limit fairly between the
+// client processes of one machine. Each participant keeps a heartbeat
+// file in a shared directory and takes total/peers as its own limit.
+// There is no byte-level coordination — just an equal static split —
+// which is simple, lock-free and fair enough for a limit meant to
+// protect a shared uplink.
+type rateShare struct {
+	dir   string
+	path  string
+	total int64
+	bw    *utils.RateLimiter
+	stop  chan struct{}
+}
+
+// defaultRateShareDir returns the per-user peer directory.
+func defaultRateShareDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".upload-http", "ratepeers"), nil
+}
+
+// startRateShare registers this process in dir and keeps bw set to its
+// fair share of total until close. Failure to register is logged and
+// disables sharing rather than failing the client: the process then
+// just uses the whole configured limit.
+func startRateShare(dir string, total int64, bw *utils.RateLimiter, logger *utils.Logger) *rateShare {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		logger.Errorf("rate share: %v", err)
+		return nil
+	}
+	s := &rateShare{
+		dir:   dir,
+		path:  filepath.Join(dir, fmt.Sprintf("%d.peer", os.Getpid())),
+		total: total,
+		bw:    bw,
+		stop:  make(chan struct{}),
+	}
+	if err := os.WriteFile(s.path, nil, 0o600); err != nil {
+		logger.Errorf("rate share: %v", err)
+		return nil
+	}
+	s.rebalance()
+	go s.loop()
+	return s
+}
+
+func (s *rateShare) loop() {
+	t := time.NewTicker(peerHeartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			os.Remove(s.path)
+			return
+		case now := <-t.C:
+			os.Chtimes(s.path, now, now)
+			s.rebalance()
+		}
+	}
+}
+
+// rebalance recounts live peers and resets the limiter to this
+// process's share.
+func (s *rateShare) rebalance() {
+	n := s.peers()
+	if n < 1 {
+		n = 1
+	}
+	per := s.total / int64(n)
+	if per < 1 {
+		per = 1
+	}
+	s.bw.SetRate(per)
+}
+
+// peers counts live heartbeat files, removing stale ones on the way.
+func (s *rateShare) peers() int {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 1
+	}
+	count := 0
+	cutoff := time.Now().Add(-peerStaleAfter)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".peer") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if fi.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(s.dir, e.Name()))
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// close deregisters this process, returning its share to the others.
+func (s *rateShare) close() {
+	close(s.stop)
+}
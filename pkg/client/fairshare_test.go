@@ -0,0 +1,50 @@
+package client
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+)
+
+func TestRateShareSplitsLimitBetweenPeers(t *testing.T) {
+	dir := t.TempDir()
+	bw := utils.NewRateLimiter(100)
+	s := startRateShare(dir, 100, bw, utils.NewLogger(io.Discard, "", false))
+	if s == nil {
+		t.Fatal("rate share did not start")
+	}
+	defer s.close()
+
+	// Alone, the process keeps the whole limit.
+	if got := bw.Rate(); got != 100 {
+		t.Errorf("solo rate = %d, want 100", got)
+	}
+
+	// A second live peer halves the share.
+	other := filepath.Join(dir, "99999.peer")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s.rebalance()
+	if got := bw.Rate(); got != 50 {
+		t.Errorf("shared rate = %d, want 50", got)
+	}
+
+	// A stale heartbeat is a dead peer: cleaned up and no longer
+	// counted.
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(other, old, old); err != nil {
+		t.Fatal(err)
+	}
+	s.rebalance()
+	if got := bw.Rate(); got != 100 {
+		t.Errorf("rate after peer death = %d, want 100", got)
+	}
+	if _, err := os.Stat(other); !os.IsNotExist(err) {
+		t.Errorf("stale peer file survived (err=%v)", err)
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// DeleteFile removes a single remote file.
+func (c *Client) DeleteFile(remotePath string) error {
+	return c.Remove(remotePath, false)
+}
+
+// Remove deletes remotePath on the server; recursive must be set to
+// delete a directory and everything below it.
+func (c *Client) Remove(remotePath string, recursive bool) error {
+	v := url.Values{"path": {remotePath}}
+	if recursive {
+		v.Set("recursive", "true")
+	}
+	req, err := http.NewRequest(http.MethodDelete, c.url("/api/files")+"?"+v.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", remotePath, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// HasContent reports whether the server already stores remotePath with
+// exactly the given checksum, via a HEAD content probe. Any failure
+// counts as "no" so callers fall back to uploading.
+func (c *Client) HasContent(remotePath, sum string) bool {
+	v := url.Values{"path": {remotePath}, "hash": {sum}}
+	req, err := http.NewRequest(http.MethodHead, c.url("/api/files")+"?"+v.Encode(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
+
+// BulkDelete deletes every remote path matching the glob pattern
+// (path.Match semantics). Matched directories need recursive; dryRun
+// only reports the matches so callers can confirm before deleting.
+func (c *Client) BulkDelete(pattern string, recursive, dryRun bool) (*api.BatchDeleteResponse, error) {
+	data, err := json.Marshal(api.BatchDeleteRequest{Pattern: pattern, Recursive: recursive, DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url("/api/files/delete-batch"), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("delete %s: %w", pattern, err)
+	}
+	defer resp.Body.Close()
+	br := &api.BatchDeleteResponse{}
+	return br, json.NewDecoder(resp.Body).Decode(br)
+}
+
+// Mkdir creates a remote directory, including missing parents.
+func (c *Client) Mkdir(remotePath string) error {
+	v := url.Values{"path": {remotePath}}
+	req, err := http.NewRequest(http.MethodPost, c.url("/api/mkdir")+"?"+v.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return fmt.Errorf("mkdir %s: %w", remotePath, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Move renames a remote file or directory entirely on the server.
+func (c *Client) Move(from, to string) error {
+	return c.fileOp("move", api.FileOpRequest{From: from, To: to})
+}
+
+// Copy duplicates a remote file, or a directory tree when recursive is
+// set, entirely on the server.
+func (c *Client) Copy(from, to string, recursive bool) error {
+	return c.fileOp("copy", api.FileOpRequest{From: from, To: to, Recursive: recursive})
+}
+
+func (c *Client) fileOp(op string, body api.FileOpRequest) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url("/api/files/"+op), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return fmt.Errorf("%s %s -> %s: %w", op, body.From, body.To, err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmptyDirectoryRoundTrip(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"sub/file.txt": "x"})
+	if err := os.MkdirAll(filepath.Join(src, "empty", "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadFolder(src, "proj", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := c.ListTree("proj", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range tree {
+		if f.Path == "proj/empty/nested" && f.IsDir {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("empty directory missing from remote tree: %+v", tree)
+	}
+
+	dst := t.TempDir()
+	if err := c.DownloadFolder("proj", dst); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dst, "empty", "nested"))
+	if err != nil || !fi.IsDir() {
+		t.Errorf("empty directory lost on download: %v", err)
+	}
+}
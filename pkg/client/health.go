@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// healthTimeout bounds the probe so an unreachable host produces a
+// diagnosis instead of hanging indefinitely.
+const healthTimeout = 10 * time.Second
+
+// HealthInfo mirrors the server's /health payload.
+type HealthInfo struct {
+	Status    string `json:"status"`
+	FreeBytes int64  `json:"free_bytes,omitempty"`
+}
+
+// Health probes the server's /health endpoint once, without retries, so
+// a failure diagnoses the first error rather than the last of several
+// attempts. Failures come back as *HealthError with setup guidance.
+func (c *Client) Health() (*HealthInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/health"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, &HealthError{Server: c.cfg.ServerURL, Err: err}
+	}
+	defer resp.Body.Close()
+	var hi HealthInfo
+	if err := json.NewDecoder(resp.Body).Decode(&hi); err != nil {
+		return nil, fmt.Errorf("decode health response: %w", err)
+	}
+	// /health is auth-exempt so a reachable server always answers it; a
+	// second probe against a protected endpoint verifies the credentials
+	// too.
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, c.url("/api/list"), nil)
+	if err != nil {
+		return nil, err
+	}
+	listResp, err := c.doMeta(req)
+	if err != nil {
+		return nil, &HealthError{Server: c.cfg.ServerURL, Err: err}
+	}
+	listResp.Body.Close()
+	return &hi, nil
+}
+
+// HealthError is a failed health probe. Error distinguishes the common
+// first-time-setup failures — DNS, refused connections, TLS, missing
+// credentials, timeouts — so the fix is evident from the message alone.
+type HealthError struct {
+	Server string
+	Err    error
+}
+
+func (e *HealthError) Unwrap() error { return e.Err }
+
+func (e *HealthError) Error() string {
+	var dns *net.DNSError
+	var cert *tls.CertificateVerificationError
+	var record tls.RecordHeaderError
+	var se *statusError
+	switch {
+	case errors.As(e.Err, &dns):
+		return fmt.Sprintf("cannot resolve %q — check the server URL for typos", dns.Name)
+	case errors.Is(e.Err, syscall.ECONNREFUSED):
+		return fmt.Sprintf("connection to %s refused — is the server running, and the port right?", e.Server)
+	case errors.As(e.Err, &cert):
+		return fmt.Sprintf("TLS certificate for %s rejected (%v) — this machine does not trust the server's certificate", e.Server, cert.Err)
+	case errors.As(e.Err, &record):
+		return fmt.Sprintf("%s answered with plaintext to a TLS handshake — the URL probably wants http:// instead of https://", e.Server)
+	case errors.As(e.Err, &se) && se.code == http.StatusUnauthorized:
+		return fmt.Sprintf("%s requires authentication — set token in the client config or pass -token", e.Server)
+	case errors.Is(e.Err, context.DeadlineExceeded):
+		return fmt.Sprintf("no answer from %s within %v — host unreachable or a firewall is dropping traffic", e.Server, healthTimeout)
+	}
+	return fmt.Sprintf("health probe of %s failed: %v", e.Server, e.Err)
+}
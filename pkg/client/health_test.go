@@ -0,0 +1,86 @@
+package client
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/server"
+)
+
+func TestNewClientValidatesServerURL(t *testing.T) {
+	cases := []struct {
+		cfg  ClientConfig
+		want string
+	}{
+		{ClientConfig{ServerURL: "ftp://host:8080"}, "scheme must be http or https"},
+		{ClientConfig{ServerURL: "http://"}, "missing host"},
+		{ClientConfig{ServerURL: "http://host", Token: "abc def"}, "whitespace"},
+	}
+	for _, tc := range cases {
+		_, err := NewClient(tc.cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("NewClient(%q) error = %v, want %q", tc.cfg.ServerURL, err, tc.want)
+		}
+	}
+	if _, err := NewClient(ClientConfig{ServerURL: "https://host:8443/"}); err != nil {
+		t.Errorf("valid URL rejected: %v", err)
+	}
+}
+
+func TestHealthReportsServerStatus(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hi, err := c.Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hi.Status != "ok" || hi.FreeBytes <= 0 {
+		t.Errorf("health = %+v, want ok with positive free_bytes", hi)
+	}
+}
+
+func TestHealthDiagnosesFailures(t *testing.T) {
+	// Refused connection: a listener that was just closed.
+	ts := httptest.NewServer(serverForTest(t))
+	url := ts.URL
+	ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Health(); err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("probe of closed server = %v, want connection-refused diagnosis", err)
+	}
+
+	// DNS failure: a name that cannot resolve.
+	c, err = NewClient(ClientConfig{ServerURL: "http://no-such-host.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Health(); err == nil || !strings.Contains(err.Error(), "cannot resolve") {
+		t.Errorf("probe of bogus host = %v, want DNS diagnosis", err)
+	}
+
+	// Missing credentials: an authenticated server without a token.
+	s, err := server.NewServer(server.ServerConfig{
+		UploadDir: t.TempDir(),
+		Users:     []server.UserConfig{{Name: "u", Token: "tok"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ats := httptest.NewServer(s)
+	defer ats.Close()
+	c, err = NewClient(ClientConfig{ServerURL: ats.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Health(); err == nil || !strings.Contains(err.Error(), "authentication") {
+		t.Errorf("probe without token = %v, want authentication diagnosis", err)
+	}
+}
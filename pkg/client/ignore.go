@@ -0,0 +1,37 @@
+package client
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// uploadIgnoreFile is looked up in the root of a folder upload; its glob
+// patterns are added to the exclude list, gitignore-style: blank lines
+// and lines starting with # are skipped.
+const uploadIgnoreFile = ".uploadignore"
+
+// loadUploadIgnore reads dir's .uploadignore file and returns its
+// patterns. A missing file is not an error.
+func loadUploadIgnore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, uploadIgnoreFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, strings.TrimSuffix(line, "/"))
+	}
+	return patterns, sc.Err()
+}
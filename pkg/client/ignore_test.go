@@ -0,0 +1,55 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUploadFolderRespectsIgnoreFile(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".uploadignore":       "# build artifacts\n*.o\nnode_modules/\n",
+		"main.go":             "package main",
+		"main.o":              "obj",
+		"node_modules/x/y.js": "js",
+		"src/keep.txt":        "keep",
+		"src/skipped.tmp":     "tmp",
+	})
+	opts := SyncOptions{Exclude: []string{"*.tmp"}}
+	if err := c.UploadFolder(dir, "proj", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := c.ListTree("proj", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, f := range tree {
+		if !f.IsDir {
+			got[f.Path] = true
+		}
+	}
+	want := map[string]bool{
+		"proj/.uploadignore": true,
+		"proj/main.go":       true,
+		"proj/src/keep.txt":  true,
+	}
+	for p := range want {
+		if !got[p] {
+			t.Errorf("missing %s from %v", p, got)
+		}
+	}
+	for p := range got {
+		if !want[p] {
+			t.Errorf("unexpected upload %s", p)
+		}
+	}
+}
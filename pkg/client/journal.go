@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UploadJournal is the header record of a folder-upload checkpoint
+// journal: one JSON-lines file per transfer in the journal directory,
+// the header first and then one line per completed file. Re-running the
+// same upload (same server, local dir and remote prefix) reopens the
+// journal and skips the files it already confirmed, so a run that died
+// deep into a large tree picks up where it stopped instead of starting
+// over.
+type UploadJournal struct {
+	ID           string    `json:"id"`
+	Server       string    `json:"server"`
+	LocalDir     string    `json:"local_dir"`
+	RemotePrefix string    `json:"remote_prefix"`
+	StartedAt    time.Time `json:"started_at"`
+
+	// TotalFiles and TotalBytes describe the tree as it looked when the
+	// journal was created, so `client status` can show how far an
+	// interrupted run got.
+	TotalFiles int   `json:"total_files,omitempty"`
+	TotalBytes int64 `json:"total_bytes,omitempty"`
+}
+
+// journalEntry is one completed file, appended as the upload goes.
+type journalEntry struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// DefaultJournalDir returns the per-user journal location.
+func DefaultJournalDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".upload-http", "journals"), nil
+}
+
+// journalID derives the stable journal identity of one upload, so the
+// same invocation always maps to the same journal file.
+func journalID(server, localDir, remotePrefix string) string {
+	sum := sha256.Sum256([]byte(server + "\x00" + localDir + "\x00" + remotePrefix))
+	return hex.EncodeToString(sum[:8])
+}
+
+// uploadJournal is an open checkpoint journal. record may be called from
+// parallel upload workers.
+type uploadJournal struct {
+	meta UploadJournal
+	path string
+
+	mu   sync.Mutex
+	f    *os.File
+	done map[string]string // completed relative path -> hash
+}
+
+// openUploadJournal opens (resuming) or creates the journal for an
+// upload in dir. totalFiles and totalBytes describe the tree being
+// uploaded; a resumed journal keeps the totals it was created with. now
+// stamps the journal header when one is created.
+func openUploadJournal(dir, server, localDir, remotePrefix string, totalFiles int, totalBytes int64, now time.Time) (*uploadJournal, error) {
+	abs, err := filepath.Abs(localDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create journal dir: %w", err)
+	}
+	j := &uploadJournal{
+		meta: UploadJournal{
+			ID:           journalID(server, abs, remotePrefix),
+			Server:       server,
+			LocalDir:     abs,
+			RemotePrefix: remotePrefix,
+			StartedAt:    now.UTC(),
+			TotalFiles:   totalFiles,
+			TotalBytes:   totalBytes,
+		},
+		done: make(map[string]string),
+	}
+	j.path = filepath.Join(dir, j.meta.ID+".jsonl")
+
+	if meta, done, err := readJournalFile(j.path); err == nil {
+		j.meta, j.done = meta, done
+		j.f, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0o600)
+		return j, err
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(j.meta)
+	if err == nil {
+		_, err = f.Write(append(header, '\n'))
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	j.f = f
+	return j, nil
+}
+
+// readJournalFile parses a journal: the header line, then one entry per
+// completed file. A torn final line from a crash is simply dropped.
+func readJournalFile(path string) (UploadJournal, map[string]string, error) {
+	var meta UploadJournal
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, nil, err
+	}
+	done := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	if !sc.Scan() || json.Unmarshal(sc.Bytes(), &meta) != nil || meta.ID == "" {
+		return meta, nil, fmt.Errorf("corrupt upload journal %s", filepath.Base(path))
+	}
+	for sc.Scan() {
+		var e journalEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Path != "" {
+			done[e.Path] = e.Hash
+		}
+	}
+	return meta, done, nil
+}
+
+// UploadJournalStatus is one journal with its progress, for
+// `client status`.
+type UploadJournalStatus struct {
+	UploadJournal
+	DoneFiles int       `json:"done_files"` // files confirmed by the interrupted run
+	UpdatedAt time.Time `json:"updated_at"` // when the journal was last written
+}
+
+// ListUploadJournals returns the journals left behind by interrupted
+// uploads in dir, most recently touched first. A missing directory just
+// means nothing has been journaled yet; unreadable or corrupt journals
+// are skipped.
+func ListUploadJournals(dir string) ([]UploadJournalStatus, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []UploadJournalStatus
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".jsonl" {
+			continue
+		}
+		meta, done, err := readJournalFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		st := UploadJournalStatus{UploadJournal: meta, DoneFiles: len(done)}
+		if fi, err := e.Info(); err == nil {
+			st.UpdatedAt = fi.ModTime()
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, nil
+}
+
+// LoadUploadJournal reads the header of the journal with the given ID,
+// for `client resume <journal-id>`.
+func LoadUploadJournal(dir, id string) (*UploadJournal, error) {
+	f, err := os.Open(filepath.Join(dir, id+".jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("no upload journal %s: %w", id, err)
+	}
+	defer f.Close()
+	var meta UploadJournal
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() || json.Unmarshal(sc.Bytes(), &meta) != nil || meta.ID == "" {
+		return nil, fmt.Errorf("corrupt upload journal %s", id)
+	}
+	return &meta, nil
+}
+
+// openJournal opens the checkpoint journal for a folder upload, or nil
+// when journaling is disabled or unavailable; a journal that cannot be
+// opened is logged and skipped rather than failing the upload.
+func (c *Client) openJournal(localDir, remotePrefix string, totalFiles int, totalBytes int64) *uploadJournal {
+	dir := c.cfg.JournalDir
+	if dir == "" || dir == "off" {
+		return nil
+	}
+	j, err := openUploadJournal(dir, c.cfg.ServerURL, localDir, remotePrefix, totalFiles, totalBytes, c.now())
+	if err != nil {
+		c.logger.Errorf("upload journal: %v", err)
+		return nil
+	}
+	return j
+}
+
+// completed returns the recorded hash of rel, if it finished in an
+// earlier run.
+func (j *uploadJournal) completed(rel string) (string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	sum, ok := j.done[rel]
+	return sum, ok
+}
+
+// record appends rel as completed with the given hash.
+func (j *uploadJournal) record(rel, sum string) error {
+	line, err := json.Marshal(journalEntry{Path: rel, Hash: sum})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done[rel] = sum
+	_, err = j.f.Write(append(line, '\n'))
+	return err
+}
+
+// finish closes the journal, removing it when the upload completed so
+// only interrupted transfers leave one behind.
+func (j *uploadJournal) finish(complete bool) {
+	j.f.Close()
+	if complete {
+		os.Remove(j.path)
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+func TestJournalRecordsAndReopens(t *testing.T) {
+	dir := t.TempDir()
+	j, err := openUploadJournal(dir, "http://srv", "/data/photos", "backup", 3, 42, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record("a.txt", "sum-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record("sub/b.txt", "sum-b"); err != nil {
+		t.Fatal(err)
+	}
+	j.finish(false) // interrupted: the journal must survive
+
+	j2, err := openUploadJournal(dir, "http://srv", "/data/photos", "backup", 3, 42, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := j2.completed("sub/b.txt"); !ok || sum != "sum-b" {
+		t.Errorf("completed(sub/b.txt) = %q, %v; want sum-b", sum, ok)
+	}
+	meta, err := LoadUploadJournal(dir, j2.meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.RemotePrefix != "backup" || meta.Server != "http://srv" {
+		t.Errorf("journal meta = %+v", meta)
+	}
+
+	j2.finish(true) // completed: the journal is cleaned up
+	if _, err := os.Stat(j2.path); !os.IsNotExist(err) {
+		t.Errorf("finished journal survived (err=%v)", err)
+	}
+}
+
+func TestListUploadJournals(t *testing.T) {
+	dir := t.TempDir()
+	j, err := openUploadJournal(dir, "http://srv", "/data/photos", "backup", 3, 42, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record("a.txt", "sum-a"); err != nil {
+		t.Fatal(err)
+	}
+	j.finish(false)
+
+	list, err := ListUploadJournals(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d journals, want 1", len(list))
+	}
+	st := list[0]
+	if st.ID != j.meta.ID || st.RemotePrefix != "backup" {
+		t.Errorf("journal status = %+v", st)
+	}
+	if st.DoneFiles != 1 || st.TotalFiles != 3 || st.TotalBytes != 42 {
+		t.Errorf("progress = %d/%d files, %d bytes; want 1/3, 42", st.DoneFiles, st.TotalFiles, st.TotalBytes)
+	}
+
+	// A completed upload leaves nothing behind.
+	j2, err := openUploadJournal(dir, "http://srv", "/data/photos", "backup", 3, 42, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	j2.finish(true)
+	if list, err = ListUploadJournals(dir); err != nil || len(list) != 0 {
+		t.Errorf("after completion: %d journals, %v; want none", len(list), err)
+	}
+
+	// A missing journal directory is not an error.
+	if list, err = ListUploadJournals(filepath.Join(dir, "nope")); err != nil || list != nil {
+		t.Errorf("missing dir: %v, %v; want nil, nil", list, err)
+	}
+}
+
+func TestUploadFolderResumesFromJournal(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	journalDir := t.TempDir()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, JournalDir: journalDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"done.txt": "already confirmed last run",
+		"new.txt":  "still to upload",
+	})
+
+	// Seed the journal as an interrupted run would have left it:
+	// done.txt confirmed, new.txt not yet reached.
+	sum, err := hash.HashFile(filepath.Join(local, "done.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := openUploadJournal(journalDir, ts.URL, local, "resumed", 2, 40, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.record("done.txt", sum); err != nil {
+		t.Fatal(err)
+	}
+	j.finish(false)
+
+	if err := c.UploadFolder(local, "resumed", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// The journaled file was skipped without touching the server; the
+	// other one went up.
+	var out bytes.Buffer
+	if err := c.Cat("resumed/done.txt", &out, 0, 0); err == nil {
+		t.Error("done.txt was re-uploaded despite its journal entry")
+	}
+	out.Reset()
+	if err := c.Cat("resumed/new.txt", &out, 0, 0); err != nil || out.String() != "still to upload" {
+		t.Errorf("new.txt = %q, %v; want it uploaded", out.String(), err)
+	}
+	// A clean finish removes the journal.
+	if _, err := os.Stat(j.path); !os.IsNotExist(err) {
+		t.Errorf("journal survived a completed upload (err=%v)", err)
+	}
+}
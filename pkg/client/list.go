@@ -0,0 +1,83 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// ListFiles returns one directory level of the server's storage at
+// remotePath ("" for the root).
+func (c *Client) ListFiles(remotePath string) ([]api.FileInfo, error) {
+	return c.list(remotePath, false, false)
+}
+
+// ListTree returns every file and directory below remotePath. With
+// withHash each file entry carries its checksum, enabling tree
+// comparison without transfers.
+func (c *Client) ListTree(remotePath string, withHash bool) ([]api.FileInfo, error) {
+	return c.list(remotePath, true, withHash)
+}
+
+// Stat returns the entry for a single remote file or directory. With
+// withHash a file entry carries its checksum.
+func (c *Client) Stat(remotePath string, withHash bool) (*api.FileInfo, error) {
+	var info *api.FileInfo
+	err := c.withRetry("stat "+remotePath, func() error {
+		v := url.Values{"path": {remotePath}}
+		if withHash {
+			v.Set("hash", "true")
+		}
+		req, err := http.NewRequest(http.MethodGet, c.url("/api/stat")+"?"+v.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.doMeta(req)
+		if err != nil {
+			return fmt.Errorf("stat %s: %w", remotePath, err)
+		}
+		defer resp.Body.Close()
+		info = &api.FileInfo{}
+		return json.NewDecoder(resp.Body).Decode(info)
+	})
+	return info, err
+}
+
+func (c *Client) list(remotePath string, recursive, withHash bool) ([]api.FileInfo, error) {
+	var files []api.FileInfo
+	err := c.withRetry("list "+remotePath, func() error {
+		var err error
+		files, err = c.listOnce(remotePath, recursive, withHash)
+		return err
+	})
+	return files, err
+}
+
+// listOnce performs one listing attempt.
+func (c *Client) listOnce(remotePath string, recursive, withHash bool) ([]api.FileInfo, error) {
+	v := url.Values{"path": {remotePath}}
+	if recursive {
+		v.Set("recursive", "true")
+	}
+	if withHash {
+		v.Set("hash", "true")
+	}
+	req, err := http.NewRequest(http.MethodGet, c.url("/api/list")+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", remotePath, err)
+	}
+	defer resp.Body.Close()
+
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, fmt.Errorf("decode listing: %w", err)
+	}
+	return files, nil
+}
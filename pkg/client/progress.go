@@ -0,0 +1,261 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/i18n"
+)
+
+// plainInterval is how often plain-mode progress emits a line.
+const plainInterval = 5 * time.Second
+
+// rateWindow is how much recent history the throughput estimate uses;
+// a windowed rate tracks the link as it speeds up or stalls instead of
+// averaging over the whole run.
+const rateWindow = 5 * time.Second
+
+// byteSample is one throughput observation: the cumulative byte count
+// at a point in time.
+type byteSample struct {
+	t     time.Time
+	bytes int64
+}
+
+// TransferProgress tracks a multi-file transfer and prints progress to
+// stderr.
+type TransferProgress struct {
+	mu               sync.Mutex
+	TotalFiles       int
+	CompletedFiles   int
+	TotalBytes       int64
+	TransferredBytes int64
+
+	// Plain emits one complete line per plainInterval with no cursor
+	// control characters, instead of redrawing a single line in place.
+	// Screen readers and log collectors cannot follow the redraws.
+	Plain bool
+
+	// JSON emits one NDJSON progress event per plainInterval instead of
+	// human-readable lines, for scripts that follow the transfer.
+	JSON bool
+
+	start      time.Time
+	lastLine   time.Time
+	plainEvery time.Duration // tests shorten this
+	out        io.Writer
+
+	current string       // file most recently started, shown in the bar
+	samples []byteSample // recent byte counts for the windowed rate
+	tty     bool         // whether redrawing in place makes sense
+}
+
+// NewTransferProgress returns a progress tracker expecting totalFiles
+// files and totalBytes bytes. When stderr is not a terminal the
+// redrawing bar degrades to plain lines, so redirected output stays
+// readable without asking for -progress plain.
+func NewTransferProgress(totalFiles int, totalBytes int64) *TransferProgress {
+	now := time.Now()
+	return &TransferProgress{
+		TotalFiles: totalFiles,
+		TotalBytes: totalBytes,
+		start:      now,
+		lastLine:   now,
+		plainEvery: plainInterval,
+		out:        os.Stderr,
+		tty:        isTerminal(os.Stderr),
+	}
+}
+
+// StartFile records the file a worker just began transferring; the bar
+// names it, so a long stall is attributable to a specific file.
+func (p *TransferProgress) StartFile(rel string) {
+	p.mu.Lock()
+	p.current = rel
+	if p.Plain || !p.tty {
+		p.mu.Unlock()
+		return
+	}
+	line := p.barLine(time.Now())
+	p.mu.Unlock()
+	fmt.Fprintf(p.out, "\r%-100s", line)
+}
+
+// FileDone records one completed file of size bytes and reprints
+// progress.
+func (p *TransferProgress) FileDone(size int64) {
+	p.mu.Lock()
+	p.CompletedFiles++
+	p.TransferredBytes += size
+	now := time.Now()
+	p.sample(now)
+	if p.JSON {
+		if now.Sub(p.lastLine) < p.plainEvery && p.CompletedFiles < p.TotalFiles {
+			p.mu.Unlock()
+			return
+		}
+		p.lastLine = now
+		line := p.jsonLine("progress", now)
+		p.mu.Unlock()
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	if p.Plain || !p.tty {
+		if now.Sub(p.lastLine) < p.plainEvery && p.CompletedFiles < p.TotalFiles {
+			p.mu.Unlock()
+			return
+		}
+		p.lastLine = now
+		line := p.plainLine(now)
+		p.mu.Unlock()
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	line := p.barLine(now)
+	p.mu.Unlock()
+	fmt.Fprintf(p.out, "\r%-100s", line)
+}
+
+// sample records the byte count at now and drops observations that fell
+// out of the rate window. Callers hold p.mu.
+func (p *TransferProgress) sample(now time.Time) {
+	p.samples = append(p.samples, byteSample{t: now, bytes: p.TransferredBytes})
+	for len(p.samples) > 1 && now.Sub(p.samples[0].t) > rateWindow {
+		p.samples = p.samples[1:]
+	}
+}
+
+// rate estimates throughput in bytes per second over the sample window,
+// falling back to the whole-run average while the window is still
+// filling. Callers hold p.mu.
+func (p *TransferProgress) rate(now time.Time) int64 {
+	if len(p.samples) >= 2 {
+		first := p.samples[0]
+		if span := now.Sub(first.t).Seconds(); span > 0 {
+			return int64(float64(p.TransferredBytes-first.bytes) / span)
+		}
+	}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		return int64(float64(p.TransferredBytes) / elapsed)
+	}
+	return 0
+}
+
+// barLine renders the redrawing progress bar with bytes, rate, ETA and
+// the current file. Callers hold p.mu.
+func (p *TransferProgress) barLine(now time.Time) string {
+	const width = 20
+	filled := 0
+	if p.TotalBytes > 0 {
+		filled = int(int64(width) * p.TransferredBytes / p.TotalBytes)
+		if filled > width {
+			filled = width
+		}
+	}
+	bar := "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+	rate := p.rate(now)
+	eta := "--"
+	if rate > 0 && p.TotalBytes > p.TransferredBytes {
+		eta = (time.Duration((p.TotalBytes-p.TransferredBytes)/rate) * time.Second).String()
+	}
+	line := bar + " " + fmt.Sprintf(i18n.T("%s / %s  %s/s  ETA %s  %d/%d files"),
+		formatBytes(p.TransferredBytes), formatBytes(p.TotalBytes),
+		formatBytes(rate), eta, p.CompletedFiles, p.TotalFiles)
+	if p.current != "" {
+		line += "  " + p.current
+	}
+	return line
+}
+
+// plainLine renders one plain-mode progress line. Callers hold p.mu.
+func (p *TransferProgress) plainLine(now time.Time) string {
+	pct := 0
+	if p.TotalBytes > 0 {
+		pct = int(p.TransferredBytes * 100 / p.TotalBytes)
+	}
+	return fmt.Sprintf(i18n.T("%d%% done, %d/%d files, %s/s"),
+		pct, p.CompletedFiles, p.TotalFiles, formatBytes(p.rate(now)))
+}
+
+// progressEvent is one machine-readable progress record, emitted as a
+// line of NDJSON when output "ndjson" is selected.
+type progressEvent struct {
+	Event            string `json:"event"` // "progress" per interval, "done" at the end
+	CompletedFiles   int    `json:"completed_files"`
+	TotalFiles       int    `json:"total_files"`
+	TransferredBytes int64  `json:"transferred_bytes"`
+	TotalBytes       int64  `json:"total_bytes"`
+	RateBytesPerSec  int64  `json:"rate_bytes_per_sec"`
+	File             string `json:"file,omitempty"`
+}
+
+// jsonLine renders one NDJSON progress event. Callers hold p.mu.
+func (p *TransferProgress) jsonLine(event string, now time.Time) string {
+	b, _ := json.Marshal(progressEvent{
+		Event:            event,
+		CompletedFiles:   p.CompletedFiles,
+		TotalFiles:       p.TotalFiles,
+		TransferredBytes: p.TransferredBytes,
+		TotalBytes:       p.TotalBytes,
+		RateBytesPerSec:  p.rate(now),
+		File:             p.current,
+	})
+	return string(b)
+}
+
+// Finish terminates the progress line.
+func (p *TransferProgress) Finish() {
+	if p.JSON {
+		p.mu.Lock()
+		line := p.jsonLine("done", time.Now())
+		p.mu.Unlock()
+		fmt.Fprintln(p.out, line)
+		return
+	}
+	if p.Plain || !p.tty {
+		return
+	}
+	fmt.Fprintln(p.out)
+}
+
+// newProgress returns a tracker honoring the client's progress and
+// output settings: "ndjson" streams events to stdout where scripts
+// read them, "json" keeps stdout parseable by suppressing progress
+// entirely.
+func (c *Client) newProgress(totalFiles int, totalBytes int64) *TransferProgress {
+	p := NewTransferProgress(totalFiles, totalBytes)
+	p.Plain = c.cfg.Progress == "plain"
+	switch c.cfg.Output {
+	case "ndjson":
+		p.JSON = true
+		p.out = os.Stdout
+	case "json":
+		p.out = io.Discard
+	}
+	return p
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// formatBytes renders n in a human-readable unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlainProgressPrintsWholeLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTransferProgress(4, 400)
+	p.Plain = true
+	p.plainEvery = 0 // every FileDone emits a line
+	p.out = &buf
+
+	for i := 0; i < 4; i++ {
+		p.FileDone(100)
+	}
+	p.Finish()
+
+	out := buf.String()
+	if strings.ContainsAny(out, "\r\x1b") {
+		t.Errorf("plain output contains cursor control characters: %q", out)
+	}
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4: %q", len(lines), out)
+	}
+	if want := "100% done, 4/4 files"; !strings.Contains(lines[3], want) {
+		t.Errorf("last line = %q, want it to contain %q", lines[3], want)
+	}
+}
+
+func TestBarShowsBytesRateAndCurrentFile(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTransferProgress(2, 2048)
+	p.tty = true // pretend stderr is a terminal
+	p.out = &buf
+	p.start = time.Now().Add(-2 * time.Second)
+
+	p.StartFile("photos/img0042.jpg")
+	p.FileDone(1024)
+	p.Finish()
+
+	out := buf.String()
+	if !strings.Contains(out, "\r") {
+		t.Errorf("bar output does not redraw in place: %q", out)
+	}
+	for _, want := range []string{"[==========          ]", "1.0 KB / 2.0 KB", "ETA", "1/2 files", "photos/img0042.jpg"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("bar output missing %q: %q", want, out)
+		}
+	}
+}
+
+func TestAutoProgressDegradesWithoutTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTransferProgress(1, 100)
+	p.tty = false // stderr piped or redirected
+	p.out = &buf
+
+	p.FileDone(100)
+	p.Finish()
+
+	out := buf.String()
+	if strings.ContainsAny(out, "\r\x1b") {
+		t.Errorf("non-terminal output contains cursor control characters: %q", out)
+	}
+	if !strings.Contains(out, "100% done, 1/1 files") {
+		t.Errorf("completion line missing: %q", out)
+	}
+}
+
+func TestJSONProgressEmitsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTransferProgress(2, 200)
+	p.JSON = true
+	p.plainEvery = 0 // every FileDone emits an event
+	p.out = &buf
+
+	p.StartFile("a.txt")
+	p.FileDone(100)
+	p.FileDone(100)
+	p.Finish()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d events, want 3: %q", len(lines), buf.String())
+	}
+	var ev progressEvent
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil {
+		t.Fatalf("event is not valid JSON: %v: %q", err, lines[0])
+	}
+	if ev.Event != "progress" || ev.CompletedFiles != 1 || ev.TransferredBytes != 100 || ev.File != "a.txt" {
+		t.Errorf("first event = %+v", ev)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Event != "done" || ev.CompletedFiles != 2 || ev.TransferredBytes != 200 {
+		t.Errorf("final event = %+v", ev)
+	}
+}
+
+func TestPlainProgressThrottlesAndPrintsCompletion(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewTransferProgress(100, 1000)
+	p.Plain = true
+	p.plainEvery = time.Hour
+	p.out = &buf
+
+	for i := 0; i < 100; i++ {
+		p.FileDone(10)
+	}
+
+	// Only the final FileDone bypasses the interval throttle.
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Errorf("got %d lines, want 1: %q", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "100/100 files") {
+		t.Errorf("completion line missing: %q", buf.String())
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Queue item states.
+const (
+	QueuePending = "pending"
+	QueueDone    = "done"
+	QueueFailed  = "failed"
+)
+
+// maxQueueBackoff caps the exponential retry delay for a queue item.
+const maxQueueBackoff = 5 * time.Minute
+
+// QueueItem is one enqueued upload. Items survive restarts: each is a
+// JSON file in the queue directory, rewritten atomically on every state
+// change.
+type QueueItem struct {
+	ID         string    `json:"id"`
+	LocalPath  string    `json:"local_path"`
+	RemotePath string    `json:"remote_path"`
+	Server     string    `json:"server"`
+	State      string    `json:"state"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	NextTry    time.Time `json:"next_try,omitempty"`
+}
+
+// Queue is a durable client-side upload queue backed by a directory of
+// JSON item files.
+type Queue struct {
+	dir string
+}
+
+// DefaultQueueDir returns the per-user queue location.
+func DefaultQueueDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".upload-http", "queue"), nil
+}
+
+// OpenQueue opens (creating if needed) the queue at dir.
+func OpenQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create queue dir: %w", err)
+	}
+	return &Queue{dir: dir}, nil
+}
+
+// Add enqueues an upload of localPath (file or folder) to remotePath on
+// server.
+func (q *Queue) Add(localPath, remotePath, server string) (*QueueItem, error) {
+	abs, err := filepath.Abs(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	item := &QueueItem{
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		LocalPath:  abs,
+		RemotePath: remotePath,
+		Server:     server,
+		State:      QueuePending,
+		EnqueuedAt: now,
+		UpdatedAt:  now,
+	}
+	return item, q.save(item)
+}
+
+// List returns all queue items ordered by enqueue time.
+func (q *Queue) List() ([]QueueItem, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	var items []QueueItem
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(q.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var item QueueItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].EnqueuedAt.Before(items[j].EnqueuedAt) })
+	return items, nil
+}
+
+// Remove deletes a queue item by ID.
+func (q *Queue) Remove(id string) error {
+	return os.Remove(filepath.Join(q.dir, id+".json"))
+}
+
+// save writes the item atomically so a crash never leaves a truncated
+// entry.
+func (q *Queue) save(item *QueueItem) error {
+	item.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(q.dir, item.ID+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(q.dir, item.ID+".json"))
+}
+
+// Run drains the queue, retrying failed items with exponential backoff.
+// With once set it makes a single pass over the ready items and returns;
+// otherwise it keeps polling forever so new items and recovered
+// connectivity are picked up, which is the daemon mode for field devices
+// with intermittent links.
+func (q *Queue) Run(cfg ClientConfig, once bool) error {
+	for {
+		items, err := q.List()
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			item := &items[i]
+			if item.State != QueuePending || time.Now().Before(item.NextTry) {
+				continue
+			}
+			if err := q.runItem(cfg, item); err != nil {
+				item.Attempts++
+				item.LastError = err.Error()
+				item.NextTry = time.Now().Add(queueBackoff(item.Attempts))
+				q.save(item)
+				continue
+			}
+			item.State = QueueDone
+			item.LastError = ""
+			q.save(item)
+		}
+		if once {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (q *Queue) runItem(cfg ClientConfig, item *QueueItem) error {
+	cfg.ServerURL = item.Server
+	c, err := NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(item.LocalPath)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return c.UploadFolder(item.LocalPath, item.RemotePath, SyncOptions{})
+	}
+	_, err = c.UploadFile(item.LocalPath, item.RemotePath)
+	return err
+}
+
+// queueBackoff returns the delay before retry number attempts.
+func queueBackoff(attempts int) time.Duration {
+	d := time.Second << uint(attempts)
+	if d <= 0 || d > maxQueueBackoff {
+		d = maxQueueBackoff
+	}
+	return d
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQueueAddListRemove(t *testing.T) {
+	q, err := OpenQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(t.TempDir(), "f.txt")
+	os.WriteFile(local, []byte("x"), 0o644)
+
+	item, err := q.Add(local, "remote/f.txt", "http://localhost:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := q.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].State != QueuePending {
+		t.Fatalf("items = %+v", items)
+	}
+	if err := q.Remove(item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := q.List(); len(items) != 0 {
+		t.Errorf("queue not empty after remove: %+v", items)
+	}
+}
+
+func TestQueueRunDrainsAndRecordsFailures(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+
+	q, err := OpenQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(t.TempDir(), "ok.txt")
+	os.WriteFile(local, []byte("content"), 0o644)
+
+	if _, err := q.Add(local, "ok.txt", ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	// Unreachable server: the item must stay pending with backoff.
+	if _, err := q.Add(local, "bad.txt", "http://127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Run(ClientConfig{Retries: -1}, true); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := q.List()
+	var done, pending int
+	for _, it := range items {
+		switch it.State {
+		case QueueDone:
+			done++
+		case QueuePending:
+			pending++
+			if it.Attempts == 0 || it.LastError == "" || it.NextTry.Before(time.Now()) {
+				t.Errorf("failed item not backed off: %+v", it)
+			}
+		}
+	}
+	if done != 1 || pending != 1 {
+		t.Errorf("done=%d pending=%d, want 1/1", done, pending)
+	}
+}
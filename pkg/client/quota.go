@@ -0,0 +1,31 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Quota returns the usage and limits of the quota scope containing
+// remotePath, i.e. its top-level directory on the server.
+func (c *Client) Quota(remotePath string) (*api.QuotaStatus, error) {
+	var st *api.QuotaStatus
+	err := c.withRetry("quota "+remotePath, func() error {
+		v := url.Values{"path": {remotePath}}
+		req, err := http.NewRequest(http.MethodGet, c.url("/api/quota")+"?"+v.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.doMeta(req)
+		if err != nil {
+			return fmt.Errorf("quota %s: %w", remotePath, err)
+		}
+		defer resp.Body.Close()
+		st = &api.QuotaStatus{}
+		return json.NewDecoder(resp.Body).Decode(st)
+	})
+	return st, err
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseRate converts a human bandwidth spec such as "10MB/s", "512KB/s"
+// or a plain bytes-per-second count into bytes per second. Units are
+// powers of 1024 and the "/s" suffix is optional.
+func ParseRate(s string) (int64, error) {
+	v := strings.TrimSpace(s)
+	v = strings.TrimSuffix(v, "/s")
+	mult := int64(1)
+	upper := strings.ToUpper(v)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(upper, u.suffix) {
+			mult = u.mult
+			v = strings.TrimSpace(v[:len(v)-len(u.suffix)])
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"10MB/s", 10 << 20},
+		{"512KB/s", 512 << 10},
+		{"1GB/s", 1 << 30},
+		{"2M", 2 << 20},
+		{"1.5MB", 3 << 19},
+		{"4096", 4096},
+		{"100B/s", 100},
+	}
+	for _, c := range cases {
+		got, err := ParseRate(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", c.in, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "fast", "-1MB/s", "0"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) did not error", bad)
+		}
+	}
+}
+
+func TestRateLimiterThrottlesReads(t *testing.T) {
+	// 1 MiB at 2 MiB/s beyond the initial 2 MiB burst: copying 3 MiB
+	// should take at least ~400ms (generous slack against CI jitter).
+	l := utils.NewRateLimiter(2 << 20)
+	src := bytes.NewReader(make([]byte, 3<<20))
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, l.Reader(src)); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("3MiB at 2MiB/s took %s, want at least 400ms", elapsed)
+	}
+
+	// A nil limiter passes data through untouched.
+	var nilL *utils.RateLimiter
+	if r := nilL.Reader(src); r != io.Reader(src) {
+		t.Error("nil limiter wrapped the reader")
+	}
+}
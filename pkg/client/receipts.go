@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// saveReceipt writes a signed upload receipt into the configured
+// receipt directory, named after the remote path so the latest receipt
+// per path wins.
+func (c *Client) saveReceipt(r *api.Receipt) error {
+	if err := os.MkdirAll(c.cfg.ReceiptDir, 0o755); err != nil {
+		return err
+	}
+	name := strings.ReplaceAll(strings.Trim(r.Path, "/"), "/", "_") + ".json"
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.cfg.ReceiptDir, name), data, 0o644)
+}
+
+// LoadReceipt reads a receipt previously stored by saveReceipt.
+func LoadReceipt(path string) (*api.Receipt, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r api.Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse receipt %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// ServerKey fetches the server's current receipt signing key.
+func (c *Client) ServerKey() (*api.KeyInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/api/key"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch server key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var key api.KeyInfo
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return nil, fmt.Errorf("decode server key: %w", err)
+	}
+	return &key, nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ResolveRemote interprets an rclone-style remote spec and returns the
+// config pointed at the right server plus the remote path. Two forms are
+// accepted:
+//
+//	http://host:8080/path/in/remote   full server URL
+//	profile:path/in/remote            named profile from the config
+//
+// Profile tokens override the config token so multi-server workflows
+// keep their credentials per remote.
+func ResolveRemote(cfg ClientConfig, spec string) (ClientConfig, string, error) {
+	if strings.Contains(spec, "://") {
+		u, err := url.Parse(spec)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return cfg, "", fmt.Errorf("invalid server URL %q", spec)
+		}
+		remote := strings.TrimPrefix(u.Path, "/")
+		u.Path = ""
+		cfg.ServerURL = u.String()
+		return cfg, remote, nil
+	}
+
+	name, rest, ok := strings.Cut(spec, ":")
+	if !ok || name == "" || strings.ContainsAny(name, "/\\") {
+		return cfg, "", fmt.Errorf("%q is not a remote spec (use profile:path or a server URL)", spec)
+	}
+	cfg, err := UseProfile(cfg, name)
+	if err != nil {
+		return cfg, "", err
+	}
+	return cfg, strings.TrimPrefix(rest, "/"), nil
+}
+
+// UseProfile returns cfg pointed at the named profile's server, for
+// whole invocations run against one profile (the -profile flag or the
+// default_profile config field) rather than a per-argument remote spec.
+// The profile token, when set, overrides the config token.
+func UseProfile(cfg ClientConfig, name string) (ClientConfig, error) {
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return cfg, fmt.Errorf("unknown profile %q", name)
+	}
+	cfg.ServerURL = p.ServerURL
+	if p.Token != "" {
+		cfg.Token = p.Token
+	}
+	return cfg, nil
+}
+
+// IsRemoteSpec reports whether arg looks like a remote spec rather than
+// a local path.
+func IsRemoteSpec(arg string) bool {
+	if strings.Contains(arg, "://") {
+		return true
+	}
+	name, _, ok := strings.Cut(arg, ":")
+	return ok && name != "" && !strings.ContainsAny(name, "/\\")
+}
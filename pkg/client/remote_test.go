@@ -0,0 +1,81 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveRemoteURL(t *testing.T) {
+	cfg, remote, err := ResolveRemote(ClientConfig{}, "http://localhost:8080/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerURL != "http://localhost:8080" {
+		t.Errorf("server URL = %q", cfg.ServerURL)
+	}
+	if remote != "a/b" {
+		t.Errorf("remote = %q", remote)
+	}
+}
+
+func TestResolveRemoteProfile(t *testing.T) {
+	base := ClientConfig{
+		Token: "default",
+		Profiles: map[string]Profile{
+			"prod": {ServerURL: "http://prod:9000", Token: "prodtoken"},
+		},
+	}
+	cfg, remote, err := ResolveRemote(base, "prod:releases/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerURL != "http://prod:9000" {
+		t.Errorf("server URL = %q", cfg.ServerURL)
+	}
+	if cfg.Token != "prodtoken" {
+		t.Errorf("token = %q", cfg.Token)
+	}
+	if remote != "releases/v1" {
+		t.Errorf("remote = %q", remote)
+	}
+}
+
+func TestResolveRemoteUnknownProfile(t *testing.T) {
+	_, _, err := ResolveRemote(ClientConfig{}, "nope:path")
+	if err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	base := ClientConfig{
+		ServerURL: "http://localhost:8080",
+		Token:     "default",
+		Profiles: map[string]Profile{
+			"work": {ServerURL: "http://work:9000", Token: "worktoken"},
+		},
+	}
+	cfg, err := UseProfile(base, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerURL != "http://work:9000" || cfg.Token != "worktoken" {
+		t.Errorf("cfg = %q token %q", cfg.ServerURL, cfg.Token)
+	}
+	if _, err := UseProfile(base, "nope"); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestIsRemoteSpec(t *testing.T) {
+	for _, spec := range []string{"http://host/p", "prod:path"} {
+		if !IsRemoteSpec(spec) {
+			t.Errorf("IsRemoteSpec(%q) = false", spec)
+		}
+	}
+	for _, spec := range []string{"./local/dir", "plain", "/abs/path:odd"} {
+		if IsRemoteSpec(spec) {
+			t.Errorf("IsRemoteSpec(%q) = true", spec)
+		}
+	}
+}
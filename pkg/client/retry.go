@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Retry pacing bounds; retries and retry_backoff in the config control
+// the attempt count and base delay.
+const (
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// withRetry runs fn, retrying transient failures with exponential
+// backoff until it succeeds or the configured retry budget is spent.
+// A 429 from the server is backpressure rather than a failure: the
+// client waits for a slot (honoring Retry-After) without spending its
+// retry budget.
+func (c *Client) withRetry(op string, fn func() error) error {
+	for attempt := 0; ; {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusTooManyRequests {
+			d := se.retryAfter
+			if d <= 0 {
+				d = c.retryDelay(0)
+			}
+			c.logger.Infof("%s: waiting for server slot (retrying in %s)", op, d)
+			time.Sleep(d)
+			continue
+		}
+		if attempt >= c.cfg.Retries || !retryable(err) {
+			return err
+		}
+		d := c.retryDelay(attempt)
+		c.logger.Debugf("%s: attempt %d failed, retrying in %s: %v", op, attempt+1, d, err)
+		time.Sleep(d)
+		attempt++
+	}
+}
+
+// retryDelay returns the pause before the given (zero-based) retry: the
+// base backoff doubled per attempt, capped, with jitter in the upper
+// half so competing clients spread out.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	d := c.backoff
+	for i := 0; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryable reports whether err is worth retrying. Transport failures
+// and the usual transient HTTP statuses qualify; local file errors and
+// client mistakes (other 4xx responses) do not.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.code {
+		case http.StatusRequestTimeout, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var pe *os.PathError
+	return !errors.As(err, &pe)
+}
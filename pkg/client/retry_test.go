@@ -0,0 +1,78 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestListRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	backend := serverForTest(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, "flaky", http.StatusServiceUnavailable)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Retries: 3, RetryBackoff: "1ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListFiles(""); err != nil {
+		t.Fatalf("list failed despite retries: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("server saw %d calls, want 3", got)
+	}
+}
+
+func TestClientWaitsOutServerBackpressure(t *testing.T) {
+	var calls atomic.Int32
+	backend := serverForTest(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "too many concurrent transfers", http.StatusTooManyRequests)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	// 429 must not consume the retry budget, even with retries disabled.
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Retries: -1, RetryBackoff: "1ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListFiles(""); err != nil {
+		t.Fatalf("list failed despite freed slot: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server saw %d calls, want 2", got)
+	}
+}
+
+func TestRetrySkipsClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Retries: 3, RetryBackoff: "1ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListFiles(""); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server saw %d calls, want 1 (4xx must not retry)", got)
+	}
+}
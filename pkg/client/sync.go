@@ -0,0 +1,273 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// modTimeSkew is the default tolerance for how much the local and remote
+// modification times may differ before a file counts as changed;
+// clock_skew_seconds in the config overrides it.
+const modTimeSkew = time.Second
+
+// skew returns the configured clock-skew tolerance.
+func (c *Client) skew() time.Duration {
+	if c.cfg.ClockSkewSeconds > 0 {
+		return time.Duration(c.cfg.ClockSkewSeconds) * time.Second
+	}
+	return modTimeSkew
+}
+
+// SyncOptions control Client.Sync.
+type SyncOptions struct {
+	// Delete removes files on the destination side that no longer exist
+	// on the source side.
+	Delete bool
+
+	// Include and Exclude are glob patterns matched against the
+	// slash-separated relative path (and its base name). When Include is
+	// non-empty only matching files are considered; Exclude then removes
+	// matches and wins over Include.
+	Include []string
+	Exclude []string
+}
+
+// selected reports whether a relative path passes the include/exclude
+// rules.
+func (o SyncOptions) selected(rel string) bool {
+	if matchAny(o.Exclude, rel) {
+		return false
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	return matchAny(o.Include, rel)
+}
+
+// matchAny reports whether rel or its base name matches any glob
+// pattern.
+func matchAny(patterns []string, rel string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pat, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SyncMapping is one configured sync profile, run by `client sync -all`.
+type SyncMapping struct {
+	Name       string `json:"name"`
+	LocalPath  string `json:"local_path"`
+	RemotePath string `json:"remote_path"`
+	// Server overrides the default server URL for this mapping.
+	Server    string   `json:"server,omitempty"`
+	Direction string   `json:"direction,omitempty"` // "upload" (default) or "download"
+	Include   []string `json:"include,omitempty"`
+	Exclude   []string `json:"exclude,omitempty"`
+	Delete    bool     `json:"delete,omitempty"`
+}
+
+// Options returns the SyncOptions encoded in the mapping.
+func (m SyncMapping) Options() SyncOptions {
+	return SyncOptions{Delete: m.Delete, Include: m.Include, Exclude: m.Exclude}
+}
+
+// SyncStats summarizes what a sync transferred.
+type SyncStats struct {
+	Uploaded int
+	Deleted  int
+	Skipped  int
+}
+
+// Sync mirrors localDir onto remoteDir: files that are missing remotely
+// or differ by size or modification time are uploaded, everything else
+// is skipped, and with opts.Delete remote files absent locally are
+// removed.
+func (c *Client) Sync(localDir, remoteDir string, opts SyncOptions) (*SyncStats, error) {
+	remote, err := c.listRecursive(remoteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{}
+	local := make(map[string]bool)
+	err = filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !opts.selected(rel) {
+			return nil
+		}
+		local[rel] = true
+		remotePath := path.Join(remoteDir, rel)
+
+		if rf, ok := remote[rel]; ok && !c.fileChanged(p, fi, rf) {
+			stats.Skipped++
+			return nil
+		}
+		if _, err := c.UploadFile(p, remotePath); err != nil {
+			return err
+		}
+		stats.Uploaded++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Delete {
+		for rel, rf := range remote {
+			if rf.IsDir || local[rel] || !opts.selected(rel) {
+				continue
+			}
+			if err := c.DeleteFile(path.Join(remoteDir, rel)); err != nil {
+				return nil, err
+			}
+			stats.Deleted++
+		}
+	}
+	return stats, nil
+}
+
+// SyncDown mirrors remoteDir into localDir: remote files missing locally
+// or differing by size or modification time are downloaded, and with
+// opts.Delete local files absent remotely are removed.
+func (c *Client) SyncDown(remoteDir, localDir string, opts SyncOptions) (*SyncStats, error) {
+	remote, err := c.listRecursive(remoteDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &SyncStats{}
+	for rel, rf := range remote {
+		if rf.IsDir || !opts.selected(rel) {
+			continue
+		}
+		localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+		fi, err := os.Stat(localPath)
+		if err == nil && !c.remoteChanged(localPath, fi, rf) {
+			stats.Skipped++
+			continue
+		}
+		if err := c.DownloadFile(path.Join(remoteDir, rel), localPath); err != nil {
+			return nil, err
+		}
+		stats.Uploaded++ // counts transferred files regardless of direction
+	}
+
+	if opts.Delete {
+		err := filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.Mode().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(localDir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if !opts.selected(rel) {
+				return nil
+			}
+			if _, ok := remote[rel]; !ok {
+				if err := os.Remove(p); err != nil {
+					return err
+				}
+				stats.Deleted++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// remoteChanged reports whether the remote file differs from its local
+// counterpart. Sizes are compared first; a modification-time difference
+// beyond the skew tolerance only counts as a change when the content
+// hashes also differ, so skewed clocks do not force re-transfers.
+func (c *Client) remoteChanged(localPath string, fi os.FileInfo, rf api.FileInfo) bool {
+	if fi.Size() != rf.Size {
+		return true
+	}
+	if !rf.ModTime.After(fi.ModTime().Add(c.skew())) {
+		return false
+	}
+	return !sameContent(localPath, rf)
+}
+
+// fileChanged reports whether the local file differs from its remote
+// counterpart, with the same size/mtime/hash cascade as remoteChanged.
+func (c *Client) fileChanged(localPath string, fi os.FileInfo, rf api.FileInfo) bool {
+	if fi.Size() != rf.Size {
+		return true
+	}
+	if !fi.ModTime().After(rf.ModTime.Add(c.skew())) {
+		return false
+	}
+	return !sameContent(localPath, rf)
+}
+
+// sameContent reports whether the local file's hash matches the remote
+// entry's. An unknown remote hash or a local read error counts as a
+// difference so the transfer errs on the side of syncing.
+func sameContent(localPath string, rf api.FileInfo) bool {
+	if rf.Hash == "" {
+		return false
+	}
+	sum, err := hash.HashFile(localPath)
+	return err == nil && sum == rf.Hash
+}
+
+// listRecursive fetches the remote tree below remoteDir in one request,
+// including hashes so skewed timestamps can fall back to content
+// comparison, and returns files keyed by path relative to remoteDir. A
+// missing remote directory yields an empty map so a first sync can
+// proceed.
+func (c *Client) listRecursive(remoteDir string) (map[string]api.FileInfo, error) {
+	files, err := c.ListTree(remoteDir, true)
+	if err != nil {
+		return map[string]api.FileInfo{}, nil
+	}
+	out := make(map[string]api.FileInfo, len(files))
+	for _, f := range files {
+		rel, err := relRemote(remoteDir, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		out[rel] = f
+	}
+	return out, nil
+}
+
+// relRemote returns p relative to the remote base directory.
+func relRemote(base, p string) (string, error) {
+	base = path.Clean("/" + base)
+	p = path.Clean("/" + p)
+	if base == "/" {
+		return p[1:], nil
+	}
+	if p == base {
+		return ".", nil
+	}
+	if len(p) > len(base) && p[:len(base)] == base && p[len(base)] == '/' {
+		return p[len(base)+1:], nil
+	}
+	return "", fmt.Errorf("remote path %q outside %q", p, base)
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncUploadsOnlyChangedFiles(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"a.txt":     "aaa",
+		"sub/b.txt": "bbb",
+	})
+
+	stats, err := c.Sync(local, "mirror", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 2 {
+		t.Errorf("first sync uploaded %d, want 2", stats.Uploaded)
+	}
+
+	// Unchanged tree: nothing to transfer.
+	stats, err = c.Sync(local, "mirror", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 0 || stats.Skipped != 2 {
+		t.Errorf("second sync = %+v, want 0 uploaded / 2 skipped", stats)
+	}
+
+	// One modified file with a newer mtime: exactly one upload.
+	writeTree(t, local, map[string]string{"a.txt": "aaaa"})
+	stats, err = c.Sync(local, "mirror", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 1 {
+		t.Errorf("third sync uploaded %d, want 1", stats.Uploaded)
+	}
+}
+
+func TestSyncToleratesClockSkew(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"a.txt": "same content"})
+	if _, err := c.Sync(local, "skew", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A clock an hour ahead makes the unchanged file look newer; the
+	// matching hash must prevent a re-transfer.
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(local, "a.txt"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Sync(local, "skew", SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 0 || stats.Skipped != 1 {
+		t.Errorf("skewed sync = %+v, want 0 uploaded / 1 skipped", stats)
+	}
+}
+
+func TestSyncOptionsSelection(t *testing.T) {
+	opts := SyncOptions{Include: []string{"*.txt"}, Exclude: []string{"secret*"}}
+	cases := map[string]bool{
+		"a.txt":        true,
+		"sub/b.txt":    true, // base name matches *.txt
+		"secret.txt":   false,
+		"image.png":    false,
+		"sub/note.txt": true,
+	}
+	for rel, want := range cases {
+		if got := opts.selected(rel); got != want {
+			t.Errorf("selected(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestSyncHonorsExclude(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"keep.txt": "k", "skip.log": "s"})
+	stats, err := c.Sync(local, "sel", SyncOptions{Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 1 {
+		t.Errorf("uploaded %d, want 1", stats.Uploaded)
+	}
+}
+
+func TestSyncDownMirrorsRemote(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"a.txt": "aaa", "sub/b.txt": "bbb"})
+	if _, err := c.Sync(src, "remote", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	stats, err := c.SyncDown("remote", dst, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Uploaded != 2 {
+		t.Errorf("downloaded %d, want 2", stats.Uploaded)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	if err != nil || string(data) != "bbb" {
+		t.Errorf("downloaded content = %q, err %v", data, err)
+	}
+}
+
+func TestSyncDeleteRemovesRemoteOnly(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"keep.txt": "k", "drop.txt": "d"})
+	if _, err := c.Sync(local, "mirror", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filepath.Join(local, "drop.txt")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Sync(local, "mirror", SyncOptions{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Deleted != 1 {
+		t.Errorf("deleted = %d, want 1", stats.Deleted)
+	}
+	files, err := c.ListFiles("mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "keep.txt" {
+		t.Errorf("remote after delete = %+v", files)
+	}
+}
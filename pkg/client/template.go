@@ -0,0 +1,33 @@
+package client
+
+import "fmt"
+
+// UploadTemplate is a named bundle of upload settings in the client
+// config, applied with `client upload -template <name>` so scripts do
+// not repeat long flag lists. Remote is a destination spec accepted by
+// ResolveRemote (a server URL or profile:path); the selection globs and
+// Replace mirror the upload command's flags.
+type UploadTemplate struct {
+	Remote  string   `json:"remote,omitempty"`
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Replace bool     `json:"replace,omitempty"`
+}
+
+// Template returns the named upload template from the config.
+func (cfg ClientConfig) Template(name string) (UploadTemplate, error) {
+	t, ok := cfg.Templates[name]
+	if !ok {
+		return UploadTemplate{}, fmt.Errorf("unknown upload template %q", name)
+	}
+	return t, nil
+}
+
+// Options returns the template's selection rules as SyncOptions, with
+// globs given on the command line appended after the template's own.
+func (t UploadTemplate) Options(include, exclude []string) SyncOptions {
+	return SyncOptions{
+		Include: append(append([]string{}, t.Include...), include...),
+		Exclude: append(append([]string{}, t.Exclude...), exclude...),
+	}
+}
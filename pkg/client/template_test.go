@@ -0,0 +1,38 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUploadTemplateLookupAndMerge(t *testing.T) {
+	cfg := ClientConfig{Templates: map[string]UploadTemplate{
+		"nightly": {
+			Remote:  "prod:releases/nightly",
+			Include: []string{"*.tar.gz"},
+			Exclude: []string{"*.tmp"},
+			Replace: true,
+		},
+	}}
+
+	tpl, err := cfg.Template("nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Remote != "prod:releases/nightly" || !tpl.Replace {
+		t.Errorf("template = %+v", tpl)
+	}
+
+	// Command-line globs are appended after the template's own.
+	opts := tpl.Options([]string{"*.sha256"}, nil)
+	if want := []string{"*.tar.gz", "*.sha256"}; !reflect.DeepEqual(opts.Include, want) {
+		t.Errorf("Include = %v, want %v", opts.Include, want)
+	}
+	if want := []string{"*.tmp"}; !reflect.DeepEqual(opts.Exclude, want) {
+		t.Errorf("Exclude = %v, want %v", opts.Exclude, want)
+	}
+
+	if _, err := cfg.Template("weekly"); err == nil {
+		t.Error("unknown template did not error")
+	}
+}
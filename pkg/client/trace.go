@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"sync"
+	"time"
+)
+
+// traceBodyLimit caps how many body bytes are written to the trace file
+// per request or response.
+const traceBodyLimit = 64 << 10
+
+// redactedHeaders are replaced with a placeholder in trace output so
+// trace files can be shared without leaking credentials.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// traceTransport wraps a RoundTripper and writes every HTTP exchange,
+// including connection-level events from httptrace, to a log destination.
+type traceTransport struct {
+	base   http.RoundTripper
+	bodies bool
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// newTraceTransport returns a RoundTripper that logs exchanges to out.
+// When bodies is true, request and response bodies are included up to
+// traceBodyLimit bytes each.
+func newTraceTransport(base http.RoundTripper, out io.Writer, bodies bool) *traceTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base, out: out, bodies: bodies}
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var buf bytes.Buffer
+	start := time.Now()
+	stamp := func(format string, args ...any) {
+		fmt.Fprintf(&buf, "[%8.3fms] %s\n", float64(time.Since(start).Microseconds())/1000, fmt.Sprintf(format, args...))
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(i httptrace.DNSStartInfo) { stamp("dns lookup %s", i.Host) },
+		DNSDone: func(i httptrace.DNSDoneInfo) {
+			stamp("dns done addrs=%v err=%v", i.Addrs, i.Err)
+		},
+		ConnectStart: func(network, addr string) { stamp("connecting %s %s", network, addr) },
+		ConnectDone: func(network, addr string, err error) {
+			stamp("connected %s %s err=%v", network, addr, err)
+		},
+		TLSHandshakeStart: func() { stamp("tls handshake start") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			stamp("tls handshake done version=%x cipher=%x err=%v", state.Version, state.CipherSuite, err)
+		},
+		GotConn: func(i httptrace.GotConnInfo) {
+			stamp("got connection reused=%v idle=%v", i.Reused, i.WasIdle)
+		},
+		GotFirstResponseByte: func() { stamp("first response byte") },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	if dump, err := httputil.DumpRequestOut(redactRequest(req), false); err == nil {
+		writeDump(&buf, ">", dump)
+	}
+	// Bodies are recorded as the transport actually streams them, so the
+	// live request body is never consumed by the dump itself.
+	var reqBody *bodyRecorder
+	if t.bodies && req.Body != nil && dumpableBody(req.Header) {
+		reqBody = &bodyRecorder{rc: req.Body}
+		req.Body = reqBody
+	}
+
+	resp, rtErr := t.base.RoundTrip(req)
+	if reqBody != nil {
+		writeDump(&buf, ">", reqBody.buf.Bytes())
+	}
+	if rtErr != nil {
+		stamp("round trip error: %v", rtErr)
+	} else {
+		if dump, err := httputil.DumpResponse(redactResponse(resp), false); err == nil {
+			writeDump(&buf, "<", dump)
+		}
+		stamp("status %s", resp.Status)
+	}
+
+	t.mu.Lock()
+	fmt.Fprintf(t.out, "---- %s %s %s\n", start.Format(time.RFC3339), req.Method, req.URL)
+	t.out.Write(buf.Bytes())
+	t.mu.Unlock()
+
+	if rtErr == nil && t.bodies && dumpableBody(resp.Header) {
+		// Stream the response body into the trace as the caller reads it.
+		resp.Body = &tracedResponseBody{rc: resp.Body, t: t}
+	}
+	return resp, rtErr
+}
+
+// bodyRecorder tees the first traceBodyLimit bytes of a body into a
+// buffer while the transport reads it.
+type bodyRecorder struct {
+	rc  io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if n > 0 && b.buf.Len() < traceBodyLimit {
+		keep := n
+		if b.buf.Len()+keep > traceBodyLimit {
+			keep = traceBodyLimit - b.buf.Len()
+		}
+		b.buf.Write(p[:keep])
+	}
+	return n, err
+}
+
+func (b *bodyRecorder) Close() error { return b.rc.Close() }
+
+// tracedResponseBody writes the recorded response body to the trace when
+// the caller finishes with it.
+type tracedResponseBody struct {
+	rc  io.ReadCloser
+	t   *traceTransport
+	buf bytes.Buffer
+}
+
+func (b *tracedResponseBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if n > 0 && b.buf.Len() < traceBodyLimit {
+		keep := n
+		if b.buf.Len()+keep > traceBodyLimit {
+			keep = traceBodyLimit - b.buf.Len()
+		}
+		b.buf.Write(p[:keep])
+	}
+	return n, err
+}
+
+func (b *tracedResponseBody) Close() error {
+	err := b.rc.Close()
+	b.t.mu.Lock()
+	writeDump(b.t.out, "<", b.buf.Bytes())
+	b.t.mu.Unlock()
+	return err
+}
+
+// redactRequest returns a copy of req with sensitive headers replaced,
+// suitable for header-only dumping. DumpRequestOut with body=false never
+// reads the shared body.
+func redactRequest(req *http.Request) *http.Request {
+	r := req.Clone(req.Context())
+	redactHeader(r.Header)
+	return r
+}
+
+func redactResponse(resp *http.Response) *http.Response {
+	r := *resp
+	r.Header = resp.Header.Clone()
+	redactHeader(r.Header)
+	return &r
+}
+
+func redactHeader(h http.Header) {
+	for _, name := range redactedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, "[REDACTED]")
+		}
+	}
+}
+
+// dumpableBody reports whether a body looks like text worth dumping;
+// binary transfer payloads are skipped to keep trace files readable.
+func dumpableBody(h http.Header) bool {
+	ct := h.Get("Content-Type")
+	switch {
+	case ct == "",
+		len(ct) >= 5 && ct[:5] == "text/",
+		len(ct) >= 16 && ct[:16] == "application/json":
+		return true
+	}
+	return false
+}
+
+func writeDump(w io.Writer, prefix string, dump []byte) {
+	if len(dump) > traceBodyLimit {
+		dump = append(dump[:traceBodyLimit], []byte("\n[truncated]\n")...)
+	}
+	for _, line := range bytes.Split(bytes.TrimRight(dump, "\r\n"), []byte("\n")) {
+		fmt.Fprintf(w, "%s %s\n", prefix, bytes.TrimRight(line, "\r"))
+	}
+}
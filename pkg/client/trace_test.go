@@ -0,0 +1,46 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceTransportRedactsAndLogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer ts.Close()
+
+	var out bytes.Buffer
+	hc := &http.Client{Transport: newTraceTransport(nil, &out, true)}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/list", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	resp, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	trace := out.String()
+	if strings.Contains(trace, "secret-token") {
+		t.Error("trace leaked the Authorization header")
+	}
+	if !strings.Contains(trace, "[REDACTED]") {
+		t.Error("trace should contain redaction placeholder")
+	}
+	if !strings.Contains(trace, "GET /api/list") {
+		t.Errorf("trace missing request line:\n%s", trace)
+	}
+	if !strings.Contains(trace, "200 OK") {
+		t.Errorf("trace missing response status:\n%s", trace)
+	}
+	if !strings.Contains(trace, `{"ok":true}`) {
+		t.Errorf("trace missing response body:\n%s", trace)
+	}
+}
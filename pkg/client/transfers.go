@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// ListTransfers returns every transfer known to the server, newest
+// first.
+func (c *Client) ListTransfers() ([]api.TransferStatus, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url("/api/status"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return nil, fmt.Errorf("list transfers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var list []api.TransferStatus
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode transfer list: %w", err)
+	}
+	return list, nil
+}
+
+// CancelTransfer cancels the running transfer with the given ID.
+func (c *Client) CancelTransfer(id string) error {
+	return c.transferAction(http.MethodDelete, "/api/status/"+id)
+}
+
+// PauseTransfer suspends the running transfer at its next file boundary.
+func (c *Client) PauseTransfer(id string) error {
+	return c.transferAction(http.MethodPost, "/api/status/"+id+"/pause")
+}
+
+// ResumeTransfer continues a paused transfer.
+func (c *Client) ResumeTransfer(id string) error {
+	return c.transferAction(http.MethodPost, "/api/status/"+id+"/resume")
+}
+
+func (c *Client) transferAction(method, path string) error {
+	req, err := http.NewRequest(method, c.url(path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,419 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// UploadFile uploads the file at localPath so it is stored on the server
+// as remotePath, retrying transient failures per the retry config.
+func (c *Client) UploadFile(localPath, remotePath string) (*api.UploadResult, error) {
+	var res *api.UploadResult
+	err := c.withRetry("upload "+localPath, func() error {
+		var err error
+		res, err = c.uploadFile(localPath, remotePath)
+		return err
+	})
+	return res, err
+}
+
+// uploadFile performs one upload attempt.
+func (c *Client) uploadFile(localPath, remotePath string) (*api.UploadResult, error) {
+	// The local checksum is declared to the server per part so corruption
+	// in transit is rejected rather than stored; it also backs the dedupe
+	// window.
+	localSum, err := hash.HashFile(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if c.dedupe != nil && c.dedupe.Seen(remotePath, localSum) {
+		c.logger.Infof("skipped duplicate %s (uploaded within dedupe window)", remotePath)
+		fi, err := os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+		return &api.UploadResult{Path: remotePath, Size: fi.Size(), Hash: localSum}, nil
+	}
+	// A cheap content probe makes re-running a folder upload after a
+	// partial failure nearly instant: files the server already has are
+	// skipped without streaming a byte.
+	if c.HasContent(remotePath, localSum) {
+		c.logger.Infof("skipped %s (server already has identical content)", remotePath)
+		fi, err := os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+		return &api.UploadResult{Path: remotePath, Size: fi.Size(), Hash: localSum}, nil
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	// Hash and count the bytes as they are streamed so the server's
+	// commit trailer can be verified afterwards.
+	th := hash.NewTeeHasher()
+	var sent int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		part, err := createFilePart(mw, filepath.ToSlash(remotePath), localSum, fi.ModTime())
+		if err == nil {
+			sent, err = copyTuned(th.Writer(part), c.bw.Reader(f), c.chunks)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, c.url("/upload"), pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upload %s: %w", localPath, err)
+	}
+	defer resp.Body.Close()
+
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		return nil, fmt.Errorf("decode upload response: %w", err)
+	}
+	if len(ur.Files) != 1 {
+		return nil, fmt.Errorf("expected 1 file in upload response, got %d", len(ur.Files))
+	}
+	if ur.Files[0].Error != "" {
+		return nil, fmt.Errorf("server failed to store %s: %s", remotePath, ur.Files[0].Error)
+	}
+	// Trailers arrive only after the body is fully consumed.
+	io.Copy(io.Discard, resp.Body)
+	<-done
+	if err := checkCommitTrailers(resp, sent, th.Sum()); err != nil {
+		return nil, fmt.Errorf("upload %s: %w", localPath, err)
+	}
+	if c.dedupe != nil {
+		c.dedupe.Record(remotePath, localSum)
+	}
+	if r := ur.Files[0].Receipt; r != nil && c.cfg.ReceiptDir != "" {
+		if err := c.saveReceipt(r); err != nil {
+			c.logger.Errorf("store receipt for %s: %v", remotePath, err)
+		}
+	}
+	return &ur.Files[0], nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, `\"`)
+
+// createFilePart writes the headers for one "file" part, carrying the
+// relative filename, the sender's checksum for server-side verification
+// and the original modification time so stored trees keep their
+// timestamps.
+func createFilePart(mw *multipart.Writer, name, sum string, modTime time.Time) (io.Writer, error) {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(name)))
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set(api.FileHashHeader, sum)
+	h.Set(api.FileMtimeHeader, modTime.UTC().Format(time.RFC3339Nano))
+	return mw.CreatePart(h)
+}
+
+// checkCommitTrailers compares the server's commit trailers against what
+// was streamed. Only bytes the server confirms committed may be treated
+// as transferred; a mismatch means the upload was cut short and must be
+// retried. Servers that send no trailers are accepted as-is.
+func checkCommitTrailers(resp *http.Response, sent int64, sum string) error {
+	if v := resp.Trailer.Get(api.CommitSizeTrailer); v != "" {
+		committed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad %s trailer %q", api.CommitSizeTrailer, v)
+		}
+		if committed != sent {
+			return fmt.Errorf("server committed %d of %d bytes", committed, sent)
+		}
+	}
+	if v := resp.Trailer.Get(api.CommitHashTrailer); v != "" && v != sum {
+		return fmt.Errorf("server committed hash %s, sent %s", v, sum)
+	}
+	return nil
+}
+
+// UploadFolderTar uploads localDir as a single tar.gz stream that the
+// server extracts under remotePrefix. One request for the whole tree
+// cuts per-file HTTP overhead and preserves structure, empty
+// directories and modification times; the trade-off against
+// UploadFolder is no per-file parallelism or content probes. The same
+// include/exclude globs and .uploadignore rules apply.
+func (c *Client) UploadFolderTar(localDir, remotePrefix string, opts SyncOptions) error {
+	return c.withRetry("upload archive "+localDir, func() error {
+		return c.uploadFolderTar(localDir, remotePrefix, opts, false)
+	})
+}
+
+// UploadFolderReplace uploads localDir like UploadFolderTar, but the
+// server stages the tree and swaps it in under remotePrefix wholesale:
+// remote files absent from localDir are gone afterwards, instead of
+// surviving a merge.
+func (c *Client) UploadFolderReplace(localDir, remotePrefix string, opts SyncOptions) error {
+	return c.withRetry("replace "+remotePrefix, func() error {
+		return c.uploadFolderTar(localDir, remotePrefix, opts, true)
+	})
+}
+
+func (c *Client) uploadFolderTar(localDir, remotePrefix string, opts SyncOptions, replace bool) error {
+	ignored, err := loadUploadIgnore(localDir)
+	if err != nil {
+		return err
+	}
+	opts.Exclude = append(opts.Exclude, ignored...)
+
+	// Trees of mostly already-compressed files go up as plain tar; the
+	// server detects either stream form by the gzip magic.
+	comp := archiveCompression(localDir, opts)
+	if comp == "none" {
+		c.logger.Infof("uploading %s as plain tar (contents are mostly already compressed)", localDir)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		var gz *gzip.Writer
+		out := io.Writer(pw)
+		if comp != "none" {
+			gz = gzip.NewWriter(pw)
+			out = gz
+		}
+		tw := tar.NewWriter(out)
+		err := filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(localDir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == "." {
+				return nil
+			}
+			if fi.IsDir() && matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			if !fi.IsDir() && (!fi.Mode().IsRegular() || !opts.selected(rel)) {
+				return nil
+			}
+			hdr, err := tar.FileInfoHeader(fi, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			if fi.IsDir() {
+				hdr.Name += "/"
+				return tw.WriteHeader(hdr)
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		if err == nil && gz != nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	v := url.Values{"dest": {remotePrefix}}
+	if replace {
+		v.Set("replace", "true")
+	}
+	req, err := http.NewRequest(http.MethodPost,
+		c.url("/api/upload/archive")+"?"+v.Encode(), c.bw.Reader(pr))
+	if err != nil {
+		return err
+	}
+	ct := "application/gzip"
+	if comp == "none" {
+		ct = "application/x-tar"
+	}
+	req.Header.Set("Content-Type", ct)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("upload archive %s: %w", localDir, err)
+	}
+	defer resp.Body.Close()
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		return fmt.Errorf("decode upload response: %w", err)
+	}
+	c.logger.Infof("uploaded %d files from %s as one archive stream", len(ur.Files), localDir)
+	return nil
+}
+
+// UploadFolder walks localDir and uploads every regular file beneath it
+// under remotePrefix, preserving the folder structure, with the configured
+// level of parallelism. Files are filtered by the include/exclude globs
+// in opts plus any patterns from a .uploadignore file in localDir;
+// excluded directories are skipped without descending into them.
+func (c *Client) UploadFolder(localDir, remotePrefix string, opts SyncOptions) error {
+	ignored, err := loadUploadIgnore(localDir)
+	if err != nil {
+		return err
+	}
+	opts.Exclude = append(opts.Exclude, ignored...)
+
+	type job struct {
+		local  string
+		rel    string
+		remote string
+		size   int64
+	}
+	var jobs []job
+	var emptyDirs []string
+	var total int64
+	err = filepath.Walk(localDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			if rel != "." && matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			// Empty directories have no file to carry them; they are
+			// created explicitly via mkdir below.
+			if rel != "." {
+				if entries, err := os.ReadDir(path); err == nil && len(entries) == 0 {
+					emptyDirs = append(emptyDirs, filepath.ToSlash(filepath.Join(remotePrefix, rel)))
+				}
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || !opts.selected(rel) {
+			return nil
+		}
+		jobs = append(jobs, job{
+			local:  path,
+			rel:    rel,
+			remote: filepath.ToSlash(filepath.Join(remotePrefix, rel)),
+			size:   fi.Size(),
+		})
+		total += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, dir := range emptyDirs {
+		if err := c.Mkdir(dir); err != nil {
+			return err
+		}
+	}
+	if len(jobs) == 0 {
+		if len(emptyDirs) > 0 {
+			return nil
+		}
+		return fmt.Errorf("no files found under %s", localDir)
+	}
+
+	journal := c.openJournal(localDir, remotePrefix, len(jobs), total)
+	if journal != nil && len(journal.done) > 0 {
+		c.logger.Infof("resuming upload of %s: journal %s confirms %d files from an earlier run",
+			localDir, journal.meta.ID, len(journal.done))
+	}
+
+	progress := c.newProgress(len(jobs), total)
+	defer progress.Finish()
+
+	ch := make(chan job)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i := 0; i < c.parallelUploads(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				// A journal entry with a matching local hash was stored
+				// by an earlier run; no need to even probe the server.
+				if journal != nil {
+					if sum, ok := journal.completed(j.rel); ok {
+						if cur, err := hash.HashFile(j.local); err == nil && cur == sum {
+							progress.FileDone(j.size)
+							continue
+						}
+					}
+				}
+				progress.StartFile(j.rel)
+				res, err := c.UploadFile(j.local, j.remote)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				if journal != nil {
+					if err := journal.record(j.rel, res.Hash); err != nil {
+						c.logger.Errorf("upload journal: %v", err)
+					}
+				}
+				progress.FileDone(j.size)
+			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+	if journal != nil {
+		journal.finish(firstErr == nil)
+	}
+	return firstErr
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestUploadRejectsShortCommit(t *testing.T) {
+	// A server that acknowledges the upload but whose commit trailer
+	// reports fewer bytes than were sent, as after a partial write.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/upload" {
+			http.NotFound(w, r) // content probes must not report a match
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Trailer", api.CommitSizeTrailer)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(api.UploadResponse{
+			Files: []api.UploadResult{{Path: "f.txt", Size: 1}},
+		})
+		w.Header().Set(api.CommitSizeTrailer, "1")
+	}))
+	defer ts.Close()
+
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL, Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(local, []byte("full content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.UploadFile(local, "f.txt")
+	if err == nil || !strings.Contains(err.Error(), "committed") {
+		t.Errorf("err = %v, want commit mismatch", err)
+	}
+}
+
+func TestUploadPreservesModTime(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := filepath.Join(t.TempDir(), "old.txt")
+	if err := os.WriteFile(local, []byte("timestamped"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	if err := os.Chtimes(local, want, want); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(local, "old.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := c.Stat("old.txt", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime.Equal(want) {
+		t.Errorf("stored mtime = %s, want %s", info.ModTime, want)
+	}
+
+	// And back down again: the downloaded copy keeps the timestamp
+	// (Last-Modified is whole-second precision).
+	dst := filepath.Join(t.TempDir(), "back.txt")
+	if err := c.DownloadFile("old.txt", dst); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Truncate(time.Second).Equal(want) {
+		t.Errorf("downloaded mtime = %s, want %s", fi.ModTime(), want)
+	}
+}
+
+func TestUploadFolderTarRoundTrip(t *testing.T) {
+	ts := httptest.NewServer(serverForTest(t))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"a.txt":     "alpha",
+		"sub/b.txt": "beta",
+	})
+	if err := c.UploadFolderTar(src, "tarred", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := c.ListTree("tarred", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, f := range tree {
+		got[f.Path] = true
+	}
+	if !got["tarred/a.txt"] || !got["tarred/sub/b.txt"] {
+		t.Errorf("remote tree = %+v", tree)
+	}
+
+	dst := filepath.Join(t.TempDir(), "b.txt")
+	if err := c.DownloadFile("tarred/sub/b.txt", dst); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "beta" {
+		t.Errorf("downloaded %q", data)
+	}
+}
+
+func TestUploadSkipsIdenticalContent(t *testing.T) {
+	var uploads atomic.Int32
+	ts := httptest.NewServer(countingHandler(serverForTest(t), &uploads))
+	defer ts.Close()
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(local, []byte("same bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := c.UploadFile(local, "probe/f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.UploadFile(local, "probe/f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := uploads.Load(); got != 1 {
+		t.Errorf("POST /upload count = %d, want 1", got)
+	}
+	if second.Hash != first.Hash || second.Size != first.Size {
+		t.Errorf("skipped result = %+v, want %+v", second, first)
+	}
+
+	// Different content at the same path must still be uploaded.
+	if err := os.WriteFile(local, []byte("changed bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UploadFile(local, "probe/f.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := uploads.Load(); got != 2 {
+		t.Errorf("POST /upload count after change = %d, want 2", got)
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Versions lists the archived versions of remotePath, oldest first. The
+// server only archives versions when versioning is enabled, so the list
+// may be empty even for files that were overwritten.
+func (c *Client) Versions(remotePath string) ([]api.VersionInfo, error) {
+	var versions []api.VersionInfo
+	err := c.withRetry("versions "+remotePath, func() error {
+		v := url.Values{"path": {remotePath}}
+		req, err := http.NewRequest(http.MethodGet, c.url("/api/versions")+"?"+v.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.doMeta(req)
+		if err != nil {
+			return fmt.Errorf("versions %s: %w", remotePath, err)
+		}
+		defer resp.Body.Close()
+		return json.NewDecoder(resp.Body).Decode(&versions)
+	})
+	return versions, err
+}
+
+// RestoreVersion puts archived version v back as the current content of
+// remotePath. The content it replaces is archived first, so a restore
+// can itself be undone.
+func (c *Client) RestoreVersion(remotePath string, version int) error {
+	v := url.Values{"path": {remotePath}, "version": {strconv.Itoa(version)}}
+	req, err := http.NewRequest(http.MethodPost, c.url("/api/versions/restore")+"?"+v.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.doMeta(req)
+	if err != nil {
+		return fmt.Errorf("restore %s@v%d: %w", remotePath, version, err)
+	}
+	resp.Body.Close()
+	return nil
+}
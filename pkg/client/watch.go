@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// defaultWatchInterval is how often watch mode rescans the tree when the
+// options do not set their own interval.
+const defaultWatchInterval = 2 * time.Second
+
+// WatchOptions control Client.Watch.
+type WatchOptions struct {
+	// Include and Exclude select files the way SyncOptions do; the
+	// directory's .uploadignore patterns are added to Exclude.
+	Include []string
+	Exclude []string
+
+	// Interval is how often the tree is rescanned (default 2s).
+	Interval time.Duration
+
+	// Debounce is how long a changed file must sit unchanged before it
+	// is uploaded, so editors writing in several steps do not trigger
+	// half-finished transfers. The default is one interval.
+	Debounce time.Duration
+}
+
+// watchEntry is the last observed state of one watched file. A zero
+// changed time means the file is in sync with the server.
+type watchEntry struct {
+	size    int64
+	mtime   time.Time
+	changed time.Time
+}
+
+// Watch mirrors localDir onto remoteDir continuously until ctx is done:
+// an initial sync converges the two, then the tree is polled and new or
+// changed files are uploaded once they have been quiet for the debounce
+// window — a lightweight one-way sync daemon. Polling keeps the client
+// free of platform notification APIs; local deletions are left alone on
+// the server. Failed uploads are logged and retried on later scans, on
+// top of the client's per-request retry budget.
+func (c *Client) Watch(ctx context.Context, localDir, remoteDir string, opts WatchOptions) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = interval
+	}
+	ignored, err := loadUploadIgnore(localDir)
+	if err != nil {
+		return err
+	}
+	sel := SyncOptions{Include: opts.Include, Exclude: append(ignored, opts.Exclude...)}
+
+	// The baseline snapshot is taken before the initial sync: a file
+	// that appears while Sync walks the tree is then picked up as new on
+	// the next tick (at worst uploaded twice) instead of being absorbed
+	// as already in sync and missed on every later scan.
+	entries := make(map[string]*watchEntry)
+	if err := scanWatch(localDir, sel, entries, time.Time{}); err != nil {
+		return err
+	}
+	if _, err := c.Sync(localDir, remoteDir, sel); err != nil {
+		return err
+	}
+	c.logger.Infof("watching %s for changes (every %s)", localDir, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		now := time.Now()
+		if err := scanWatch(localDir, sel, entries, now); err != nil {
+			c.logger.Errorf("watch scan: %v", err)
+			continue
+		}
+		for rel, e := range entries {
+			if e.changed.IsZero() || now.Sub(e.changed) < debounce {
+				continue
+			}
+			local := filepath.Join(localDir, filepath.FromSlash(rel))
+			if _, err := c.UploadFile(local, path.Join(remoteDir, rel)); err != nil {
+				c.logger.Errorf("watch upload %s: %v", rel, err)
+				continue
+			}
+			c.logger.Infof("watch uploaded %s", rel)
+			e.changed = time.Time{}
+		}
+	}
+}
+
+// scanWatch folds the current state of localDir into entries, stamping
+// files whose size or mtime moved with now and dropping files that no
+// longer exist so a re-created file counts as a change again.
+func scanWatch(localDir string, sel SyncOptions, entries map[string]*watchEntry, now time.Time) error {
+	present := make(map[string]bool, len(entries))
+	err := filepath.Walk(localDir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !sel.selected(rel) {
+			return nil
+		}
+		present[rel] = true
+		e, ok := entries[rel]
+		if !ok {
+			entries[rel] = &watchEntry{size: fi.Size(), mtime: fi.ModTime(), changed: now}
+			return nil
+		}
+		if e.size != fi.Size() || !e.mtime.Equal(fi.ModTime()) {
+			e.size, e.mtime, e.changed = fi.Size(), fi.ModTime(), now
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for rel := range entries {
+		if !present[rel] {
+			delete(entries, rel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/server"
+)
+
+// waitForContent polls until path holds exactly want.
+func waitForContent(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if b, err := os.ReadFile(path); err == nil && string(b) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s never reached content %q", path, want)
+}
+
+func TestWatchUploadsNewAndChangedFiles(t *testing.T) {
+	uploadDir := t.TempDir()
+	s, err := server.NewServer(server.ServerConfig{UploadDir: uploadDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	local := t.TempDir()
+	if err := os.WriteFile(filepath.Join(local, "initial.txt"), []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Watch(ctx, local, "mirror", WatchOptions{Interval: 20 * time.Millisecond})
+	}()
+
+	// The initial sync converges the existing tree.
+	waitForContent(t, filepath.Join(uploadDir, "mirror", "initial.txt"), "first")
+
+	if err := os.WriteFile(filepath.Join(local, "new.txt"), []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForContent(t, filepath.Join(uploadDir, "mirror", "new.txt"), "second")
+
+	if err := os.WriteFile(filepath.Join(local, "new.txt"), []byte("second, edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForContent(t, filepath.Join(uploadDir, "mirror", "new.txt"), "second, edited")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch returned %v, want context.Canceled", err)
+	}
+}
+
+func TestWatchHonorsIgnoreFile(t *testing.T) {
+	uploadDir := t.TempDir()
+	s, err := server.NewServer(server.ServerConfig{UploadDir: uploadDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	local := t.TempDir()
+	if err := os.WriteFile(filepath.Join(local, ".uploadignore"), []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(ClientConfig{ServerURL: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Watch(ctx, local, "mirror", WatchOptions{
+		Interval: 20 * time.Millisecond,
+		Exclude:  []string{".uploadignore"},
+	})
+
+	if err := os.WriteFile(filepath.Join(local, "scratch.tmp"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(local, "kept.txt"), []byte("y"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForContent(t, filepath.Join(uploadDir, "mirror", "kept.txt"), "y")
+	if _, err := os.Stat(filepath.Join(uploadDir, "mirror", "scratch.tmp")); !os.IsNotExist(err) {
+		t.Errorf("ignored scratch.tmp was uploaded")
+	}
+}
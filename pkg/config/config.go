@@ -0,0 +1,110 @@
+// Package config loads configuration files for the server and client
+// binaries. Configuration is JSON by default; files named *.yaml, *.yml
+// or *.toml are decoded with built-in subset parsers so no extra
+// dependencies are pulled in. Every format maps onto the caller's
+// struct through its json tags, so field names are identical across
+// formats.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Load reads the config file at path into v, choosing the format by
+// extension. A missing file is an error; callers that treat
+// configuration as optional should check for it with os.IsNotExist
+// before calling Load.
+func Load(path string, v any) error {
+	return load(path, v, false)
+}
+
+// LoadStrict is Load with unknown keys rejected, so a misspelled key
+// becomes an error instead of silently leaving its field at the
+// default. It backs the validate commands; normal startup stays
+// lenient so configs written for newer versions still load.
+func LoadStrict(path string, v any) error {
+	return load(path, v, true)
+}
+
+func load(path string, v any, strict bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		doc, err = parseYAML(data)
+	case ".toml":
+		doc, err = parseTOML(data)
+	default:
+		doc = nil
+	}
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if doc != nil {
+		// Re-encode through JSON so the caller's struct tags apply the
+		// same way in every format.
+		data, err = json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// Save writes v to path as indented JSON, creating or truncating the file.
+func Save(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Generate renders v as a config file in the named format: "json"
+// (default), "yaml" or "toml". Field names and omissions follow the
+// struct's json tags in every format, so a generated sample loads back
+// unchanged.
+func Generate(v any, format string) ([]byte, error) {
+	switch format {
+	case "", "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml", "yml", "toml":
+	default:
+		return nil, fmt.Errorf("unknown config format %q (want json, yaml or toml)", format)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// Decode with Number so integers survive the trip instead of
+	// turning into floats.
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if format == "toml" {
+		return encodeTOML(doc)
+	}
+	return encodeYAML(doc), nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConfig mirrors the shape of the real configs: scalars, a nested
+// map and a list of structs.
+type testConfig struct {
+	Server    string             `json:"default_server"`
+	ChunkSize int64              `json:"chunk_size"`
+	Debug     bool               `json:"debug,omitempty"`
+	Profiles  map[string]profile `json:"profiles,omitempty"`
+	Mappings  []mapping          `json:"sync_mappings,omitempty"`
+}
+
+type profile struct {
+	ServerURL string `json:"server_url"`
+	Token     string `json:"token,omitempty"`
+}
+
+type mapping struct {
+	Name    string   `json:"name"`
+	Include []string `json:"include,omitempty"`
+}
+
+func loadString(t *testing.T, name, content string) testConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var cfg testConfig
+	if err := Load(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadYAML(t *testing.T) {
+	cfg := loadString(t, "config.yaml", `# client settings
+default_server: "http://localhost:8080"
+chunk_size: 1048576
+debug: true
+profiles:
+  prod:
+    server_url: https://files.example.com
+    token: secret # keep out of logs
+sync_mappings:
+- name: docs
+  include:
+  - "*.md"
+  - "*.txt"
+`)
+	want := testConfig{
+		Server:    "http://localhost:8080",
+		ChunkSize: 1 << 20,
+		Debug:     true,
+		Profiles:  map[string]profile{"prod": {ServerURL: "https://files.example.com", Token: "secret"}},
+		Mappings:  []mapping{{Name: "docs", Include: []string{"*.md", "*.txt"}}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("yaml config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	cfg := loadString(t, "config.toml", `# client settings
+default_server = "http://localhost:8080"
+chunk_size = 1048576
+debug = true
+
+[profiles.prod]
+server_url = "https://files.example.com"
+token = "secret" # keep out of logs
+
+[[sync_mappings]]
+name = "docs"
+include = ["*.md", "*.txt"]
+`)
+	want := testConfig{
+		Server:    "http://localhost:8080",
+		ChunkSize: 1 << 20,
+		Debug:     true,
+		Profiles:  map[string]profile{"prod": {ServerURL: "https://files.example.com", Token: "secret"}},
+		Mappings:  []mapping{{Name: "docs", Include: []string{"*.md", "*.txt"}}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("toml config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadStrictRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.json": `{"default_server": "http://localhost:8080", "chunk_sizes": 4096}`,
+		"config.yaml": "default_server: http://localhost:8080\nchunk_sizes: 4096\n",
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var cfg testConfig
+		// Lenient loading keeps working; only LoadStrict objects.
+		if err := Load(path, &cfg); err != nil {
+			t.Errorf("%s: Load: %v", name, err)
+		}
+		err := LoadStrict(path, &cfg)
+		if err == nil || !strings.Contains(err.Error(), "chunk_sizes") {
+			t.Errorf("%s: LoadStrict err = %v, want unknown field chunk_sizes", name, err)
+		}
+	}
+}
+
+func TestGenerateRoundTrips(t *testing.T) {
+	src := testConfig{
+		Server:    "http://localhost:8080",
+		ChunkSize: 4096,
+		Profiles:  map[string]profile{"prod": {ServerURL: "https://files.example.com"}},
+		Mappings:  []mapping{{Name: "docs", Include: []string{"*.md"}}},
+	}
+	for _, format := range []string{"json", "yaml", "toml"} {
+		data, err := Generate(src, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		path := filepath.Join(t.TempDir(), "config."+format)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var back testConfig
+		if err := Load(path, &back); err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, data)
+		}
+		if !reflect.DeepEqual(back, src) {
+			t.Errorf("%s round trip = %+v, want %+v\n%s", format, back, src, data)
+		}
+	}
+}
+
+func TestGenerateRejectsUnknownFormat(t *testing.T) {
+	if _, err := Generate(testConfig{}, "xml"); err == nil {
+		t.Error("unknown format did not error")
+	}
+}
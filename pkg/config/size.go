@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSize converts a human byte-size spec such as "100MB", "2GiB",
+// "512k" or a plain byte count into bytes. Units are powers of 1024,
+// so "MB" and "MiB" mean the same thing, matching how the client's
+// rate specs are read.
+func ParseSize(s string) (int64, error) {
+	v := strings.TrimSpace(s)
+	upper := strings.ToUpper(v)
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(upper, u.suffix) {
+			mult = u.mult
+			v = strings.TrimSpace(v[:len(v)-len(u.suffix)])
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// Bytes is an int64 byte count whose config representation also
+// accepts human-readable size strings: "max_file_size": "2GiB" and
+// "max_file_size": 2147483648 load identically. It marshals back to a
+// plain number, so generated configs stay loadable everywhere.
+type Bytes int64
+
+// UnmarshalJSON accepts either a JSON number or a size string.
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		n, err := ParseSize(s)
+		if err != nil {
+			return err
+		}
+		*b = Bytes(n)
+		return nil
+	}
+	return json.Unmarshal(data, (*int64)(b))
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":      0,
+		"4096":   4096,
+		"512k":   512 << 10,
+		"100MB":  100 << 20,
+		"2GiB":   2 << 30,
+		"1.5MB":  3 << 19,
+		" 10 kb": 10 << 10,
+	}
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "lots", "-1MB", "MB"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", in)
+		}
+	}
+}
+
+func TestBytesAcceptsNumberAndString(t *testing.T) {
+	var v struct {
+		Max Bytes `json:"max"`
+	}
+	if err := json.Unmarshal([]byte(`{"max": "2GiB"}`), &v); err != nil || v.Max != 2<<30 {
+		t.Errorf("string form: %d, %v", v.Max, err)
+	}
+	if err := json.Unmarshal([]byte(`{"max": 4096}`), &v); err != nil || v.Max != 4096 {
+		t.Errorf("number form: %d, %v", v.Max, err)
+	}
+	if err := json.Unmarshal([]byte(`{"max": "huge"}`), &v); err == nil {
+		t.Error("bad size string unmarshaled")
+	}
+	// Bytes marshals back to a plain number.
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"max":4096}` {
+		t.Errorf("marshal = %s, %v", out, err)
+	}
+}
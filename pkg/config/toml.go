@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML decodes the TOML subset config files need: [table] and
+// [[array-of-tables]] headers with dotted names, and key = value pairs
+// with strings, numbers, booleans and single-line arrays. Multi-line
+// strings and inline tables are not supported — a config that needs
+// them can stay JSON.
+func parseTOML(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	cur := root
+	for i, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(stripTOMLComment(raw))
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]"):
+			parts := strings.Split(strings.TrimSpace(line[2:len(line)-2]), ".")
+			parent, err := tomlDescend(root, parts[:len(parts)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			last := strings.TrimSpace(parts[len(parts)-1])
+			arr, ok := parent[last].([]any)
+			if parent[last] != nil && !ok {
+				return nil, fmt.Errorf("line %d: %q is not an array of tables", i+1, last)
+			}
+			m := map[string]any{}
+			parent[last] = append(arr, m)
+			cur = m
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			parts := strings.Split(strings.TrimSpace(line[1:len(line)-1]), ".")
+			t, err := tomlDescend(root, parts)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			cur = t
+		default:
+			key, val, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key = value", i+1)
+			}
+			key = strings.Trim(strings.TrimSpace(key), `"'`)
+			v, err := tomlValue(strings.TrimSpace(val))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			cur[key] = v
+		}
+	}
+	return root, nil
+}
+
+// tomlDescend walks (creating as needed) the nested tables named by
+// parts, entering the last element of any array of tables on the way.
+func tomlDescend(root map[string]any, parts []string) (map[string]any, error) {
+	cur := root
+	for _, p := range parts {
+		p = strings.Trim(strings.TrimSpace(p), `"'`)
+		switch v := cur[p].(type) {
+		case map[string]any:
+			cur = v
+		case []any:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("%q is an empty array of tables", p)
+			}
+			m, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%q is not a table", p)
+			}
+			cur = m
+		case nil:
+			m := map[string]any{}
+			cur[p] = m
+			cur = m
+		default:
+			return nil, fmt.Errorf("%q is not a table", p)
+		}
+	}
+	return cur, nil
+}
+
+// tomlValue interprets one value expression.
+func tomlValue(s string) (any, error) {
+	switch {
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2:
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated array %q", s)
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		out := []any{}
+		if inner == "" {
+			return out, nil
+		}
+		for _, part := range splitTOMLList(inner) {
+			v, err := tomlValue(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
+
+// splitTOMLList splits an array body on commas that are outside quotes
+// and nested brackets.
+func splitTOMLList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote && (quote != '"' || s[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a
+// string.
+func stripTOMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote && (quote != '"' || s[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// encodeTOML renders doc as TOML in the same subset parseTOML reads
+// back: plain keys first, then [tables] and [[arrays of tables]], keys
+// sorted within each.
+func encodeTOML(doc map[string]any) ([]byte, error) {
+	var b strings.Builder
+	if err := writeTOMLTable(&b, doc, ""); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func writeTOMLTable(b *strings.Builder, m map[string]any, prefix string) error {
+	keys := sortedKeys(m)
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]any, nil:
+		case []any:
+			if tomlIsTableArray(v) {
+				continue
+			}
+			items := make([]string, len(v))
+			for i, it := range v {
+				s, err := tomlScalarString(it)
+				if err != nil {
+					return err
+				}
+				items[i] = s
+			}
+			fmt.Fprintf(b, "%s = [%s]\n", k, strings.Join(items, ", "))
+		default:
+			s, err := tomlScalarString(v)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s = %s\n", k, s)
+		}
+	}
+	for _, k := range keys {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		switch v := m[k].(type) {
+		case map[string]any:
+			fmt.Fprintf(b, "\n[%s]\n", name)
+			if err := writeTOMLTable(b, v, name); err != nil {
+				return err
+			}
+		case []any:
+			if !tomlIsTableArray(v) {
+				continue
+			}
+			for _, it := range v {
+				fmt.Fprintf(b, "\n[[%s]]\n", name)
+				if err := writeTOMLTable(b, it.(map[string]any), name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tomlIsTableArray reports whether v must be rendered as [[sections]]
+// rather than an inline array.
+func tomlIsTableArray(v []any) bool {
+	if len(v) == 0 {
+		return false
+	}
+	_, ok := v[0].(map[string]any)
+	return ok
+}
+
+func tomlScalarString(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case fmt.Stringer:
+		return t.String(), nil
+	case int64, float64:
+		return fmt.Sprint(t), nil
+	default:
+		return "", fmt.Errorf("cannot render %T as TOML", v)
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes the YAML subset config files need: mappings nested
+// by indentation, block sequences ("- item") of scalars or mappings,
+// quoted and plain scalars, and # comments. Anchors, flow collections
+// and multi-line strings are not supported — a config that needs them
+// can stay JSON.
+func parseYAML(data []byte) (map[string]any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		lead := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if strings.Contains(lead, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in YAML indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(lead), text: text, n: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return map[string]any{}, nil
+	}
+	m, err := p.parseMapping(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].n)
+	}
+	return m, nil
+}
+
+// yamlLine is one non-blank line with its indentation and source line
+// number for error messages.
+type yamlLine struct {
+	indent int
+	text   string
+	n      int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseMapping consumes "key: value" lines at exactly the given indent.
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent != indent || yamlIsDash(ln.text) {
+			break
+		}
+		key, rest, ok := splitYAMLKey(ln.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", ln.n)
+		}
+		if rest != "" {
+			m[key] = yamlScalar(rest)
+			p.pos++
+			continue
+		}
+		p.pos++
+		v, err := p.parseValueBlock(indent)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// parseValueBlock parses whatever follows a bare "key:" (or bare "-")
+// line: a nested mapping, a sequence, or nothing (null).
+func (p *yamlParser) parseValueBlock(parentIndent int) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	ln := p.lines[p.pos]
+	// Sequences may sit at the key's own indent; mappings must nest.
+	if ln.indent >= parentIndent && yamlIsDash(ln.text) {
+		return p.parseSequence(ln.indent)
+	}
+	if ln.indent > parentIndent {
+		return p.parseMapping(ln.indent)
+	}
+	return nil, nil
+}
+
+// parseSequence consumes "- item" lines at exactly the given indent.
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	seq := []any{}
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent != indent || !yamlIsDash(ln.text) {
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(ln.text, "-"))
+		switch {
+		case rest == "":
+			p.pos++
+			v, err := p.parseValueBlock(indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		default:
+			if _, _, ok := splitYAMLKey(rest); ok {
+				// A mapping whose first key shares the dash line; the
+				// "- " prefix pushed it two columns deeper, exactly where
+				// the continuation keys sit.
+				p.lines[p.pos] = yamlLine{indent: ln.indent + 2, text: rest, n: ln.n}
+				m, err := p.parseMapping(ln.indent + 2)
+				if err != nil {
+					return nil, err
+				}
+				seq = append(seq, m)
+			} else {
+				seq = append(seq, yamlScalar(rest))
+				p.pos++
+			}
+		}
+	}
+	return seq, nil
+}
+
+func yamlIsDash(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" (or a bare "key:"), reporting
+// ok=false for plain scalars such as URLs, whose colons are not
+// followed by a space.
+func splitYAMLKey(s string) (key, value string, ok bool) {
+	i := strings.Index(s, ":")
+	for i >= 0 {
+		if i == len(s)-1 || s[i+1] == ' ' {
+			key = strings.TrimSpace(s[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(s[i+1:]), true
+		}
+		j := strings.Index(s[i+1:], ":")
+		if j < 0 {
+			break
+		}
+		i += 1 + j
+	}
+	return "", "", false
+}
+
+// yamlScalar interprets one scalar value, stripping quotes and trailing
+// comments and recognizing the JSON-compatible types.
+func yamlScalar(s string) any {
+	if strings.HasPrefix(s, `"`) {
+		if i := strings.LastIndex(s, `"`); i > 0 {
+			if v, err := strconv.Unquote(s[:i+1]); err == nil {
+				return v
+			}
+		}
+		return s
+	}
+	if strings.HasPrefix(s, "'") {
+		if i := strings.LastIndex(s, "'"); i > 0 {
+			return strings.ReplaceAll(s[1:i], "''", "'")
+		}
+		return s
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	switch s {
+	case "", "null", "~":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	case "[]":
+		return []any{}
+	case "{}":
+		return map[string]any{}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// encodeYAML renders doc as YAML with two-space indentation and sorted
+// keys, in the same subset parseYAML reads back.
+func encodeYAML(doc map[string]any) []byte {
+	var b strings.Builder
+	writeYAMLMap(&b, doc, 0)
+	return []byte(b.String())
+}
+
+func writeYAMLMap(b *strings.Builder, m map[string]any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, k := range sortedKeys(m) {
+		switch v := m[k].(type) {
+		case map[string]any:
+			if len(v) == 0 {
+				fmt.Fprintf(b, "%s%s: {}\n", pad, k)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, k)
+			writeYAMLMap(b, v, indent+2)
+		case []any:
+			if len(v) == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", pad, k)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, k)
+			for _, it := range v {
+				if mm, ok := it.(map[string]any); ok {
+					fmt.Fprintf(b, "%s-\n", pad)
+					writeYAMLMap(b, mm, indent+2)
+				} else {
+					fmt.Fprintf(b, "%s- %s\n", pad, yamlScalarString(it))
+				}
+			}
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", pad, k, yamlScalarString(v))
+		}
+	}
+}
+
+func yamlScalarString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,125 @@
+// Package hash provides file integrity helpers used by both the server
+// and the CLI client. All checksums are hex-encoded SHA-256.
+package hash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Algorithm is the name of the checksum algorithm used across the API.
+const Algorithm = "sha256"
+
+// New returns a streaming hasher for callers that compute a checksum
+// while copying data; finish it with Sum.
+func New() hash.Hash {
+	return sha256.New()
+}
+
+// Sum returns the hex-encoded checksum accumulated in h.
+func Sum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TeeHasher accumulates a checksum of data as it flows through a copy,
+// so large transfers are read from disk only once instead of being
+// re-read for hashing afterwards.
+type TeeHasher struct {
+	h hash.Hash
+}
+
+// NewTeeHasher returns an empty TeeHasher.
+func NewTeeHasher() *TeeHasher {
+	return &TeeHasher{h: sha256.New()}
+}
+
+// Reader wraps r so every byte read from it is also hashed.
+func (t *TeeHasher) Reader(r io.Reader) io.Reader {
+	return io.TeeReader(r, t.h)
+}
+
+// Writer wraps w so every byte written to it is also hashed.
+func (t *TeeHasher) Writer(w io.Writer) io.Writer {
+	return io.MultiWriter(w, t.h)
+}
+
+// Sum returns the hex-encoded checksum of everything seen so far.
+func (t *TeeHasher) Sum() string {
+	return hex.EncodeToString(t.h.Sum(nil))
+}
+
+// HashFile returns the hex-encoded SHA-256 checksum of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	return HashReader(f)
+}
+
+// HashReader returns the hex-encoded SHA-256 checksum of everything read
+// from r.
+func HashReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TreeHash combines per-file checksums into one deterministic digest of
+// a whole directory tree: each "path:hash" line, sorted by the
+// slash-separated relative path, is hashed in order. Two trees are
+// identical exactly when their TreeHash values match, which lets a
+// single value compare folders without transferring anything.
+func TreeHash(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s:%s\n", p, files[p])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashDir computes the TreeHash of a local directory by hashing every
+// regular file beneath it, keyed by slash-separated path relative to
+// dir. Files are hashed in parallel via HashFiles.
+func HashDir(dir string) (string, error) {
+	rels := make(map[string]string) // absolute path -> relative key
+	var paths []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rels[p] = filepath.ToSlash(rel)
+		paths = append(paths, p)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sums, err := HashFiles(paths, 0)
+	if err != nil {
+		return "", err
+	}
+	files := make(map[string]string, len(sums))
+	for p, sum := range sums {
+		files[rels[p]] = sum
+	}
+	return TreeHash(files), nil
+}
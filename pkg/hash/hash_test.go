@@ -0,0 +1,97 @@
+package hash
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashReader(t *testing.T) {
+	// sha256("hello world")
+	const want = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	got, err := HashReader(strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("HashReader = %s, want %s", got, want)
+	}
+}
+
+func TestTeeHasher(t *testing.T) {
+	want, _ := HashReader(strings.NewReader("hello world"))
+
+	th := NewTeeHasher()
+	var sink bytes.Buffer
+	if _, err := io.Copy(&sink, th.Reader(strings.NewReader("hello world"))); err != nil {
+		t.Fatal(err)
+	}
+	if sink.String() != "hello world" {
+		t.Errorf("copied %q", sink.String())
+	}
+	if got := th.Sum(); got != want {
+		t.Errorf("Reader tee Sum = %s, want %s", got, want)
+	}
+
+	th = NewTeeHasher()
+	sink.Reset()
+	io.Copy(th.Writer(&sink), strings.NewReader("hello world"))
+	if got := th.Sum(); got != want {
+		t.Errorf("Writer tee Sum = %s, want %s", got, want)
+	}
+}
+
+func TestTreeHash(t *testing.T) {
+	a := TreeHash(map[string]string{"a.txt": "1111", "b/c.txt": "2222"})
+	b := TreeHash(map[string]string{"b/c.txt": "2222", "a.txt": "1111"})
+	if a != b {
+		t.Error("TreeHash depends on map iteration order")
+	}
+	if c := TreeHash(map[string]string{"a.txt": "1111", "b/c.txt": "ffff"}); c == a {
+		t.Error("TreeHash unchanged after a file hash changed")
+	}
+	if d := TreeHash(map[string]string{"a.txt": "1111"}); d == a {
+		t.Error("TreeHash unchanged after a file was removed")
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("beta"), 0o644)
+
+	got, err := HashDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ha, _ := HashReader(strings.NewReader("alpha"))
+	hb, _ := HashReader(strings.NewReader("beta"))
+	want := TreeHash(map[string]string{"a.txt": ha, "sub/b.txt": hb})
+	if got != want {
+		t.Errorf("HashDir = %s, want %s", got, want)
+	}
+
+	os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("gamma"), 0o644)
+	if again, _ := HashDir(dir); again == got {
+		t.Error("HashDir unchanged after a file changed")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := HashReader(strings.NewReader("hello world"))
+	if got != want {
+		t.Errorf("HashFile = %s, want %s", got, want)
+	}
+}
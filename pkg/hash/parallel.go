@@ -0,0 +1,63 @@
+package hash
+
+import (
+	"runtime"
+	"sync"
+)
+
+// HashFiles checksums every path with a bounded worker pool and returns
+// the results keyed by path. Hashing large trees is I/O- and CPU-bound
+// per file with no shared state, so spreading files across cores cuts
+// verification time roughly by the core count. workers <= 0 means one
+// worker per CPU; the first error wins and the remaining work is
+// skipped.
+func HashFiles(paths []string, workers int) (map[string]string, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	out := make(map[string]string, len(paths))
+	if len(paths) == 0 {
+		return out, nil
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				sum, err := HashFile(p)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					out[p] = sum
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range paths {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return out, nil
+}
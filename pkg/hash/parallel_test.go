@@ -0,0 +1,48 @@
+package hash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFilesMatchesHashFile(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("f%02d", i))
+		if err := os.WriteFile(p, []byte(fmt.Sprintf("content %d", i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	sums, err := HashFiles(paths, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != len(paths) {
+		t.Fatalf("got %d sums, want %d", len(sums), len(paths))
+	}
+	for _, p := range paths {
+		want, err := HashFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sums[p] != want {
+			t.Errorf("HashFiles(%s) = %s, want %s", p, sums[p], want)
+		}
+	}
+}
+
+func TestHashFilesReportsFirstError(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "exists")
+	if err := os.WriteFile(p, []byte("ok"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := HashFiles([]string{p, filepath.Join(dir, "missing")}, 2); err == nil {
+		t.Fatal("HashFiles ignored an unreadable file")
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// accessIndex tracks per-file download counts and last-access times,
+// keyed by resolved filesystem path. Listings and stat expose the
+// numbers so retention tooling can act on them ("delete if not
+// downloaded in 90 days").
+type accessIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]accessEntry
+}
+
+type accessEntry struct {
+	Count      int64     `json:"count"`
+	LastAccess time.Time `json:"last_access"`
+}
+
+// newAccessIndex loads the index persisted at path, starting empty if
+// the file does not exist yet.
+func newAccessIndex(path string) (*accessIndex, error) {
+	ix := &accessIndex{path: path, entries: make(map[string]accessEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ix.entries); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Record counts one download of full at the given time.
+func (ix *accessIndex) Record(full string, at time.Time) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e := ix.entries[full]
+	e.Count++
+	e.LastAccess = at
+	ix.entries[full] = e
+	return ix.flush()
+}
+
+// Get returns the access stats for full, zero when it was never
+// downloaded.
+func (ix *accessIndex) Get(full string) accessEntry {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.entries[full]
+}
+
+// flush persists the index atomically; callers hold ix.mu.
+func (ix *accessIndex) flush() error {
+	data, err := json.MarshalIndent(ix.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
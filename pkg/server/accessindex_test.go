@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func statFile(t *testing.T, ts *httptest.Server, rel string) api.FileInfo {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/stat?path=" + rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stat %s status = %d, want 200", rel, resp.StatusCode)
+	}
+	var info api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestDownloadsTrackedInAccessIndex(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "acc/file.txt", "count me")
+
+	if info := statFile(t, ts, "acc/file.txt"); info.Downloads != 0 || !info.LastAccess.IsZero() {
+		t.Errorf("fresh upload stat = %+v, want no access stats", info)
+	}
+
+	before := time.Now().UTC()
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/download/acc/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	info := statFile(t, ts, "acc/file.txt")
+	if info.Downloads != 2 {
+		t.Errorf("downloads = %d, want 2", info.Downloads)
+	}
+	if info.LastAccess.Before(before) {
+		t.Errorf("last access = %v, want at or after %v", info.LastAccess, before)
+	}
+
+	// Listings carry the same stats so retention sweeps can work from a
+	// single recursive list.
+	resp, err := http.Get(ts.URL + "/api/list?path=acc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Downloads != 2 {
+		t.Errorf("list = %+v, want one entry with 2 downloads", files)
+	}
+}
+
+func TestAccessIndexSurvivesReload(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "acc/keep.txt", "hi")
+	resp, err := http.Get(ts.URL + "/download/acc/keep.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	s2, err := NewServer(s.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts2 := httptest.NewServer(s2)
+	defer ts2.Close()
+	if info := statFile(t, ts2, "acc/keep.txt"); info.Downloads != 1 {
+		t.Errorf("downloads after reload = %d, want 1", info.Downloads)
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// The /admin surface groups runtime operations that act on the server as
+// a whole: inspecting the effective configuration, listing and canceling
+// transfers, running maintenance jobs out of schedule, toggling
+// read-only mode and summarizing storage usage. Like /api/jobs it is
+// admin-only once users are configured; on an open server every caller
+// may administer.
+
+// adminFor authenticates r and requires the admin role when users are
+// configured, writing the error response when either check fails.
+func (s *Server) adminFor(w http.ResponseWriter, r *http.Request) bool {
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return false
+	}
+	if u != nil && !u.Admin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return false
+	}
+	return true
+}
+
+// handleAdminConfig serves GET /admin/config: the configuration the
+// server is actually running with, after defaults, with credentials
+// redacted.
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.adminFor(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.redactedConfig())
+}
+
+// redactedConfig copies the effective config with every secret replaced,
+// so it can be shown without leaking credentials.
+func (s *Server) redactedConfig() ServerConfig {
+	cfg := s.cfg
+	cfg.Users = append([]UserConfig(nil), cfg.Users...)
+	for i := range cfg.Users {
+		if cfg.Users[i].Token != "" {
+			cfg.Users[i].Token = "<redacted>"
+		}
+	}
+	cfg.Webhooks = append([]WebhookConfig(nil), cfg.Webhooks...)
+	for i := range cfg.Webhooks {
+		if cfg.Webhooks[i].Secret != "" {
+			cfg.Webhooks[i].Secret = "<redacted>"
+		}
+	}
+	return cfg
+}
+
+// handleAdminTransfers serves GET /admin/transfers, every transfer
+// newest first, and DELETE /admin/transfers/{id} to cancel one. The data
+// matches /api/status; living under /admin it stays reachable for
+// operators regardless of how the public API is restricted.
+func (s *Server) handleAdminTransfers(w http.ResponseWriter, r *http.Request) {
+	if !s.adminFor(w, r) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/admin/transfers")
+	id = strings.TrimPrefix(id, "/")
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		writeJSON(w, http.StatusOK, s.transfers.List())
+	case r.Method == http.MethodDelete && id != "":
+		s.cancelTransfer(w, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleAdminRunJob serves POST /admin/jobs/run?name=: it runs the named
+// maintenance job immediately, outside its schedule, and returns the
+// job's status including how this run went.
+func (s *Server) handleAdminRunJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.adminFor(w, r) {
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "missing job name")
+		return
+	}
+	st, ok := s.sched.runNow(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown job")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// handleAdminReadOnly serves GET /admin/readonly (current mode) and POST
+// with a {"readonly": bool} body to change it. Read-only mode rejects
+// mutating requests server-wide until cleared; it does not persist
+// across restarts.
+func (s *Server) handleAdminReadOnly(w http.ResponseWriter, r *http.Request) {
+	if !s.adminFor(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body struct {
+			ReadOnly bool `json:"readonly"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		s.mu.Lock()
+		s.readOnly = body.ReadOnly
+		s.mu.Unlock()
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.mu.Lock()
+	ro := s.readOnly
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"readonly": ro})
+}
+
+// readOnlyGuard rejects mutating requests while read-only mode is on.
+// Reads, transfer control under /api/status/ and the admin surface
+// itself stay available, so the mode can always be turned off again.
+func (s *Server) readOnlyGuard(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	ro := s.readOnly
+	s.mu.Unlock()
+	if !ro || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	if strings.HasPrefix(r.URL.Path, "/admin/") || strings.HasPrefix(r.URL.Path, "/api/status/") {
+		return true
+	}
+	writeError(w, http.StatusServiceUnavailable, "server is in read-only mode")
+	return false
+}
+
+// handleAdminStats serves GET /admin/stats: how much is stored, how much
+// disk remains and how many transfers are running right now. The file
+// walk is the same one the reconciler uses, so the numbers reflect the
+// client-visible tree including exports.
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.adminFor(w, r) {
+		return
+	}
+	states, err := s.scanTree()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stats := api.StorageStats{Files: len(states)}
+	for _, st := range states {
+		stats.Bytes += st.size
+	}
+	if free, err := freeSpace(s.cfg.UploadDir); err == nil {
+		stats.FreeBytes = free
+	}
+	for _, t := range s.transfers.List() {
+		if t.State == api.TransferRunning || t.State == api.TransferPaused {
+			stats.ActiveTransfers++
+		}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
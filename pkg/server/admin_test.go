@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func adminRequest(t *testing.T, ts *httptest.Server, method, path, token, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAdminSurfaceRequiresAdmin(t *testing.T) {
+	_, ts := newAuthTestServer(t)
+
+	if resp := adminRequest(t, ts, http.MethodGet, "/admin/stats", "alice-token", ""); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want 403", resp.StatusCode)
+	}
+	if resp := adminRequest(t, ts, http.MethodGet, "/admin/stats", "root-token", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("admin status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestAdminConfigRedactsTokens(t *testing.T) {
+	_, ts := newAuthTestServer(t)
+
+	resp := adminRequest(t, ts, http.MethodGet, "/admin/config", "root-token", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var cfg ServerConfig
+	if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range cfg.Users {
+		if u.Token != "<redacted>" {
+			t.Errorf("token for %s = %q, want <redacted>", u.Name, u.Token)
+		}
+	}
+}
+
+func TestReadOnlyModeBlocksUploads(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp := adminRequest(t, ts, http.MethodPost, "/admin/readonly", "", `{"readonly": true}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("toggle status = %d, want 200", resp.StatusCode)
+	}
+
+	up := uploadRaw(t, ts, "f.txt", "blocked")
+	defer up.Body.Close()
+	if up.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("upload status = %d, want 503", up.StatusCode)
+	}
+	// Reads and the admin surface stay available, so the mode can be
+	// turned off again.
+	if resp := adminRequest(t, ts, http.MethodGet, "/api/status", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("read status = %d, want 200", resp.StatusCode)
+	}
+	resp = adminRequest(t, ts, http.MethodPost, "/admin/readonly", "", `{"readonly": false}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("toggle off status = %d, want 200", resp.StatusCode)
+	}
+	uploadFile(t, ts, "f.txt", "allowed")
+}
+
+func TestAdminRunJobAndStats(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "a.txt", "12345")
+
+	resp := adminRequest(t, ts, http.MethodPost, "/admin/jobs/run?name=temp-clean", "", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("run job status = %d, want 200", resp.StatusCode)
+	}
+	var st api.JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Name != "temp-clean" || st.Runs != 1 || st.LastError != "" {
+		t.Errorf("job status = %+v", st)
+	}
+	if resp := adminRequest(t, ts, http.MethodPost, "/admin/jobs/run?name=nope", "", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want 404", resp.StatusCode)
+	}
+
+	resp = adminRequest(t, ts, http.MethodGet, "/admin/stats", "", "")
+	var stats api.StorageStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 1 || stats.Bytes != 5 {
+		t.Errorf("stats = %+v, want 1 file of 5 bytes", stats)
+	}
+}
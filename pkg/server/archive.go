@@ -0,0 +1,438 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// handleArchive serves GET /api/archive?path= against a stored .tar.gz,
+// .tgz or .zip. Without member= it returns the archive's member listing,
+// with hash=true adding each member's checksum (computed once per
+// archive and cached) so content can be verified without extraction;
+// with member= it streams just that member, so one file can be pulled
+// out of a huge uploaded archive without downloading the whole thing.
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	if rel == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil || fi.IsDir() {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	member := path.Clean(r.URL.Query().Get("member"))
+	withHash := r.URL.Query().Get("hash") == "true"
+
+	switch {
+	case strings.HasSuffix(rel, ".tar.gz") || strings.HasSuffix(rel, ".tgz"):
+		err = s.archiveTar(w, full, fi, member, withHash)
+	case strings.HasSuffix(rel, ".zip"):
+		err = s.archiveZip(w, full, fi, member, withHash)
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported archive type")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("archive %s: %v", rel, err)
+	}
+}
+
+// handleArchiveUpload serves POST /api/upload/archive?dest=: the body is
+// a tar or tar.gz stream whose entries are extracted (path-jailed) under
+// dest. Uploading a whole folder as one stream avoids per-file HTTP
+// overhead while keeping structure, empty directories and mtimes.
+func (s *Server) handleArchiveUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	ttl, err := requestTTL(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.acquireSlot(u) {
+		w.Header().Set("Retry-After", "1")
+		writeLimitError(w, http.StatusTooManyRequests, api.CodeBusy, "too many concurrent transfers", "max_concurrent_transfers", int64(s.cfg.MaxConcurrentTransfers))
+		return
+	}
+	defer s.releaseSlot(u)
+	if !s.acquireTransfer(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, "canceled while queued for a transfer slot")
+		return
+	}
+	defer s.releaseTransfer()
+	release, err := s.admitUpload(r)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer release()
+	r.Body = s.throttleBody(r, r.Body)
+	dest := r.URL.Query().Get("dest")
+	replace := r.URL.Query().Get("replace") == "true"
+	if replace && dest == "" {
+		writeError(w, http.StatusBadRequest, "replace requires dest")
+		return
+	}
+	// Every temp artifact of this request — extraction temp files and
+	// the staging directory — lives in one session, removed wholesale
+	// however the request ends.
+	ses := s.newUploadSession()
+	defer ses.cleanup()
+	var stageDir string
+	if replace {
+		stageDir, err = s.makeStageDir(u, dest, ses)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	t := s.newTransfer()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctrl := newTransferControl(cancel)
+	s.registerControl(t.ID, ctrl)
+	defer s.unregisterControl(t.ID)
+
+	resp, err := s.extractArchiveUpload(ctx, r, u, dest, stageDir, t, ctrl, ses)
+	if err == nil && replace {
+		err = s.swapInStaging(u, dest, stageDir, resp)
+	}
+	s.finishTransfer(t, err)
+	var files []api.UploadResult
+	if resp != nil {
+		files = resp.Files
+	}
+	s.finalizeSummary(t, files)
+	if err != nil {
+		s.logger.Errorf("archive upload %s failed: %v", t.ID, err)
+		s.notifyHook("upload_failed", dest, 0, err.Error())
+		writeUploadError(w, err)
+		return
+	}
+	resp.TransferID = t.ID
+	s.applyTTL(resp, ttl)
+	writeUploadResponse(w, resp)
+}
+
+// extractArchiveUpload streams the request body through tar (gunzipping
+// when the stream starts with the gzip magic) and stores every entry.
+// With a non-empty stageDir entries land there instead of at their
+// final paths, for swapInStaging to move into place afterwards.
+func (s *Server) extractArchiveUpload(ctx context.Context, r *http.Request, u *UserConfig, dest, stageDir string, t *api.TransferStatus, ctrl *transferControl, ses *uploadSession) (*api.UploadResponse, error) {
+	br := bufio.NewReader(r.Body)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("bad gzip stream: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+	tr := tar.NewReader(src)
+
+	resp := &api.UploadResponse{}
+	for {
+		if err := ctrl.checkpoint(ctx); err != nil {
+			return nil, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		name := memberName(hdr.Name)
+		if name == "." {
+			continue
+		}
+		rel := path.Join(dest, name)
+		if hdr.Typeflag == tar.TypeDir {
+			full, err := s.resolvePath(namespacePath(u, rel))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rel, err)
+			}
+			if stageDir != "" {
+				full = filepath.Join(stageDir, filepath.FromSlash(name))
+			}
+			if err := s.mkdirAll(full); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		var res *api.UploadResult
+		if stageDir != "" {
+			// Path safety still goes through resolvePath even though the
+			// staged destination replaces its result.
+			if _, err := s.resolvePath(namespacePath(u, rel)); err != nil {
+				return nil, fmt.Errorf("%s: %w", rel, err)
+			}
+			res, err = s.storeFileAt(tr, u, rel, filepath.Join(stageDir, filepath.FromSlash(name)), "", hdr.ModTime, ses)
+		} else {
+			res, err = s.storeFile(tr, u, rel, "", hdr.ModTime, ses)
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp.Files = append(resp.Files, *res)
+		s.mu.Lock()
+		t.CompletedFiles++
+		t.TotalFiles++
+		t.Bytes += res.Size
+		t.TotalBytes += res.Size
+		s.mu.Unlock()
+	}
+	if len(resp.Files) == 0 {
+		return nil, fmt.Errorf("no files in archive")
+	}
+	return resp, nil
+}
+
+// makeStageDir creates the hidden staging directory a replace upload
+// extracts into, inside the session's directory next to dest so the
+// final rename stays on one filesystem. The session directory carries
+// uploadTmpPrefix, which keeps it out of listings and lets startup
+// cleanup remove it after a crash.
+func (s *Server) makeStageDir(u *UserConfig, dest string, ses *uploadSession) (string, error) {
+	destFull, err := s.resolvePath(namespacePath(u, dest))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", dest, err)
+	}
+	if fi, err := os.Stat(destFull); err == nil && !fi.IsDir() {
+		return "", fmt.Errorf("%s: not a directory", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(destFull), 0o755); err != nil {
+		return "", err
+	}
+	return ses.stageDir(destFull)
+}
+
+// swapInStaging moves a fully staged tree into place: the old directory
+// is renamed aside, the staging directory takes its name, and only then
+// is the old tree removed. Readers see either the old tree or the
+// complete new one, never a merge of the two, and a failed upload
+// leaves the destination untouched.
+func (s *Server) swapInStaging(u *UserConfig, dest, staging string, resp *api.UploadResponse) error {
+	destFull, err := s.resolvePath(namespacePath(u, dest))
+	if err != nil {
+		return fmt.Errorf("%s: %w", dest, err)
+	}
+	var oldRels []string
+	old := staging + ".old"
+	replaced := false
+	if _, err := os.Stat(destFull); err == nil {
+		if oldRels, _, err = s.filesUnder(destFull, dest); err != nil {
+			return err
+		}
+		if err := os.Rename(destFull, old); err != nil {
+			return err
+		}
+		replaced = true
+	}
+	if err := os.Rename(staging, destFull); err != nil {
+		if replaced {
+			os.Rename(old, destFull)
+		}
+		return err
+	}
+	if !replaced {
+		return nil
+	}
+	os.RemoveAll(old)
+	// Index entries for both trees are keyed by paths that no longer
+	// hold those files.
+	s.hashes.Invalidate(destFull)
+	s.hashes.Invalidate(staging)
+	// The extraction already journaled every staged file under its final
+	// path; what remains is the old files the swap deleted.
+	kept := make(map[string]bool, len(resp.Files))
+	for _, f := range resp.Files {
+		kept[f.Path] = true
+	}
+	for _, rel := range oldRels {
+		if !kept[rel] {
+			s.noteChange(u, rel, api.ChangeDelete, 0)
+		}
+	}
+	s.logger.Infof("replaced %s (%d files in, %d before)", dest, len(resp.Files), len(oldRels))
+	return nil
+}
+
+// memberName normalizes an archive entry name for comparison and
+// listing.
+func memberName(name string) string {
+	return strings.TrimSuffix(path.Clean(strings.TrimPrefix(name, "./")), "/")
+}
+
+// sendMember writes the headers for one extracted member followed by its
+// content.
+func sendMember(w http.ResponseWriter, member string, size int64, src io.Reader) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", path.Base(member)))
+	w.Header().Set("Content-Length", fmt.Sprint(size))
+	_, err := io.Copy(w, src)
+	return err
+}
+
+// memberHash returns the checksum of one archive member, served from
+// the hash index while the archive itself is unchanged. The cache key
+// combines the archive path and member name; validation against the
+// archive's size and mtime keeps replaced archives from serving stale
+// sums.
+func (s *Server) memberHash(full string, fi os.FileInfo, name string, content io.Reader) string {
+	key := full + "::" + name
+	if sum, ok := s.hashes.Get(key, fi.Size(), fi.ModTime()); ok {
+		return sum
+	}
+	sum, err := hash.HashReader(content)
+	if err != nil {
+		return ""
+	}
+	if err := s.hashes.Put(key, sum, fi.Size(), fi.ModTime()); err != nil {
+		s.logger.Errorf("update hash index: %v", err)
+	}
+	return sum
+}
+
+// archiveTar lists or extracts from a gzipped tarball. member is "." for
+// a listing.
+func (s *Server) archiveTar(w http.ResponseWriter, full string, fi os.FileInfo, member string, withHash bool) error {
+	f, err := os.Open(full)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return nil
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "not a gzip archive")
+		return nil
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var members []api.FileInfo
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "corrupt archive")
+			return nil
+		}
+		name := memberName(hdr.Name)
+		if member != "." {
+			if name == member && hdr.Typeflag != tar.TypeDir {
+				return sendMember(w, member, hdr.Size, tr)
+			}
+			continue
+		}
+		info := api.FileInfo{
+			Path:    name,
+			Name:    path.Base(name),
+			Size:    hdr.Size,
+			ModTime: hdr.ModTime.UTC(),
+			IsDir:   hdr.Typeflag == tar.TypeDir,
+		}
+		if withHash && !info.IsDir {
+			info.Hash = s.memberHash(full, fi, name, tr)
+		}
+		members = append(members, info)
+	}
+	if member != "." {
+		writeError(w, http.StatusNotFound, "member not found")
+		return nil
+	}
+	writeJSON(w, http.StatusOK, members)
+	return nil
+}
+
+// archiveZip lists or extracts from a zip file. member is "." for a
+// listing.
+func (s *Server) archiveZip(w http.ResponseWriter, full string, fi os.FileInfo, member string, withHash bool) error {
+	zr, err := zip.OpenReader(full)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "not a zip archive")
+		return nil
+	}
+	defer zr.Close()
+
+	var members []api.FileInfo
+	for _, zf := range zr.File {
+		name := memberName(zf.Name)
+		isDir := strings.HasSuffix(zf.Name, "/")
+		if member != "." {
+			if name == member && !isDir {
+				rc, err := zf.Open()
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "corrupt archive")
+					return nil
+				}
+				defer rc.Close()
+				return sendMember(w, member, int64(zf.UncompressedSize64), rc)
+			}
+			continue
+		}
+		info := api.FileInfo{
+			Path:    name,
+			Name:    path.Base(name),
+			Size:    int64(zf.UncompressedSize64),
+			ModTime: zf.Modified.UTC(),
+			IsDir:   isDir,
+		}
+		if withHash && !isDir {
+			if rc, err := zf.Open(); err == nil {
+				info.Hash = s.memberHash(full, fi, name, rc)
+				rc.Close()
+			}
+		}
+		members = append(members, info)
+	}
+	if member != "." {
+		writeError(w, http.StatusNotFound, "member not found")
+		return nil
+	}
+	writeJSON(w, http.StatusOK, members)
+	return nil
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+func makeTarGz(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, content)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.String()
+}
+
+func makeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, content)
+	}
+	zw.Close()
+	return buf.String()
+}
+
+func TestArchiveListAndExtractTarGz(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "big.tar.gz", makeTarGz(t, map[string]string{
+		"dir/file.txt": "inside the tarball",
+		"top.txt":      "top level",
+	}))
+
+	resp, err := http.Get(ts.URL + "/api/archive?path=big.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var members []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("listing = %+v", members)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/archive?path=big.tar.gz&member=dir/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "inside the tarball" {
+		t.Errorf("extracted %q", body)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/archive?path=big.tar.gz&member=missing.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing member status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestArchiveListingHashes(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "sums.tar.gz", makeTarGz(t, map[string]string{
+		"data.txt": "verify me",
+	}))
+	want, err := hash.HashReader(strings.NewReader("verify me"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func() []api.FileInfo {
+		resp, err := http.Get(ts.URL + "/api/archive?path=sums.tar.gz&hash=true")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var members []api.FileInfo
+		if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+			t.Fatal(err)
+		}
+		return members
+	}
+	members := list()
+	if len(members) != 1 || members[0].Hash != want {
+		t.Fatalf("hashed listing = %+v, want hash %s", members, want)
+	}
+
+	// The second listing is served from the hash index.
+	full, err := s.resolvePath("sums.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.hashes.Get(full+"::data.txt", fi.Size(), fi.ModTime()); !ok {
+		t.Error("member hash was not cached")
+	}
+	if members := list(); members[0].Hash != want {
+		t.Errorf("cached listing hash = %s", members[0].Hash)
+	}
+}
+
+func TestArchiveExtractZip(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "bundle.zip", makeZip(t, map[string]string{
+		"nested/readme.md": "zipped content",
+	}))
+
+	resp, err := http.Get(ts.URL + "/api/archive?path=bundle.zip&member=nested/readme.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "zipped content" {
+		t.Errorf("extracted %q", body)
+	}
+}
+
+func TestArchiveUploadExtractsUnderDest(t *testing.T) {
+	s, ts := newTestServer(t)
+	body := makeTarGz(t, map[string]string{
+		"dir/file.txt": "streamed in",
+		"top.txt":      "also here",
+	})
+
+	resp, err := http.Post(ts.URL+"/api/upload/archive?dest=proj", "application/gzip",
+		strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("archive upload returned %d: %s", resp.StatusCode, b)
+	}
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		t.Fatal(err)
+	}
+	if len(ur.Files) != 2 {
+		t.Fatalf("outcomes = %+v", ur.Files)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "proj", "dir", "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "streamed in" {
+		t.Errorf("extracted %q", data)
+	}
+}
+
+func TestArchiveUploadReplaceSwapsDirectory(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "proj/old.txt", "left over")
+	uploadFile(t, ts, "proj/keep.txt", "stale")
+
+	body := makeTarGz(t, map[string]string{
+		"keep.txt": "fresh",
+		"new.txt":  "brand new",
+	})
+	resp, err := http.Post(ts.URL+"/api/upload/archive?dest=proj&replace=true",
+		"application/gzip", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("replace upload returned %d: %s", resp.StatusCode, b)
+	}
+
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "proj", "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt survived the replace")
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "proj", "keep.txt"))
+	if err != nil || string(data) != "fresh" {
+		t.Errorf("keep.txt = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "proj", "new.txt")); err != nil {
+		t.Errorf("new.txt missing: %v", err)
+	}
+
+	// Neither the staging directory nor the renamed-aside old tree may
+	// linger.
+	entries, err := os.ReadDir(s.cfg.UploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), uploadTmpPrefix) {
+			t.Errorf("leftover staging entry %s", e.Name())
+		}
+	}
+
+	// The swap journals the disappearance of files the new tree lacks.
+	cr, err := http.Get(ts.URL + "/api/changes?cursor=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Body.Close()
+	var cl api.ChangeList
+	if err := json.NewDecoder(cr.Body).Decode(&cl); err != nil {
+		t.Fatal(err)
+	}
+	deleted := false
+	for _, ev := range cl.Events {
+		if ev.Op == api.ChangeDelete && ev.Path == "proj/old.txt" {
+			deleted = true
+		}
+	}
+	if !deleted {
+		t.Errorf("no delete event for proj/old.txt in %+v", cl.Events)
+	}
+}
+
+func TestArchiveUploadReplaceLeavesDestOnFailure(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "proj/old.txt", "still here")
+
+	// A corrupt stream fails mid-extraction; the destination must be
+	// untouched because everything happened in staging.
+	resp, err := http.Post(ts.URL+"/api/upload/archive?dest=proj&replace=true",
+		"application/gzip", strings.NewReader("not a tarball"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("corrupt replace upload succeeded")
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "proj", "old.txt"))
+	if err != nil || string(data) != "still here" {
+		t.Errorf("old.txt = %q, %v", data, err)
+	}
+}
+
+func TestArchiveUploadRejectsTraversal(t *testing.T) {
+	s, ts := newTestServer(t)
+	body := makeTarGz(t, map[string]string{"../escape.txt": "nope"})
+
+	resp, err := http.Post(ts.URL+"/api/upload/archive", "application/gzip",
+		strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(s.cfg.UploadDir), "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("traversal entry was written (err=%v)", err)
+	}
+}
+
+func TestArchiveRejectsUnsupportedType(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "plain.txt", "not an archive")
+
+	resp, err := http.Get(ts.URL + "/api/archive?path=plain.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
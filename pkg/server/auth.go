@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// UserConfig declares one account. Each non-admin user is confined to a
+// home directory named after the user under the storage root; admins see
+// the whole tree.
+type UserConfig struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Admin bool   `json:"admin,omitempty"`
+}
+
+// authenticate resolves the request's user. With no users configured the
+// server is open and every caller is treated as an unscoped anonymous
+// user (nil). Otherwise a valid bearer token (or X-Auth-Token header) is
+// required.
+func (s *Server) authenticate(r *http.Request) (*UserConfig, bool) {
+	if len(s.cfg.Users) == 0 {
+		return nil, true
+	}
+	token := r.Header.Get("X-Auth-Token")
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return nil, false
+	}
+	for i := range s.cfg.Users {
+		u := &s.cfg.Users[i]
+		if subtle.ConstantTimeCompare([]byte(u.Token), []byte(token)) == 1 {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// userFor authenticates r, writing a 401 response when it fails.
+func (s *Server) userFor(w http.ResponseWriter, r *http.Request) (*UserConfig, bool) {
+	u, ok := s.authenticate(r)
+	s.noteAuthResult(r, ok)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return nil, false
+	}
+	return u, true
+}
+
+// namespacePath prefixes rel with the user's home directory. Admins and
+// anonymous users (open server) address the storage root directly.
+//
+// The rel is cleaned before prefixing so that a traversal sequence can
+// never cancel the home directory out again: "alice" + "../bob/x" must
+// not resolve to bob's namespace. A rel that still climbs upward after
+// cleaning maps to "..", which resolvePath rejects as an invalid path.
+func namespacePath(u *UserConfig, rel string) string {
+	if u == nil || u.Admin {
+		return rel
+	}
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "/")
+	rel = path.Clean(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return ".."
+	}
+	if rel == "" || rel == "." {
+		return u.Name
+	}
+	return u.Name + "/" + rel
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func newAuthTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Users: []UserConfig{
+			{Name: "alice", Token: "alice-token"},
+			{Name: "root", Token: "root-token", Admin: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func authUpload(t *testing.T, ts *httptest.Server, token, name, content string) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, _ := mw.CreateFormFile("file", name)
+	io.WriteString(part, content)
+	mw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuthRequiredWhenUsersConfigured(t *testing.T) {
+	_, ts := newAuthTestServer(t)
+	if resp := authUpload(t, ts, "", "f.txt", "x"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	if resp := authUpload(t, ts, "wrong", "f.txt", "x"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad token status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestUploadScopedToUserNamespace(t *testing.T) {
+	s, ts := newAuthTestServer(t)
+	resp := authUpload(t, ts, "alice-token", "notes/a.txt", "hi")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "alice", "notes", "a.txt")); err != nil {
+		t.Errorf("file not stored in alice namespace: %v", err)
+	}
+	// The response keeps alice's view of the path but also names the
+	// canonical stored location.
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		t.Fatal(err)
+	}
+	if len(ur.Files) != 1 || ur.Files[0].Path != "notes/a.txt" || ur.Files[0].StoredPath != "alice/notes/a.txt" {
+		t.Errorf("upload result = %+v", ur.Files)
+	}
+}
+
+func TestNamespaceTraversalRejected(t *testing.T) {
+	s, ts := newAuthTestServer(t)
+	planted := filepath.Join(s.cfg.UploadDir, "bob", "planted.txt")
+	do := func(req *http.Request) *http.Response {
+		t.Helper()
+		req.Header.Set("Authorization", "Bearer alice-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// A multipart filename with ".." must not cancel out the home
+	// directory prefix and land in another namespace.
+	if resp := authUpload(t, ts, "alice-token", "../bob/planted.txt", "x"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("traversal upload status = %d, want 400", resp.StatusCode)
+	}
+	if _, err := os.Stat(planted); !os.IsNotExist(err) {
+		t.Errorf("traversal upload escaped the namespace: %v", err)
+	}
+
+	// Tar entry names in archive uploads go through the same check.
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../bob/planted.txt", Mode: 0o644, Size: 1, Typeflag: tar.TypeReg})
+	tw.Write([]byte("x"))
+	tw.Close()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/upload/archive", &buf)
+	if resp := do(req); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("traversal archive status = %d, want 400", resp.StatusCode)
+	}
+	if _, err := os.Stat(planted); !os.IsNotExist(err) {
+		t.Errorf("traversal archive entry escaped the namespace: %v", err)
+	}
+
+	// Server-side move cannot aim either end outside the namespace.
+	if resp := authUpload(t, ts, "alice-token", "a.txt", "hi"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("setup upload status = %d, want 200", resp.StatusCode)
+	}
+	body, _ := json.Marshal(api.FileOpRequest{From: "a.txt", To: "../bob/stolen.txt"})
+	req, _ = http.NewRequest(http.MethodPost, ts.URL+"/api/files/move", bytes.NewReader(body))
+	if resp := do(req); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("traversal move status = %d, want 400", resp.StatusCode)
+	}
+
+	// Nor can reads peek into a sibling namespace.
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/api/list?path=../root", nil)
+	if resp := do(req); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("traversal list status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestAdminSeesWholeTree(t *testing.T) {
+	_, ts := newAuthTestServer(t)
+	authUpload(t, ts, "alice-token", "a.txt", "hi")
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/list?path=alice", nil)
+	req.Header.Set("Authorization", "Bearer root-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("admin list status = %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// patternMatch is one entry a bulk-delete pattern matched, captured
+// before anything is removed.
+type patternMatch struct {
+	rel  string
+	full string
+	fi   os.FileInfo
+}
+
+// handleBatchDelete serves POST /api/files/delete-batch: every path in
+// the caller's namespace matching the glob pattern is deleted in one
+// request. Patterns follow path.Match, so "*" does not cross "/"; a
+// matched directory requires recursive, which removes its whole
+// subtree. dry_run reports the matches without touching anything, so
+// clients can show the list and ask for confirmation first.
+func (s *Server) handleBatchDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	var req api.BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Pattern == "" {
+		writeError(w, http.StatusBadRequest, "pattern is required")
+		return
+	}
+	if _, err := path.Match(req.Pattern, ""); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid pattern")
+		return
+	}
+	matches, err := s.matchPattern(u, req.Pattern)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := api.BatchDeleteResponse{DryRun: req.DryRun}
+	for _, m := range matches {
+		if m.fi.IsDir() && !req.Recursive {
+			writeError(w, http.StatusBadRequest, m.rel+" is a directory (use recursive)")
+			return
+		}
+		resp.Matched = append(resp.Matched, m.rel)
+	}
+	if req.DryRun {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	for _, m := range matches {
+		if err := s.deletePath(u, m.rel, m.full, m.fi); err != nil {
+			// Keep going: what was already deleted is gone either way,
+			// and the counts tell the client the run was partial.
+			s.logger.Errorf("bulk delete %s: %v", m.rel, err)
+			continue
+		}
+		resp.Deleted++
+	}
+	s.logger.Infof("bulk delete %s removed %d of %d matches", req.Pattern, resp.Deleted, len(matches))
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// matchPattern walks the user's namespace and returns every file or
+// directory whose user-visible path matches the glob pattern, sorted. A
+// matched directory is returned whole without descending into it.
+func (s *Server) matchPattern(u *UserConfig, pattern string) ([]patternMatch, error) {
+	root, err := s.resolvePath(namespacePath(u, ""))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var matches []patternMatch
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		if fi.IsDir() && fi.Name() == dataDirName {
+			return filepath.SkipDir
+		}
+		if strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ok, _ := path.Match(pattern, rel); !ok {
+			return nil
+		}
+		matches = append(matches, patternMatch{rel: rel, full: p, fi: fi})
+		if fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].rel < matches[j].rel })
+	return matches, nil
+}
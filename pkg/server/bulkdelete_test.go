@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func batchDelete(t *testing.T, ts *httptest.Server, req api.BatchDeleteRequest) (*api.BatchDeleteResponse, int) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(ts.URL+"/api/files/delete-batch", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode
+	}
+	br := &api.BatchDeleteResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(br); err != nil {
+		t.Fatal(err)
+	}
+	return br, resp.StatusCode
+}
+
+func TestBatchDeleteMatchesPattern(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "logs/2023-01.txt", "a")
+	uploadFile(t, ts, "logs/2023-02.txt", "b")
+	uploadFile(t, ts, "logs/2024-01.txt", "c")
+
+	// The dry run reports matches without removing anything.
+	br, _ := batchDelete(t, ts, api.BatchDeleteRequest{Pattern: "logs/2023-*", DryRun: true})
+	want := []string{"logs/2023-01.txt", "logs/2023-02.txt"}
+	if !reflect.DeepEqual(br.Matched, want) || br.Deleted != 0 {
+		t.Errorf("dry run = %+v, want matched %v", br, want)
+	}
+
+	br, _ = batchDelete(t, ts, api.BatchDeleteRequest{Pattern: "logs/2023-*"})
+	if br.Deleted != 2 {
+		t.Errorf("deleted = %d, want 2", br.Deleted)
+	}
+	resp, err := http.Get(ts.URL + "/api/stat?path=logs/2023-01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted file stat = %d, want 404", resp.StatusCode)
+	}
+	resp, err = http.Get(ts.URL + "/api/stat?path=logs/2024-01.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unmatched file stat = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestBatchDeleteDirectoryNeedsRecursive(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "logs/app.txt", "x")
+
+	if _, code := batchDelete(t, ts, api.BatchDeleteRequest{Pattern: "logs"}); code != http.StatusBadRequest {
+		t.Errorf("directory match without recursive = %d, want 400", code)
+	}
+	br, _ := batchDelete(t, ts, api.BatchDeleteRequest{Pattern: "logs", Recursive: true})
+	if br.Deleted != 1 || len(br.Matched) != 1 {
+		t.Errorf("recursive delete = %+v", br)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// changeIndex records, per directory, when its contents last changed.
+// Sync clients use it (via GET /api/changes?since=) to skip untouched
+// subtrees instead of rescanning the whole tree. Keys are
+// slash-separated paths relative to the storage root, with "." for the
+// root itself.
+type changeIndex struct {
+	mu   sync.Mutex
+	path string
+	dirs map[string]time.Time
+}
+
+// newChangeIndex loads the index persisted at path, starting empty if
+// the file does not exist yet.
+func newChangeIndex(path string) (*changeIndex, error) {
+	ix := &changeIndex{path: path, dirs: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ix.dirs); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Note marks every directory on the way to the changed file rel (a
+// slash-separated storage-relative path) as changed at the given time.
+func (ix *changeIndex) Note(rel string, at time.Time) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for dir := path.Dir(rel); ; dir = path.Dir(dir) {
+		ix.dirs[dir] = at
+		if dir == "." || dir == "/" {
+			break
+		}
+	}
+	return ix.flush()
+}
+
+// ChangedSince returns the directories whose contents changed after t,
+// restricted to those under prefix ("" for all).
+func (ix *changeIndex) ChangedSince(t time.Time, prefix string) []string {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var dirs []string
+	for dir, at := range ix.dirs {
+		if !at.After(t) {
+			continue
+		}
+		if prefix != "" && dir != prefix && !strings.HasPrefix(dir, prefix+"/") {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// flush persists the index atomically; callers hold ix.mu.
+func (ix *changeIndex) flush() error {
+	data, err := json.MarshalIndent(ix.dirs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestChangeIndexNoteMarksAncestors(t *testing.T) {
+	ix, err := newChangeIndex(filepath.Join(t.TempDir(), "changes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().UTC()
+	if err := ix.Note("a/b/c.txt", at); err != nil {
+		t.Fatal(err)
+	}
+	dirs := ix.ChangedSince(at.Add(-time.Second), "")
+	want := map[string]bool{"a/b": true, "a": true, ".": true}
+	if len(dirs) != len(want) {
+		t.Fatalf("changed dirs = %v", dirs)
+	}
+	for _, d := range dirs {
+		if !want[d] {
+			t.Errorf("unexpected dir %q", d)
+		}
+	}
+}
+
+func TestChangesSinceReturnsOnlyNewFiles(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "old/a.txt", "old")
+
+	time.Sleep(20 * time.Millisecond)
+	since := time.Now().UTC()
+	time.Sleep(20 * time.Millisecond)
+	uploadFile(t, ts, "new/b.txt", "new")
+
+	resp, err := http.Get(ts.URL + "/api/changes?since=" +
+		url.QueryEscape(since.Format(time.RFC3339Nano)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "new/b.txt" {
+		t.Errorf("changes = %+v, want just new/b.txt", files)
+	}
+}
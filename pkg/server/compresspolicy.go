@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// incompressibleExts lists formats that are already compressed; running
+// them through gzip again costs CPU without shrinking anything.
+var incompressibleExts = map[string]bool{
+	".7z": true, ".aac": true, ".avi": true, ".br": true, ".bz2": true,
+	".flac": true, ".gif": true, ".gz": true, ".heic": true, ".jpeg": true,
+	".jpg": true, ".mkv": true, ".mov": true, ".mp3": true, ".mp4": true,
+	".ogg": true, ".png": true, ".rar": true, ".tgz": true, ".webm": true,
+	".webp": true, ".xz": true, ".zip": true, ".zst": true,
+}
+
+// compressionPolicy resolves a file name to "gzip" or "none": the
+// configured rules are consulted in order, then the built-in
+// incompressible set, and everything else defaults to gzip.
+type compressionPolicy struct {
+	rules []CompressionRule
+}
+
+// newCompressionPolicy validates the configured rules.
+func newCompressionPolicy(rules []CompressionRule) (*compressionPolicy, error) {
+	for _, r := range rules {
+		switch r.Compression {
+		case "gzip", "none":
+		default:
+			return nil, fmt.Errorf("invalid compression %q in compression rule (want gzip or none)", r.Compression)
+		}
+		if len(r.Extensions) == 0 && len(r.MimeTypes) == 0 {
+			return nil, fmt.Errorf("compression rule needs extensions or mime_types")
+		}
+	}
+	return &compressionPolicy{rules: rules}, nil
+}
+
+// choiceFor returns the compression choice for the file name.
+func (p *compressionPolicy) choiceFor(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	mt, _, _ := strings.Cut(mime.TypeByExtension(ext), ";")
+	for _, r := range p.rules {
+		for _, e := range r.Extensions {
+			if "."+strings.TrimPrefix(strings.ToLower(e), ".") == ext {
+				return r.Compression
+			}
+		}
+		for _, m := range r.MimeTypes {
+			if m == mt || (strings.HasSuffix(m, "/") && strings.HasPrefix(mt, m)) {
+				return r.Compression
+			}
+		}
+	}
+	if incompressibleExts[ext] {
+		return "none"
+	}
+	return "gzip"
+}
+
+// adviseTreeCompression recommends a codec for the archive of dir:
+// "none" when more than half of its bytes sit in files the policy calls
+// incompressible, "gzip" otherwise.
+func (s *Server) adviseTreeCompression(dir string) string {
+	var stored, compressible int64
+	filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if fi.IsDir() && fi.Name() == dataDirName {
+			return filepath.SkipDir
+		}
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		if s.compress.choiceFor(fi.Name()) == "none" {
+			stored += fi.Size()
+		} else {
+			compressible += fi.Size()
+		}
+		return nil
+	})
+	if stored > compressible {
+		return "none"
+	}
+	return "gzip"
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCompressionPolicyChoices(t *testing.T) {
+	p, err := newCompressionPolicy([]CompressionRule{
+		{Extensions: []string{"zip"}, Compression: "gzip"},
+		{MimeTypes: []string{"image/"}, Compression: "none"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name, want string
+	}{
+		{"bundle.zip", "gzip"},  // rule overrides the built-in default
+		{"logo.svg", "none"},    // MIME class rule
+		{"notes.txt", "gzip"},   // unmatched, compressible
+		{"frames.mp4", "none"},  // unmatched, built-in incompressible
+		{"ARCHIVE.TGZ", "none"}, // extension matching is case-insensitive
+	}
+	for _, c := range cases {
+		if got := p.choiceFor(c.name); got != c.want {
+			t.Errorf("choiceFor(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNewServerRejectsBadCompressionRule(t *testing.T) {
+	_, err := NewServer(ServerConfig{
+		UploadDir:        t.TempDir(),
+		CompressionRules: []CompressionRule{{Extensions: []string{"zip"}, Compression: "zstd"}},
+	})
+	if err == nil {
+		t.Fatal("NewServer accepted a compression rule with an unsupported codec")
+	}
+}
+
+func TestDirectoryDownloadSkipsRecompression(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "media/clip.mp4", "pretend this is compressed video data")
+	uploadFile(t, ts, "media/readme.txt", "short note")
+
+	get := func(url string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Mostly-mp4 bytes: the default gzip choice is downgraded to tar.
+	resp := get(ts.URL + "/download/media")
+	if got := resp.Header.Get("Content-Type"); got != "application/x-tar" {
+		t.Errorf("Content-Type = %q, want plain tar for incompressible content", got)
+	}
+	// An explicit request still gets exactly what it asked for.
+	resp = get(ts.URL + "/download/media?compression=gzip")
+	if got := resp.Header.Get("Content-Type"); got != "application/gzip" {
+		t.Errorf("Content-Type = %q, want gzip when requested explicitly", got)
+	}
+}
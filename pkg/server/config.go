@@ -0,0 +1,410 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/config"
+)
+
+// ServerConfig controls the HTTP server. Zero values are replaced by the
+// defaults below in NewServer.
+type ServerConfig struct {
+	Port      int    `json:"port"`
+	UploadDir string `json:"upload_dir"`
+
+	// MaxFileSize caps stored file size. Byte-count fields accept a
+	// human-readable size ("100MB", "2GiB") or a plain number.
+	MaxFileSize   config.Bytes `json:"max_file_size"`
+	EnableLogging bool         `json:"enable_logging"`
+	LogFile       string       `json:"log_file"`
+	Debug         bool         `json:"debug"`
+
+	// LogFormat selects "text" (default) or "json" log output.
+	LogFormat string `json:"log_format,omitempty"`
+
+	// DataDir holds server state (transfer status, indexes). It defaults
+	// to a hidden .upload-http directory inside the storage root, which
+	// is never served or listed.
+	DataDir string `json:"data_dir,omitempty"`
+
+	// TempDir overrides where in-progress upload temp files are written
+	// for the main storage root; the default is next to their
+	// destination. Keep it on the same filesystem as UploadDir so the
+	// final rename stays atomic — a cross-device temp_dir still works,
+	// but through a copy-then-rename fallback. Useful when SELinux
+	// labels or xattr policies on the storage tree make temp-file
+	// creation there undesirable.
+	TempDir string `json:"temp_dir,omitempty"`
+
+	// StatusRetentionHours is how long finished transfer statuses are
+	// kept before being pruned (default 24).
+	StatusRetentionHours int `json:"status_retention_hours,omitempty"`
+
+	// MaxConcurrentTransfers caps how many uploads and downloads each
+	// token (or the anonymous caller on an open server) may run at once;
+	// exceeding it returns 429 with a Retry-After header. Zero means no
+	// cap.
+	MaxConcurrentTransfers int `json:"max_concurrent_transfers,omitempty"`
+
+	// DownloadCompression is the default codec for directory downloads
+	// when the request does not pick one via ?compression= or
+	// Accept-Encoding: "gzip" (the default) or "none".
+	DownloadCompression string `json:"download_compression,omitempty"`
+
+	// CompressionLevel is the default gzip level for directory
+	// downloads; zero means gzip.DefaultCompression. Requests may
+	// override it with ?level=N.
+	CompressionLevel int `json:"compression_level,omitempty"`
+
+	// CompressionRules map file types to a compression choice,
+	// overriding the built-in list of already-compressed formats. They
+	// steer whether a directory download is worth gzipping: when most of
+	// a tree's bytes resolve to "none", its archive is served as plain
+	// tar instead of recompressing data that cannot shrink.
+	CompressionRules []CompressionRule `json:"compression_rules,omitempty"`
+
+	// ReconcileSeconds, when set, rescans the storage tree on that
+	// interval so files added, changed or removed out-of-band (scp, NFS)
+	// still show up in listings and the change feed. Zero disables the
+	// scan.
+	ReconcileSeconds int `json:"reconcile_seconds,omitempty"`
+
+	// MinFreeBytes is the free-space floor for the storage filesystem:
+	// uploads that would take available space below it are rejected with
+	// 507 Insufficient Storage, and in-flight uploads are cut off when
+	// outside writers eat the remaining space. Zero disables the check.
+	MinFreeBytes config.Bytes `json:"min_free_bytes,omitempty"`
+
+	// MaxActiveTransfers caps upload and download requests in flight
+	// across the whole server, on top of the per-user
+	// MaxConcurrentTransfers cap. Transfers over the cap queue until a
+	// slot frees or the client gives up; metadata requests — listings,
+	// stat, health — are never gated, so the server stays responsive
+	// for interactive use during large bulk transfers. Zero means no
+	// cap.
+	MaxActiveTransfers int `json:"max_active_transfers,omitempty"`
+
+	// MaxRequestsPerSecond caps API requests per client — keyed by the
+	// auth token when one is presented, the client IP otherwise — with a
+	// burst of one second's worth. Requests over the cap get 429 with a
+	// Retry-After hint. Zero disables the cap.
+	MaxRequestsPerSecond int `json:"max_requests_per_second,omitempty"`
+
+	// MaxBandwidth caps the total bytes per second moved across all
+	// transfers; MaxBandwidthPerConn additionally caps each individual
+	// connection. Zero means unlimited. Both apply to uploads and
+	// downloads alike.
+	MaxBandwidth        config.Bytes `json:"max_bandwidth,omitempty"`
+	MaxBandwidthPerConn config.Bytes `json:"max_bandwidth_per_conn,omitempty"`
+
+	// BandwidthRules give clients in a CIDR block their own
+	// per-connection cap instead of MaxBandwidthPerConn, so internal
+	// build machines can run full speed while the public default stays
+	// capped. The first matching rule wins. See BandwidthRule.
+	BandwidthRules []BandwidthRule `json:"bandwidth_rules,omitempty"`
+
+	// Exports are additional named storage roots, addressed by their
+	// name as the first path segment ("archive/build.tar" stores under
+	// the "archive" export's directory). Each may carry its own file
+	// size limit.
+	Exports []ExportConfig `json:"exports,omitempty"`
+
+	// FileMode and DirMode override the permission bits on stored files
+	// and created directories, as octal strings ("0640", "0750"), for
+	// setups where other services read the storage tree directly. Empty
+	// keeps the defaults (0644 files, 0755 directories, minus umask).
+	// Owner and Group additionally chown stored content, which needs the
+	// server to run as root.
+	FileMode string `json:"file_mode,omitempty"`
+	DirMode  string `json:"dir_mode,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Group    string `json:"group,omitempty"`
+
+	// Versioning keeps overwritten files as numbered versions in a
+	// hidden area under DataDir instead of discarding them; /api/versions
+	// lists, fetches and restores them. MaxVersions caps how many
+	// versions are kept per file, oldest pruned first (0 keeps all).
+	Versioning  bool `json:"versioning,omitempty"`
+	MaxVersions int  `json:"max_versions,omitempty"`
+
+	// Trash diverts deletes into a trash area under DataDir instead of
+	// removing data: GET /api/trash lists what is there with the
+	// original paths, POST /api/trash/restore?id= puts an entry back,
+	// and entries older than TrashRetentionHours (default 168, one
+	// week) are purged in the background.
+	Trash               bool `json:"trash,omitempty"`
+	TrashRetentionHours int  `json:"trash_retention_hours,omitempty"`
+
+	// ScrubIntervalHours, when set, re-hashes stored files against the
+	// hash index on that interval to catch silent disk corruption;
+	// mismatches are logged and reported via GET /api/scrub.
+	// ScrubQuarantine additionally moves corrupted files into a
+	// quarantine area under DataDir so they can no longer be served.
+	ScrubIntervalHours int  `json:"scrub_interval_hours,omitempty"`
+	ScrubQuarantine    bool `json:"scrub_quarantine,omitempty"`
+
+	// Search enables GET /api/grep, which scans text files under a path
+	// for a query on demand — no index is kept, so it costs nothing when
+	// unused. SearchMaxFileSize caps how large a file is still scanned
+	// (default 10 MiB) and SearchMaxMatches caps how many matching lines
+	// one query returns (default 1000); binary files are skipped.
+	Search            bool         `json:"search,omitempty"`
+	SearchMaxFileSize config.Bytes `json:"search_max_file_size,omitempty"`
+	SearchMaxMatches  int          `json:"search_max_matches,omitempty"`
+
+	// Webhooks POST a JSON event to external URLs when files change or
+	// transfers finish, delivered in the background with retries. See
+	// WebhookConfig for per-endpoint filters and signing.
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
+
+	// ExecHooks run external commands when files change or transfers
+	// finish — antivirus scans, indexers, post-processing — without
+	// modifying the server. See ExecHookConfig.
+	ExecHooks []ExecHookConfig `json:"exec_hooks,omitempty"`
+
+	// Quotas cap how much may be stored under named top-level
+	// directories. With users configured each home directory is named
+	// after its user, so a quota whose Path is a user name limits that
+	// user. Uploads that would exceed a quota are rejected with 507.
+	Quotas []QuotaConfig `json:"quotas,omitempty"`
+
+	// AllowIPs and DenyIPs restrict which client addresses may talk to
+	// the server at all, as CIDR blocks ("10.0.0.0/8") or bare
+	// addresses, checked before any handler. A non-empty AllowIPs
+	// admits only matching addresses; DenyIPs rejects matches even when
+	// the allow list admits them.
+	AllowIPs []string `json:"allow_ips,omitempty"`
+	DenyIPs  []string `json:"deny_ips,omitempty"`
+
+	// BanAfterFailures temporarily bans a client IP after that many
+	// consecutive authentication failures, slowing down token guessing;
+	// bans are logged and emitted as "auth_ban" webhook events.
+	// BanMinutes is how long a ban lasts (default 15). Zero disables
+	// automatic bans.
+	BanAfterFailures int `json:"ban_after_failures,omitempty"`
+	BanMinutes       int `json:"ban_minutes,omitempty"`
+
+	// TLSCert and TLSKey, when both set, serve HTTPS with the named PEM
+	// certificate and key instead of plain HTTP.
+	TLSCert string `json:"tls_cert,omitempty"`
+	TLSKey  string `json:"tls_key,omitempty"`
+
+	// TLSClientCA switches HTTPS to mutual TLS: it names a PEM bundle of
+	// CA certificates, and connecting clients must present a certificate
+	// signed by one of them — machine-to-machine deployments get
+	// transport-level authentication without handing out tokens.
+	// TLSClientAuth relaxes "require" (the default) to "verify", which
+	// checks a certificate when one is presented but still admits bare
+	// TLS clients, so certificate and token auth can share a port.
+	TLSClientCA   string `json:"tls_client_ca,omitempty"`
+	TLSClientAuth string `json:"tls_client_auth,omitempty"`
+
+	// Users, when non-empty, switches the server to authenticated mode:
+	// every request needs a user token and non-admin users are confined
+	// to a home directory named after them under the storage root.
+	Users []UserConfig `json:"users,omitempty"`
+}
+
+// ExportConfig maps one named storage root onto a directory. A zero
+// MaxFileSize inherits the server-wide limit; TempDir overrides the
+// temp file location for this root the way ServerConfig.TempDir does
+// for the main one.
+type ExportConfig struct {
+	Name        string       `json:"name"`
+	Dir         string       `json:"dir"`
+	MaxFileSize config.Bytes `json:"max_file_size,omitempty"`
+	TempDir     string       `json:"temp_dir,omitempty"`
+}
+
+// BandwidthRule overrides the per-connection bandwidth cap for clients
+// whose address falls inside CIDR, given as a block ("10.0.0.0/8") or a
+// bare address. A zero cap exempts matching connections from bandwidth
+// throttling entirely, including the global MaxBandwidth pool.
+type BandwidthRule struct {
+	CIDR                string       `json:"cidr"`
+	MaxBandwidthPerConn config.Bytes `json:"max_bandwidth_per_conn,omitempty"`
+}
+
+// QuotaConfig caps the storage under one top-level directory by total
+// bytes and file count. Zero fields are unlimited.
+type QuotaConfig struct {
+	Path     string `json:"path"`
+	MaxBytes int64  `json:"max_bytes,omitempty"`
+	MaxFiles int64  `json:"max_files,omitempty"`
+}
+
+// WebhookConfig is one webhook endpoint. Events filters which event
+// types are delivered ("create", "modify", "delete", "download",
+// "upload_failed", "auth_ban"); empty means all. Secret, when set, signs every
+// delivery body with HMAC-SHA256, sent hex-encoded in the
+// X-Webhook-Signature header so receivers can verify the origin.
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// ExecHookConfig is one external command run on matching events. The
+// command is split on whitespace and a literal {path} in any argument
+// is replaced with the event's storage path
+// ("/usr/local/bin/scan.sh {path}"). Events filters the same event
+// names as WebhookConfig; empty means all. The command also receives
+// the event in UPLOAD_EVENT, UPLOAD_PATH, UPLOAD_SIZE, UPLOAD_ERROR
+// and UPLOAD_TIME environment variables, and is killed after
+// TimeoutSeconds (default 60).
+type ExecHookConfig struct {
+	Command        string   `json:"command"`
+	Events         []string `json:"events,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+// CompressionRule maps file types to a compression choice ("gzip" or
+// "none"). A file matches by extension ("zip" or ".zip") or by MIME
+// type, where a trailing slash matches the whole class ("video/"). The
+// first matching rule wins; unmatched files fall back to the built-in
+// list of already-compressed formats.
+type CompressionRule struct {
+	Extensions  []string `json:"extensions,omitempty"`
+	MimeTypes   []string `json:"mime_types,omitempty"`
+	Compression string   `json:"compression"`
+}
+
+// Defaults applied by NewServer for unset config fields.
+const (
+	DefaultPort                 = 8080
+	DefaultUploadDir            = "./uploads"
+	DefaultMaxFileSize          = 1 << 30 // 1 GiB
+	DefaultStatusRetentionHours = 24
+	DefaultTrashRetentionHours  = 168 // one week
+)
+
+// dataDirName is the reserved directory inside the storage root that
+// holds server state; it is excluded from every client-visible path.
+const dataDirName = ".upload-http"
+
+// Validate checks the ranges and cross-field rules that JSON decoding
+// cannot: port bounds, negative sizes and counts, permission strings,
+// webhook URLs. Zero values pass, since applyDefaults replaces them.
+// It backs the server's -validate-config flag, so mistakes surface
+// before a restart picks them up.
+func (c *ServerConfig) Validate() error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("port %d is out of range (1-65535)", c.Port)
+	}
+	byteFields := []struct {
+		name string
+		v    config.Bytes
+	}{
+		{"max_file_size", c.MaxFileSize},
+		{"min_free_bytes", c.MinFreeBytes},
+		{"max_bandwidth", c.MaxBandwidth},
+		{"max_bandwidth_per_conn", c.MaxBandwidthPerConn},
+		{"search_max_file_size", c.SearchMaxFileSize},
+	}
+	for _, f := range byteFields {
+		if f.v < 0 {
+			return fmt.Errorf("%s must not be negative", f.name)
+		}
+	}
+	countFields := []struct {
+		name string
+		v    int
+	}{
+		{"status_retention_hours", c.StatusRetentionHours},
+		{"max_concurrent_transfers", c.MaxConcurrentTransfers},
+		{"max_active_transfers", c.MaxActiveTransfers},
+		{"max_requests_per_second", c.MaxRequestsPerSecond},
+		{"reconcile_seconds", c.ReconcileSeconds},
+		{"max_versions", c.MaxVersions},
+		{"trash_retention_hours", c.TrashRetentionHours},
+		{"scrub_interval_hours", c.ScrubIntervalHours},
+		{"search_max_matches", c.SearchMaxMatches},
+		{"ban_after_failures", c.BanAfterFailures},
+		{"ban_minutes", c.BanMinutes},
+	}
+	for _, f := range countFields {
+		if f.v < 0 {
+			return fmt.Errorf("%s must not be negative", f.name)
+		}
+	}
+	switch c.DownloadCompression {
+	case "", "gzip", "none":
+	default:
+		return fmt.Errorf("unsupported download_compression %q (want gzip or none)", c.DownloadCompression)
+	}
+	if c.CompressionLevel != 0 && (c.CompressionLevel < gzip.HuffmanOnly || c.CompressionLevel > gzip.BestCompression) {
+		return fmt.Errorf("compression_level %d is out of range (%d-%d)", c.CompressionLevel, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	if _, err := parseMode(c.FileMode); err != nil {
+		return fmt.Errorf("file_mode: %w", err)
+	}
+	if _, err := parseMode(c.DirMode); err != nil {
+		return fmt.Errorf("dir_mode: %w", err)
+	}
+	for _, w := range c.Webhooks {
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook url %q must be an http or https URL", w.URL)
+		}
+	}
+	for _, h := range c.ExecHooks {
+		if strings.TrimSpace(h.Command) == "" {
+			return fmt.Errorf("exec hook has an empty command")
+		}
+	}
+	for _, b := range c.BandwidthRules {
+		if _, err := parseCIDRs("bandwidth_rules", []string{b.CIDR}); err != nil {
+			return err
+		}
+		if b.MaxBandwidthPerConn < 0 {
+			return fmt.Errorf("bandwidth rule %s: max_bandwidth_per_conn must not be negative", b.CIDR)
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		return fmt.Errorf("tls_client_ca needs tls_cert and tls_key")
+	}
+	switch c.TLSClientAuth {
+	case "", "require", "verify":
+	default:
+		return fmt.Errorf("unsupported tls_client_auth %q (want require or verify)", c.TLSClientAuth)
+	}
+	if c.TLSClientAuth != "" && c.TLSClientCA == "" {
+		return fmt.Errorf("tls_client_auth needs tls_client_ca")
+	}
+	seen := make(map[string]bool)
+	for _, e := range c.Exports {
+		if e.Name == "" || e.Dir == "" || strings.ContainsAny(e.Name, `/\`) || e.Name == dataDirName || seen[e.Name] {
+			return fmt.Errorf("invalid export %q", e.Name)
+		}
+		seen[e.Name] = true
+	}
+	return nil
+}
+
+func (c *ServerConfig) applyDefaults() {
+	if c.Port == 0 {
+		c.Port = DefaultPort
+	}
+	if c.UploadDir == "" {
+		c.UploadDir = DefaultUploadDir
+	}
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = DefaultMaxFileSize
+	}
+	if c.DataDir == "" {
+		c.DataDir = filepath.Join(c.UploadDir, dataDirName)
+	}
+	if c.StatusRetentionHours == 0 {
+		c.StatusRetentionHours = DefaultStatusRetentionHours
+	}
+	if c.TrashRetentionHours == 0 {
+		c.TrashRetentionHours = DefaultTrashRetentionHours
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestServerConfigValidate(t *testing.T) {
+	good := ServerConfig{
+		Port:        8080,
+		MaxFileSize: 100 << 20,
+		FileMode:    "0640",
+		Webhooks:    []WebhookConfig{{URL: "https://hooks.example.com/files"}},
+	}
+	if err := good.Validate(); err != nil {
+		t.Errorf("good config: %v", err)
+	}
+	// A zero config validates: defaults fill it in.
+	if err := (&ServerConfig{}).Validate(); err != nil {
+		t.Errorf("zero config: %v", err)
+	}
+
+	bad := []ServerConfig{
+		{Port: 70000},
+		{MaxFileSize: -1},
+		{StatusRetentionHours: -1},
+		{DownloadCompression: "brotli"},
+		{CompressionLevel: 12},
+		{FileMode: "rw-r--r--"},
+		{Webhooks: []WebhookConfig{{URL: "not-a-url"}}},
+		{ExecHooks: []ExecHookConfig{{Command: " "}}},
+		{Exports: []ExportConfig{{Name: "a/b", Dir: "/tmp"}}},
+		{BandwidthRules: []BandwidthRule{{CIDR: "10.0.0.0/40"}}},
+		{BandwidthRules: []BandwidthRule{{CIDR: "10.0.0.0/8", MaxBandwidthPerConn: -1}}},
+		{TLSCert: "server.pem"},
+		{TLSClientCA: "ca.pem"},
+		{TLSCert: "server.pem", TLSKey: "server.key", TLSClientCA: "ca.pem", TLSClientAuth: "optional"},
+	}
+	for _, cfg := range bad {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("config %+v validated", cfg)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// transferControl lets API handlers cancel or pause a transfer that is
+// being processed by another goroutine. The processing loop calls
+// checkpoint between files, which blocks while paused and reports
+// cancellation.
+type transferControl struct {
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+func newTransferControl(cancel context.CancelFunc) *transferControl {
+	return &transferControl{cancel: cancel}
+}
+
+// pause suspends the transfer at its next checkpoint.
+func (c *transferControl) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resume = make(chan struct{})
+	}
+}
+
+// unpause releases a paused transfer.
+func (c *transferControl) unpause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+	}
+}
+
+// checkpoint blocks while the transfer is paused and returns the context
+// error once the transfer is canceled.
+func (c *transferControl) checkpoint(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		paused := c.paused
+		resume := c.resume
+		c.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// registerControl attaches a control to a transfer ID for the lifetime of
+// the transfer.
+func (s *Server) registerControl(id string, c *transferControl) {
+	s.mu.Lock()
+	s.controls[id] = c
+	s.mu.Unlock()
+}
+
+func (s *Server) unregisterControl(id string) {
+	s.mu.Lock()
+	delete(s.controls, id)
+	s.mu.Unlock()
+}
+
+func (s *Server) control(id string) (*transferControl, bool) {
+	s.mu.Lock()
+	c, ok := s.controls[id]
+	s.mu.Unlock()
+	return c, ok
+}
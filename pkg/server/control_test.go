@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTransferControlPauseResumeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctrl := newTransferControl(cancel)
+
+	// Not paused: checkpoint returns immediately.
+	if err := ctrl.checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl.pause()
+	released := make(chan error, 1)
+	go func() { released <- ctrl.checkpoint(ctx) }()
+	select {
+	case err := <-released:
+		t.Fatalf("checkpoint returned %v while paused", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ctrl.unpause()
+	select {
+	case err := <-released:
+		if err != nil {
+			t.Fatalf("checkpoint after resume = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("checkpoint did not release after resume")
+	}
+
+	ctrl.cancel()
+	if err := ctrl.checkpoint(ctx); err != context.Canceled {
+		t.Errorf("checkpoint after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestCancelUnknownOrFinishedTransfer(t *testing.T) {
+	_, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "f.txt", "x")
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/status/"+ur.TransferID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("cancel finished transfer status = %d, want 409", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/api/status/nope", nil)
+	resp2, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp2.Body.Close()
+	if resp2.StatusCode != http.StatusNotFound {
+		t.Errorf("cancel unknown transfer status = %d, want 404", resp2.StatusCode)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// errUnsupportedEncoding is returned for Content-Encoding values the
+// server cannot decompress (anything but identity and gzip).
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// maxDecompressRatio caps how much a compressed upload body may expand,
+// so a tiny wire payload cannot balloon into an enormous decompressed
+// stream (a decompression bomb). The ratio is only enforced once the
+// decompressed size passes ratioCheckFloor, since small bodies compress
+// to almost nothing legitimately.
+const (
+	maxDecompressRatio = 200
+	ratioCheckFloor    = 1 << 20 // 1 MiB
+)
+
+// countingReader counts the bytes read through it; used to track wire
+// bytes underneath the decompressor.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// bombGuard wraps a decompressed stream, failing the read once the
+// output exceeds the size cap or expands past maxDecompressRatio of the
+// wire bytes consumed so far.
+type bombGuard struct {
+	r    io.Reader
+	wire *countingReader
+	max  int64
+	n    int64
+}
+
+func (g *bombGuard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	g.n += int64(n)
+	if g.n > g.max {
+		return n, fmt.Errorf("decompressed body exceeds the %d byte limit", g.max)
+	}
+	if g.n > ratioCheckFloor && g.n/g.wire.n > maxDecompressRatio {
+		return n, fmt.Errorf("decompression ratio exceeds %d:1", maxDecompressRatio)
+	}
+	return n, err
+}
+
+func (g *bombGuard) Close() error { return nil }
+
+// decompressBody swaps the request body for a streaming decompressor
+// when the upload arrives with a Content-Encoding, enforcing the
+// decompressed-size cap and ratio check as the bytes flow. It returns
+// the wire-byte counter, which is nil for identity bodies.
+func decompressBody(r *http.Request, limit int64) (*countingReader, error) {
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "", "identity":
+		return nil, nil
+	case "gzip":
+		wire := &countingReader{r: r.Body}
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			return nil, fmt.Errorf("bad gzip body: %w", err)
+		}
+		r.Body = &bombGuard{r: gz, wire: wire, max: limit}
+		return wire, nil
+	default:
+		return nil, fmt.Errorf("%w %q", errUnsupportedEncoding, enc)
+	}
+}
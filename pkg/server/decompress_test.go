@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// gzipUpload posts a gzip-compressed multipart body with one file part.
+func gzipUpload(t *testing.T, url, name, content string) *http.Response {
+	t.Helper()
+	var plain bytes.Buffer
+	mw := multipart.NewWriter(&plain)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, content)
+	mw.Close()
+
+	var wire bytes.Buffer
+	gz := gzip.NewWriter(&wire)
+	gz.Write(plain.Bytes())
+	gz.Close()
+
+	req, err := http.NewRequest(http.MethodPost, url+"/upload", &wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestGzipUploadStoresAndRecordsWireBytes(t *testing.T) {
+	s, ts := newTestServer(t)
+	content := strings.Repeat("compressible ", 1000)
+	resp := gzipUpload(t, ts.URL, "gz/big.txt", content)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("gzip upload returned %d: %s", resp.StatusCode, body)
+	}
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "gz", "big.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("stored %d bytes, want %d", len(data), len(content))
+	}
+
+	st, ok := s.transfers.Get(ur.TransferID)
+	if !ok {
+		t.Fatalf("transfer %s not found", ur.TransferID)
+	}
+	if st.WireBytes == 0 || st.WireBytes >= st.Bytes {
+		t.Errorf("wire bytes = %d, stored bytes = %d; want 0 < wire < stored", st.WireBytes, st.Bytes)
+	}
+}
+
+func TestGzipUploadRejectsDecompressedOverLimit(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MaxFileSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// A few KiB of zeros compress to well under the wire cap but blow
+	// the decompressed limit.
+	resp := gzipUpload(t, ts.URL, "bomb.txt", strings.Repeat("\x00", 64*1024))
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestUploadRejectsUnknownEncoding(t *testing.T) {
+	_, ts := newTestServer(t)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/upload", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	req.Header.Set("Content-Encoding", "zstd")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", resp.StatusCode)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// insufficientSpaceError reports that storing more data would push free
+// disk space below the configured floor.
+type insufficientSpaceError struct {
+	free, floor int64
+}
+
+func (e *insufficientSpaceError) Error() string {
+	return fmt.Sprintf("insufficient storage: %d bytes free, floor is %d", e.free, e.floor)
+}
+
+// admitUpload enforces the free-space floor before an upload starts,
+// reserving the declared content length so concurrent uploads cannot
+// jointly overshoot the floor; the returned release must be called when
+// the upload finishes. With no floor configured every upload is
+// admitted.
+func (s *Server) admitUpload(r *http.Request) (func(), error) {
+	release := func() {}
+	if s.cfg.MinFreeBytes <= 0 {
+		return release, nil
+	}
+	free, err := freeSpace(s.cfg.UploadDir)
+	if err != nil {
+		// A failed statfs should not take uploads down with it.
+		s.logger.Errorf("check free space: %v", err)
+		return release, nil
+	}
+	declared := r.ContentLength
+	if declared < 0 {
+		declared = 0
+	}
+	s.mu.Lock()
+	pending := s.reserved
+	s.mu.Unlock()
+	if free-pending-declared < int64(s.cfg.MinFreeBytes) {
+		return nil, &insufficientSpaceError{free: free - pending, floor: int64(s.cfg.MinFreeBytes)}
+	}
+	s.mu.Lock()
+	s.reserved += declared
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		s.reserved -= declared
+		s.mu.Unlock()
+	}, nil
+}
+
+// checkFreeSpace re-checks the floor mid-upload, before each file is
+// stored, catching bodies larger than they declared and disk pressure
+// from outside the server.
+func (s *Server) checkFreeSpace() error {
+	if s.cfg.MinFreeBytes <= 0 {
+		return nil
+	}
+	free, err := freeSpace(s.cfg.UploadDir)
+	if err != nil || free >= int64(s.cfg.MinFreeBytes) {
+		return nil
+	}
+	return &insufficientSpaceError{free: free, floor: int64(s.cfg.MinFreeBytes)}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+
+package server
+
+import "errors"
+
+// freeSpace has no portable implementation on this platform; callers
+// treat the error as "unknown", so the free-space floor goes
+// unenforced and stats omit the free-byte count.
+func freeSpace(path string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestUploadRejectedBelowFreeSpaceFloor(t *testing.T) {
+	// A floor no filesystem can satisfy makes every upload hit the
+	// admission check.
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MinFreeBytes: 1 << 62})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp := uploadRaw(t, ts, "full.txt", "no room")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInsufficientStorage {
+		t.Fatalf("status = %d, want 507", resp.StatusCode)
+	}
+	var e api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != api.CodeInsufficientStorage {
+		t.Errorf("envelope code = %q, want %q", e.Code, api.CodeInsufficientStorage)
+	}
+}
+
+func TestHealthReportsFreeSpace(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Status    string `json:"status"`
+		FreeBytes int64  `json:"free_bytes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "ok" || health.FreeBytes <= 0 {
+		t.Errorf("health = %+v, want ok with positive free_bytes", health)
+	}
+}
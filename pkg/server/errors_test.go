@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestErrorsUseJSONEnvelope(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/stat?path=missing.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var e api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != api.CodeNotFound || e.Message == "" {
+		t.Errorf("envelope = %+v, want code %q", e, api.CodeNotFound)
+	}
+}
+
+func TestSizeLimitErrorCarriesTooLargeCode(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MaxFileSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	resp := uploadRaw(t, ts, "big.txt", "more than eight bytes")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	var e api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != api.CodeTooLarge {
+		t.Errorf("envelope code = %q, want %q", e.Code, api.CodeTooLarge)
+	}
+	if e.Limit != "max_file_size" || e.Value != 8 {
+		t.Errorf("envelope limit = %q/%d, want max_file_size/8", e.Limit, e.Value)
+	}
+}
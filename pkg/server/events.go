@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// eventHub fans server events out to the WebSocket clients connected to
+// GET /api/events, each with an optional path-prefix filter.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan api.WebhookEvent]string
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan api.WebhookEvent]string)}
+}
+
+// subscribe registers a listener for events whose path starts with
+// prefix (empty matches everything).
+func (h *eventHub) subscribe(prefix string) chan api.WebhookEvent {
+	ch := make(chan api.WebhookEvent, 64)
+	h.mu.Lock()
+	h.subs[ch] = prefix
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan api.WebhookEvent) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+// broadcast delivers ev to every matching subscriber without blocking;
+// a subscriber that cannot keep up loses events rather than stalling
+// the request that caused them.
+func (h *eventHub) broadcast(ev api.WebhookEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, prefix := range h.subs {
+		if prefix != "" && !strings.HasPrefix(ev.Path, prefix) {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// websocketMagic is the GUID every WebSocket handshake hashes into the
+// Sec-WebSocket-Accept response header (RFC 6455).
+const websocketMagic = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// handleEvents serves GET /api/events: a WebSocket stream of server
+// events — file created/modified/deleted, downloads, failed uploads —
+// as JSON WebhookEvent messages, so dashboards and sync clients can
+// react in real time. ?prefix= narrows the stream to paths under a
+// prefix; non-admin users only ever see their own namespace.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		writeError(w, http.StatusBadRequest, "websocket upgrade required")
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	if u != nil && !u.Admin {
+		prefix = namespacePath(u, prefix)
+		if prefix == u.Name {
+			// Bare home must not also match other users whose names
+			// share the prefix.
+			prefix += "/"
+		}
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "connection cannot be hijacked")
+		return
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + websocketMagic))
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := buf.Flush(); err != nil {
+		return
+	}
+
+	ch := s.events.subscribe(prefix)
+	defer s.events.unsubscribe(ch)
+
+	// Drain client frames in the background; a read error (including the
+	// close handshake) tears the connection down, which ends the write
+	// loop below.
+	go func() {
+		io.Copy(io.Discard, buf)
+		conn.Close()
+	}()
+
+	for ev := range ch {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		if err := writeTextFrame(conn, payload); err != nil {
+			return
+		}
+	}
+}
+
+// writeTextFrame writes one final unmasked text frame, as servers send
+// them.
+func writeTextFrame(w io.Writer, payload []byte) error {
+	header := []byte{0x81}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n < 1<<16:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		for shift := 56; shift >= 0; shift -= 8 {
+			header = append(header, byte(n>>shift))
+		}
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// dialEvents opens a raw WebSocket connection to /api/events and
+// returns it past the handshake.
+func dialEvents(t *testing.T, ts *httptest.Server, rawQuery string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	target := "/api/events"
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	req := "GET " + target + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return conn, br
+}
+
+// readEvent reads one text frame and decodes it.
+func readEvent(t *testing.T, br *bufio.Reader) api.WebhookEvent {
+	t.Helper()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[0] != 0x81 {
+		t.Fatalf("frame header = %#x, want final text frame", header[0])
+	}
+	n := int(header[1])
+	if n == 126 {
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(br, ext); err != nil {
+			t.Fatal(err)
+		}
+		n = int(ext[0])<<8 | int(ext[1])
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	var ev api.WebhookEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		t.Fatalf("bad event payload %q: %v", payload, err)
+	}
+	return ev
+}
+
+func TestEventsStreamBroadcastsChanges(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	conn, br := dialEvents(t, ts, "")
+	defer conn.Close()
+
+	uploadFile(t, ts, "live/a.txt", "watch this")
+	ev := readEvent(t, br)
+	if ev.Event != api.ChangeCreate || ev.Path != "live/a.txt" || ev.Size != 10 {
+		t.Errorf("event = %+v", ev)
+	}
+
+	deletePath(t, ts, "path=live/a.txt")
+	ev = readEvent(t, br)
+	if ev.Event != api.ChangeDelete || ev.Path != "live/a.txt" {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestEventsPrefixFilter(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	conn, br := dialEvents(t, ts, "prefix=watched/")
+	defer conn.Close()
+
+	uploadFile(t, ts, "elsewhere/b.txt", "x")
+	uploadFile(t, ts, "watched/c.txt", "y")
+
+	// The unwatched upload must be filtered out, so the first frame is
+	// the watched one.
+	ev := readEvent(t, br)
+	if ev.Path != "watched/c.txt" {
+		t.Errorf("event = %+v, want watched/c.txt first", ev)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// defaultExecHookTimeout bounds a hook command when the config does not
+// set its own timeout.
+const defaultExecHookTimeout = 60 * time.Second
+
+// execHookRunner runs configured external commands on server events
+// from a background goroutine, so scanners and indexers never stall the
+// request that caused the event.
+type execHookRunner struct {
+	logger *utils.Logger
+	hooks  []ExecHookConfig
+	ch     chan api.WebhookEvent
+}
+
+// newExecHookRunner returns a running hook runner, or nil when no exec
+// hooks are configured.
+func newExecHookRunner(hooks []ExecHookConfig, logger *utils.Logger) *execHookRunner {
+	if len(hooks) == 0 {
+		return nil
+	}
+	r := &execHookRunner{
+		logger: logger,
+		hooks:  hooks,
+		ch:     make(chan api.WebhookEvent, 256),
+	}
+	go r.run()
+	return r
+}
+
+// notify queues ev without blocking; when the queue is full the event
+// is dropped with a log line, trading completeness for never stalling a
+// transfer.
+func (r *execHookRunner) notify(ev api.WebhookEvent) {
+	select {
+	case r.ch <- ev:
+	default:
+		r.logger.Errorf("exec hook queue full, dropping %s event for %s", ev.Event, ev.Path)
+	}
+}
+
+func (r *execHookRunner) run() {
+	for ev := range r.ch {
+		for _, h := range r.hooks {
+			if !h.wants(ev.Event) {
+				continue
+			}
+			if err := r.runHook(h, ev); err != nil {
+				r.logger.Errorf("exec hook %q: %s event: %v", h.Command, ev.Event, err)
+			}
+		}
+	}
+}
+
+// runHook runs one hook command for ev, with the event in the
+// environment and {path} placeholders substituted, killed when it
+// outlives its timeout.
+func (r *execHookRunner) runHook(h ExecHookConfig, ev api.WebhookEvent) error {
+	argv := strings.Fields(h.Command)
+	if len(argv) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	for i, a := range argv {
+		argv[i] = strings.ReplaceAll(a, "{path}", ev.Path)
+	}
+	timeout := defaultExecHookTimeout
+	if h.TimeoutSeconds > 0 {
+		timeout = time.Duration(h.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(),
+		"UPLOAD_EVENT="+ev.Event,
+		"UPLOAD_PATH="+ev.Path,
+		fmt.Sprintf("UPLOAD_SIZE=%d", ev.Size),
+		"UPLOAD_ERROR="+ev.Error,
+		"UPLOAD_TIME="+ev.Time.Format(time.RFC3339),
+	)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("killed after %s", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// wants reports whether the hook's event filter admits event; an empty
+// filter admits everything.
+func (h ExecHookConfig) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
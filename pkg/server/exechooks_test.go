@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// writeHookScript writes a shell script that appends one
+// "<event> <arg> <size>" line per invocation to outFile.
+func writeHookScript(t *testing.T, outFile string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "hook.sh")
+	body := "#!/bin/sh\necho \"$UPLOAD_EVENT $1 $UPLOAD_SIZE\" >> " + outFile + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// waitForFile polls until path exists and returns its content.
+func waitForFile(t *testing.T, path string) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if b, err := os.ReadFile(path); err == nil {
+			return string(b)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("hook never wrote %s", path)
+	return ""
+}
+
+func TestExecHookRunsOnUpload(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events.log")
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		ExecHooks: []ExecHookConfig{{Command: writeHookScript(t, out) + " {path}"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "scan/me.txt", "suspicious bytes")
+
+	got := waitForFile(t, out)
+	if want := "create scan/me.txt 16\n"; !strings.Contains(got, want) {
+		t.Errorf("hook output = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestExecHookEventFilter(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "events.log")
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		ExecHooks: []ExecHookConfig{{
+			Command: writeHookScript(t, out) + " {path}",
+			Events:  []string{api.ChangeDelete},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "filtered.txt", "x")
+	deletePath(t, ts, "path=filtered.txt")
+
+	// The create must have been filtered out, so the first line is the
+	// delete.
+	got := waitForFile(t, out)
+	if !strings.HasPrefix(got, "delete filtered.txt") {
+		t.Errorf("hook output = %q, want the delete first", got)
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// expiryIndex persists which stored files expire when, keyed by
+// resolved filesystem path so the reaper can act without path
+// resolution.
+type expiryIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]expiryEntry
+}
+
+type expiryEntry struct {
+	Path      string    `json:"path"` // stored (namespaced) path, for journal events
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// newExpiryIndex loads the index persisted at path, starting empty if
+// the file does not exist yet.
+func newExpiryIndex(path string) (*expiryIndex, error) {
+	ix := &expiryIndex{path: path, entries: make(map[string]expiryEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ix.entries); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Put records that full (stored path nsRel) expires at the given time.
+func (ix *expiryIndex) Put(full, nsRel string, at time.Time) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[full] = expiryEntry{Path: nsRel, ExpiresAt: at}
+	return ix.flush()
+}
+
+// Remove drops the expiry for full, making it permanent.
+func (ix *expiryIndex) Remove(full string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if _, ok := ix.entries[full]; !ok {
+		return nil
+	}
+	delete(ix.entries, full)
+	return ix.flush()
+}
+
+// List returns a copy of all entries.
+func (ix *expiryIndex) List() map[string]expiryEntry {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	out := make(map[string]expiryEntry, len(ix.entries))
+	for full, e := range ix.entries {
+		out[full] = e
+	}
+	return out
+}
+
+// flush persists the index atomically; callers hold ix.mu.
+func (ix *expiryIndex) flush() error {
+	data, err := json.MarshalIndent(ix.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// requestTTL reads the upload TTL from the X-Expires-After header or
+// the ttl query parameter: a Go duration string ("90m", "24h") or plain
+// seconds. Zero means the upload never expires.
+func requestTTL(r *http.Request) (time.Duration, error) {
+	v := r.Header.Get(api.ExpiresAfterHeader)
+	if v == "" {
+		v = r.URL.Query().Get("ttl")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d, nil
+	}
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return time.Duration(n) * time.Second, nil
+	}
+	return 0, fmt.Errorf("invalid ttl %q", v)
+}
+
+// applyTTL records the expiry for every file resp stored, or clears it
+// when the upload carried no TTL — re-uploading a path without one
+// makes it permanent again.
+func (s *Server) applyTTL(resp *api.UploadResponse, ttl time.Duration) {
+	at := time.Now().UTC().Add(ttl)
+	for _, f := range resp.Files {
+		if f.Error != "" {
+			continue
+		}
+		full, err := s.resolvePath(f.StoredPath)
+		if err != nil {
+			continue
+		}
+		if ttl > 0 {
+			err = s.expiries.Put(full, f.StoredPath, at)
+		} else {
+			err = s.expiries.Remove(full)
+		}
+		if err != nil {
+			s.logger.Errorf("update expiry index: %v", err)
+		}
+	}
+}
+
+// reapExpired removes every file whose TTL passed before now, pruning
+// directories the removals leave empty, and returns how many files
+// went.
+func (s *Server) reapExpired(now time.Time) int {
+	n := 0
+	for full, e := range s.expiries.List() {
+		if e.ExpiresAt.After(now) {
+			continue
+		}
+		if err := os.RemoveAll(full); err != nil {
+			s.logger.Errorf("reap expired %s: %v", e.Path, err)
+			continue
+		}
+		if err := s.expiries.Remove(full); err != nil {
+			s.logger.Errorf("update expiry index: %v", err)
+		}
+		s.hashes.Invalidate(full)
+		s.noteChange(nil, e.Path, api.ChangeDelete, 0)
+		s.pruneEmptyDirs(full)
+		s.logger.Infof("expired %s", e.Path)
+		n++
+	}
+	return n
+}
+
+// pruneEmptyDirs removes now-empty parents of full up to (but never
+// including) the storage root that holds it.
+func (s *Server) pruneEmptyDirs(full string) {
+	root := filepath.Clean(s.cfg.UploadDir)
+	for _, e := range s.cfg.Exports {
+		if strings.HasPrefix(full, filepath.Clean(e.Dir)+string(filepath.Separator)) {
+			root = filepath.Clean(e.Dir)
+		}
+	}
+	for dir := filepath.Dir(full); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// Remove fails on non-empty directories, which is the stop
+		// condition.
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// uploadWithTTL posts one file part with the given X-Expires-After value
+// and returns the response.
+func uploadWithTTL(t *testing.T, ts *httptest.Server, name, content, ttl string) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, content)
+	mw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/upload", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(api.ExpiresAfterHeader, ttl)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestUploadWithTTLIsReaped(t *testing.T) {
+	s, ts := newTestServer(t)
+	resp := uploadWithTTL(t, ts, "drop/tmp.txt", "ephemeral", "1h")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload status = %d, want 200", resp.StatusCode)
+	}
+	uploadFile(t, ts, "drop/keep.txt", "stays")
+
+	if n := s.reapExpired(time.Now().UTC()); n != 0 {
+		t.Errorf("reaped %d files before expiry, want 0", n)
+	}
+	if n := s.reapExpired(time.Now().UTC().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("reaped %d files after expiry, want 1", n)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "drop", "tmp.txt")); !os.IsNotExist(err) {
+		t.Error("expired file still present")
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "drop", "keep.txt")); err != nil {
+		t.Errorf("file without TTL gone: %v", err)
+	}
+}
+
+func TestTTLReaperPrunesEmptyDirs(t *testing.T) {
+	s, ts := newTestServer(t)
+	resp := uploadWithTTL(t, ts, "scratch/deep/only.txt", "gone soon", "30s")
+	resp.Body.Close()
+
+	s.reapExpired(time.Now().UTC().Add(time.Minute))
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "scratch")); !os.IsNotExist(err) {
+		t.Error("empty directory left behind after reap")
+	}
+	if _, err := os.Stat(s.cfg.UploadDir); err != nil {
+		t.Errorf("storage root removed: %v", err)
+	}
+}
+
+func TestReuploadWithoutTTLMakesFilePermanent(t *testing.T) {
+	s, ts := newTestServer(t)
+	resp := uploadWithTTL(t, ts, "f.txt", "v1", "1s")
+	resp.Body.Close()
+	uploadFile(t, ts, "f.txt", "v2")
+
+	if n := s.reapExpired(time.Now().UTC().Add(time.Hour)); n != 0 {
+		t.Errorf("reaped %d files, want 0 after TTL was cleared", n)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "f.txt")); err != nil {
+		t.Errorf("file gone: %v", err)
+	}
+}
+
+func TestUploadRejectsInvalidTTL(t *testing.T) {
+	_, ts := newTestServer(t)
+	resp := uploadWithTTL(t, ts, "f.txt", "x", "soon")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("upload with bad ttl status = %d, want 400", resp.StatusCode)
+	}
+}
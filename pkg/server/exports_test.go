@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestExportStoresUnderItsOwnRoot(t *testing.T) {
+	archive := t.TempDir()
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Exports:   []ExportConfig{{Name: "archive", Dir: archive}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "archive/build/out.bin", "payload")
+	data, err := os.ReadFile(filepath.Join(archive, "build", "out.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "payload" {
+		t.Errorf("stored content = %q", data)
+	}
+	// Nothing should land under the default root.
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "archive")); !os.IsNotExist(err) {
+		t.Errorf("export leaked into upload dir (err=%v)", err)
+	}
+
+	resp, err := http.Get(ts.URL + "/download/archive/build/out.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("download from export status = %d", resp.StatusCode)
+	}
+}
+
+func TestExportListedAtRoot(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Exports:   []ExportConfig{{Name: "archive", Dir: t.TempDir()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/list?path=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range files {
+		if f.Name == "archive" && f.IsDir {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("export missing from root listing: %+v", files)
+	}
+}
+
+func TestExportEnforcesOwnSizeLimit(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Exports:   []ExportConfig{{Name: "tiny", Dir: t.TempDir(), MaxFileSize: 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Within the export limit.
+	uploadFile(t, ts, "tiny/ok.txt", "1234")
+
+	// Over the export limit but far below the server-wide default.
+	resp := uploadRaw(t, ts, "tiny/big.txt", "12345")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("oversized export upload status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestExportNameValidation(t *testing.T) {
+	for _, e := range []ExportConfig{
+		{Name: "", Dir: "x"},
+		{Name: "a/b", Dir: "x"},
+		{Name: dataDirName, Dir: "x"},
+	} {
+		_, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Exports: []ExportConfig{e}})
+		if err == nil || !strings.Contains(err.Error(), "invalid export") {
+			t.Errorf("export %+v: err = %v, want invalid export", e, err)
+		}
+	}
+}
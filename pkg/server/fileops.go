@@ -0,0 +1,226 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// handleFileOps serves the server-side file management endpoints:
+//
+//	POST /api/files/move          rename a file or directory
+//	POST /api/files/copy          duplicate a file (or directory with recursive)
+//	POST /api/files/delete-batch  delete everything matching a glob pattern
+//
+// All operate entirely on the server so no data flows through the
+// client.
+func (s *Server) handleFileOps(w http.ResponseWriter, r *http.Request) {
+	switch op := strings.TrimPrefix(r.URL.Path, "/api/files/"); op {
+	case "move", "copy":
+		s.fileOp(w, r, op)
+	case "delete-batch":
+		s.handleBatchDelete(w, r)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleCopy serves POST /api/copy, the short-form alias of
+// /api/files/copy.
+func (s *Server) handleCopy(w http.ResponseWriter, r *http.Request) {
+	s.fileOp(w, r, "copy")
+}
+
+func (s *Server) fileOp(w http.ResponseWriter, r *http.Request, op string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	var req api.FileOpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.From == "" || req.To == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	src, err := s.resolvePath(namespacePath(u, req.From))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dst, err := s.resolvePath(namespacePath(u, req.To))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if fi.IsDir() && op == "copy" && !req.Recursive {
+		writeError(w, http.StatusBadRequest, "copying a directory requires recursive")
+		return
+	}
+
+	// Capture the affected files before the tree changes so journal
+	// events can be emitted per file afterwards.
+	rels, sizes, err := s.filesUnder(src, req.From)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.mkdirAll(filepath.Dir(dst)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if op == "move" {
+		err = os.Rename(src, dst)
+	} else {
+		err = s.copyPath(src, dst)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if op == "move" {
+		s.hashes.Invalidate(src)
+	}
+
+	for i, rel := range rels {
+		toRel := path.Join(req.To, strings.TrimPrefix(rel, req.From))
+		if op == "move" {
+			s.noteChange(u, rel, api.ChangeDelete, 0)
+		}
+		s.noteChange(u, toRel, api.ChangeCreate, sizes[i])
+	}
+	s.logger.Infof("%s %s -> %s (%d files)", op, req.From, req.To, len(rels))
+	writeJSON(w, http.StatusOK, map[string]string{"op": op, "from": req.From, "to": req.To})
+}
+
+// handleMkdir serves POST /api/mkdir?path=, creating the directory and
+// any missing parents so empty folders survive mirroring.
+func (s *Server) handleMkdir(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	if rel == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.mkdirAll(full); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logger.Infof("created directory %s", rel)
+	writeJSON(w, http.StatusOK, map[string]string{"created": rel})
+}
+
+// filesUnder returns the user-visible paths and sizes of every regular
+// file at or below full, where rel is the user-visible path of full.
+func (s *Server) filesUnder(full, rel string) ([]string, []int64, error) {
+	var rels []string
+	var sizes []int64
+	err := filepath.Walk(full, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == dataDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		sub, err := filepath.Rel(full, p)
+		if err != nil {
+			return err
+		}
+		rels = append(rels, path.Join(rel, filepath.ToSlash(sub)))
+		sizes = append(sizes, fi.Size())
+		return nil
+	})
+	return rels, sizes, err
+}
+
+// copyPath duplicates the file or directory tree at src as dst, with
+// the configured permissions applied to everything it creates.
+func (s *Server) copyPath(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return s.copyFile(src, dst)
+	}
+	return filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		sub, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, sub)
+		if fi.IsDir() {
+			return s.mkdirAll(target)
+		}
+		return s.copyFile(p, target)
+	})
+}
+
+// copyFile copies one file through a temp file and rename, mirroring how
+// uploads are stored so a failure never leaves a partial destination.
+func (s *Server) copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), uploadTmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	// A filesystem-level clone shares blocks copy-on-write, making the
+	// copy instant regardless of size; filesystems without reflink
+	// support fall back to a byte copy.
+	if reflink(out, in) != nil {
+		_, err = io.Copy(out, in)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	s.applyPerms(dst, false)
+	return nil
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"os"
+	"syscall"
+)
+
+// reflink clones src into dst via the FICLONE ioctl. It fails cleanly
+// on filesystems without clone support, in which case the caller copies
+// bytes instead.
+func reflink(dst, src *os.File) error {
+	const ficlone = 0x40049409
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
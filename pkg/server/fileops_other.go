@@ -0,0 +1,13 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"os"
+)
+
+// reflink is unsupported off Linux; the caller copies bytes instead.
+func reflink(dst, src *os.File) error {
+	return errors.ErrUnsupported
+}
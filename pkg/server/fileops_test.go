@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func fileOp(t *testing.T, url, op string, req api.FileOpRequest) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	resp, err := http.Post(url+"/api/files/"+op, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMoveAndCopy(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "src/a.txt", "move me")
+
+	resp := fileOp(t, ts.URL, "move", api.FileOpRequest{From: "src/a.txt", To: "dst/a.txt"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("move returned %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "src", "a.txt")); !os.IsNotExist(err) {
+		t.Error("source still exists after move")
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "dst", "a.txt"))
+	if err != nil || string(data) != "move me" {
+		t.Errorf("moved content = %q, err = %v", data, err)
+	}
+
+	resp = fileOp(t, ts.URL, "copy", api.FileOpRequest{From: "dst/a.txt", To: "copy/a.txt"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("copy returned %d", resp.StatusCode)
+	}
+	for _, p := range []string{"dst/a.txt", "copy/a.txt"} {
+		if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, filepath.FromSlash(p))); err != nil {
+			t.Errorf("%s missing after copy: %v", p, err)
+		}
+	}
+}
+
+func TestCopyDirectoryRequiresRecursive(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "tree/a.txt", "a")
+
+	resp := fileOp(t, ts.URL, "copy", api.FileOpRequest{From: "tree", To: "tree2"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("non-recursive dir copy returned %d, want 400", resp.StatusCode)
+	}
+
+	resp = fileOp(t, ts.URL, "copy", api.FileOpRequest{From: "tree", To: "tree2", Recursive: true})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("recursive dir copy returned %d", resp.StatusCode)
+	}
+}
+
+func TestRecursiveDelete(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "gone/a.txt", "a")
+	uploadFile(t, ts, "gone/sub/b.txt", "b")
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/files?path=gone", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("dir delete without recursive returned %d, want 400", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/api/files?path=gone&recursive=true", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("recursive delete returned %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "gone")); !os.IsNotExist(err) {
+		t.Error("directory still exists after recursive delete")
+	}
+}
+
+func TestCopyEndpointAlias(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "orig/data.txt", "clone me")
+
+	body, _ := json.Marshal(api.FileOpRequest{From: "orig/data.txt", To: "dup/data.txt"})
+	resp, err := http.Post(ts.URL+"/api/copy", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("copy returned %d", resp.StatusCode)
+	}
+	b, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "dup", "data.txt"))
+	if err != nil || string(b) != "clone me" {
+		t.Errorf("copied content = %q, %v", b, err)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestHandlerMountsUnderPrefix(t *testing.T) {
+	h, err := NewHandler(ServerConfig{UploadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/storage/", http.StripPrefix("/storage", h))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, "mounted")
+	mw.Close()
+	resp, err := http.Post(ts.URL+"/storage/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload via prefix status = %d, want 200", resp.StatusCode)
+	}
+
+	lr, err := http.Get(ts.URL + "/storage/api/list?path=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lr.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(lr.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "a.txt" {
+		t.Errorf("list via prefix = %+v", files)
+	}
+
+	dr, err := http.Get(ts.URL + "/storage/download/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dr.Body.Close()
+	if b, _ := io.ReadAll(dr.Body); string(b) != "mounted" {
+		t.Errorf("download via prefix = %q", b)
+	}
+}
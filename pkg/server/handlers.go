@@ -0,0 +1,1258 @@
+package server
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// handleUpload accepts a multipart/form-data POST. Each "file" part is
+// stored under the storage directory using the part's file name, which may
+// contain a relative path to preserve folder structure. An optional "path"
+// form value prefixes every file in the request.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	ttl, err := requestTTL(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.acquireSlot(u) {
+		w.Header().Set("Retry-After", "1")
+		writeLimitError(w, http.StatusTooManyRequests, api.CodeBusy, "too many concurrent transfers", "max_concurrent_transfers", int64(s.cfg.MaxConcurrentTransfers))
+		return
+	}
+	defer s.releaseSlot(u)
+	if !s.acquireTransfer(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, "canceled while queued for a transfer slot")
+		return
+	}
+	defer s.releaseTransfer()
+	release, err := s.admitUpload(r)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	defer release()
+	// Per-path limits are enforced file by file below; the request body
+	// cap only needs to admit the largest configured limit.
+	limit := s.cfg.MaxFileSize
+	for _, e := range s.cfg.Exports {
+		if e.MaxFileSize > limit {
+			limit = e.MaxFileSize
+		}
+	}
+	r.Body = s.throttleBody(r, http.MaxBytesReader(w, r.Body, int64(limit)))
+	wire, err := decompressBody(r, int64(limit))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedEncoding) {
+			status = http.StatusUnsupportedMediaType
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	t := s.newTransfer()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctrl := newTransferControl(cancel)
+	s.registerControl(t.ID, ctrl)
+	defer s.unregisterControl(t.ID)
+
+	// Every temp artifact of this request lives in one session, removed
+	// wholesale however the request ends.
+	ses := s.newUploadSession()
+	defer ses.cleanup()
+
+	resp, err := s.processUpload(ctx, r, u, t, ctrl, ses)
+	if wire != nil {
+		// Stats carry both what crossed the wire and what was stored.
+		s.mu.Lock()
+		t.WireBytes = wire.n
+		s.mu.Unlock()
+	}
+	s.finishTransfer(t, err)
+	var files []api.UploadResult
+	if resp != nil {
+		files = resp.Files
+	}
+	s.finalizeSummary(t, files)
+	if err != nil {
+		s.logger.Errorf("upload %s failed: %v", t.ID, err)
+		s.notifyHook("upload_failed", "", 0, err.Error())
+		writeUploadError(w, err)
+		return
+	}
+	resp.TransferID = t.ID
+	s.applyTTL(resp, ttl)
+	writeUploadResponse(w, resp)
+}
+
+// writeUploadError classifies a failed upload for the error envelope:
+// checksum mismatches are 422, and size-limit failures carry the
+// too_large code so clients can tell them from other bad requests.
+// Limit violations additionally name the limit and its configured
+// value.
+func writeUploadError(w http.ResponseWriter, err error) {
+	status, code := http.StatusBadRequest, api.CodeBadRequest
+	var limit string
+	var value int64
+	var hm *hashMismatchError
+	var sl *sizeLimitError
+	var mb *http.MaxBytesError
+	var is *insufficientSpaceError
+	var qe *quotaExceededError
+	switch {
+	case errors.As(err, &hm):
+		status, code = http.StatusUnprocessableEntity, api.CodeChecksumMismatch
+	case errors.As(err, &sl):
+		code = api.CodeTooLarge
+		limit, value = "max_file_size", sl.limit
+	case errors.As(err, &mb):
+		code = api.CodeTooLarge
+		limit, value = "max_file_size", mb.Limit
+	case errors.As(err, &is):
+		status, code = http.StatusInsufficientStorage, api.CodeInsufficientStorage
+		limit, value = "min_free_bytes", is.floor
+	case errors.As(err, &qe):
+		status, code = http.StatusInsufficientStorage, api.CodeQuotaExceeded
+		limit, value = "quota_max_bytes", qe.limit
+		if qe.what == "files" {
+			limit = "quota_max_files"
+		}
+	}
+	if limit != "" {
+		writeLimitError(w, status, code, err.Error(), limit, value)
+		return
+	}
+	writeErrorCode(w, status, code, err.Error())
+}
+
+// writeUploadResponse sends an upload response with commit trailers so
+// the client can verify that what it streamed is what reached disk; the
+// trailers must be declared before the body is written.
+func writeUploadResponse(w http.ResponseWriter, resp *api.UploadResponse) {
+	w.Header().Set("Trailer", api.CommitSizeTrailer+", "+api.CommitHashTrailer)
+	writeJSON(w, http.StatusOK, resp)
+	var committed int64
+	for _, f := range resp.Files {
+		committed += f.Size
+	}
+	w.Header().Set(api.CommitSizeTrailer, strconv.FormatInt(committed, 10))
+	if len(resp.Files) == 1 {
+		w.Header().Set(api.CommitHashTrailer, resp.Files[0].Hash)
+	}
+}
+
+// processUpload stores every file part of the request, updating t as it
+// goes.
+func (s *Server) processUpload(ctx context.Context, r *http.Request, u *UserConfig, t *api.TransferStatus, ctrl *transferControl, ses *uploadSession) (*api.UploadResponse, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("parse multipart body: %w", err)
+	}
+	var prefix string
+	var firstErr error
+	var failed int
+	resp := &api.UploadResponse{}
+	for {
+		// Honour pause and cancel requests between files.
+		if err := ctrl.checkpoint(ctx); err != nil {
+			return nil, err
+		}
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "path" {
+			b, err := io.ReadAll(io.LimitReader(part, 4096))
+			if err != nil {
+				return nil, err
+			}
+			prefix = strings.TrimSpace(string(b))
+			continue
+		}
+		if part.FormName() != "file" || partFileName(part) == "" {
+			continue
+		}
+		rel := partFileName(part)
+		if prefix != "" {
+			rel = filepath.ToSlash(filepath.Join(prefix, rel))
+		}
+		res, err := s.processUploadedFile(part, u, rel, ses)
+		if err != nil {
+			// A failed part does not abort the batch: its outcome is
+			// recorded so the client can retry just this part.
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			resp.Files = append(resp.Files, api.UploadResult{Path: rel, Error: err.Error()})
+			s.mu.Lock()
+			t.TotalFiles++
+			s.mu.Unlock()
+			continue
+		}
+		resp.Files = append(resp.Files, *res)
+		s.mu.Lock()
+		t.CompletedFiles++
+		t.TotalFiles++
+		t.Bytes += res.Size
+		t.TotalBytes += res.Size
+		s.mu.Unlock()
+	}
+	if len(resp.Files) == 0 {
+		return nil, fmt.Errorf("no file parts in request")
+	}
+	// Only when every part failed is the request itself an error; mixed
+	// outcomes are reported per part in a normal response.
+	if failed == len(resp.Files) {
+		return nil, firstErr
+	}
+	return resp, nil
+}
+
+// processUploadedFile stores one multipart file part under the
+// user-visible path rel and returns what was written.
+func (s *Server) processUploadedFile(part *multipart.Part, u *UserConfig, rel string, ses *uploadSession) (*api.UploadResult, error) {
+	var modTime time.Time
+	if v := part.Header.Get(api.FileMtimeHeader); v != "" {
+		if mt, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			modTime = mt
+		}
+	}
+	return s.storeFile(part, u, rel, part.Header.Get(api.FileHashHeader), modTime, ses)
+}
+
+// storeFile streams content into the user-visible path rel, verifying
+// it against the claimed checksum when one is given and applying the
+// original modification time when it is non-zero. It is the single
+// write path shared by multipart and tar-stream uploads.
+func (s *Server) storeFile(content io.Reader, u *UserConfig, rel, claimed string, modTime time.Time, ses *uploadSession) (*api.UploadResult, error) {
+	// The stored location is namespaced per user, but responses keep the
+	// caller's view of the path.
+	dst, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", rel, err)
+	}
+	return s.storeFileAt(content, u, rel, dst, claimed, modTime, ses)
+}
+
+// storeFileAt is storeFile with the destination pre-resolved, which
+// lets staged (replace) uploads write under a staging directory while
+// reporting the final user-visible path.
+func (s *Server) storeFileAt(content io.Reader, u *UserConfig, rel, dst, claimed string, modTime time.Time, ses *uploadSession) (*api.UploadResult, error) {
+	if err := s.checkFreeSpace(); err != nil {
+		return nil, err
+	}
+	if err := s.mkdirAll(filepath.Dir(dst)); err != nil {
+		return nil, err
+	}
+	_, statErr := os.Stat(dst)
+	existed := statErr == nil
+	// Stream into a session temp file and rename only after a successful
+	// fsync, so a failed upload never leaves a truncated file under the
+	// final name.
+	f, err := ses.tempFile(dst)
+	if err != nil {
+		return nil, err
+	}
+	limit := s.maxSizeFor(namespacePath(u, rel))
+	// The checksum is computed while the bytes stream to disk, so the
+	// file is never re-read for hashing.
+	th := hash.NewTeeHasher()
+	n, err := io.Copy(f, th.Reader(io.LimitReader(content, limit+1)))
+	if err == nil && n > limit {
+		err = &sizeLimitError{limit: limit}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	// Verify against the sender's checksum, when given, before the rename
+	// so corrupted data never replaces a good file under the final name.
+	sum := th.Sum()
+	if err == nil && claimed != "" && claimed != sum {
+		err = &hashMismatchError{rel: rel, want: claimed, got: sum}
+	}
+	if err == nil {
+		err = s.checkQuota(namespacePath(u, rel), dst, n)
+	}
+	// The outgoing content is archived only once the replacement is
+	// fully validated, so failed uploads never burn a version slot.
+	if err == nil && existed && s.cfg.Versioning {
+		if verr := s.keepVersion(path.Clean(namespacePath(u, rel)), dst); verr != nil {
+			s.logger.Errorf("archive version of %s: %v", rel, verr)
+		}
+	}
+	if err == nil {
+		err = s.renameOrCopy(f.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("store %s: %w", rel, err)
+	}
+	if !modTime.IsZero() {
+		if err := os.Chtimes(dst, modTime, modTime); err != nil {
+			s.logger.Errorf("apply mtime to %s: %v", rel, err)
+		}
+	}
+	s.applyPerms(dst, false)
+	// The checksum just computed seeds the hash index so later listings
+	// and downloads do not re-read the file.
+	if fi, serr := os.Stat(dst); serr == nil {
+		if ierr := s.hashes.Put(dst, sum, fi.Size(), fi.ModTime()); ierr != nil {
+			s.logger.Errorf("update hash index: %v", ierr)
+		}
+	}
+	op := api.ChangeCreate
+	if existed {
+		op = api.ChangeModify
+	}
+	s.noteChange(u, rel, op, n)
+	s.logger.Infof("stored %s (%d bytes)", rel, n)
+	res := &api.UploadResult{
+		Path:       rel,
+		StoredPath: path.Clean(filepath.ToSlash(namespacePath(u, rel))),
+		Size:       n,
+		Hash:       sum,
+	}
+	res.Receipt = s.signer.Receipt(res, time.Now())
+	return res, nil
+}
+
+// hashMismatchError reports a part whose content did not hash to the
+// checksum the sender claimed in its X-File-Hash header.
+type hashMismatchError struct {
+	rel, want, got string
+}
+
+func (e *hashMismatchError) Error() string {
+	return fmt.Sprintf("%s: content hashed to %s, sender claimed %s", e.rel, e.got, e.want)
+}
+
+// sizeLimitError reports a file larger than its per-path size limit.
+type sizeLimitError struct {
+	limit int64
+}
+
+func (e *sizeLimitError) Error() string {
+	return fmt.Sprintf("exceeds the %d byte limit", e.limit)
+}
+
+// partFileName returns the raw multipart filename including any relative
+// directory components. Part.FileName cannot be used here because it
+// strips the directory part, which would flatten uploaded folder
+// structure; path safety is enforced by resolvePath instead.
+func partFileName(part *multipart.Part) string {
+	cd := part.Header.Get("Content-Disposition")
+	_, params, err := mime.ParseMediaType(cd)
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// handleDownload serves GET /download/{path}. Files are served directly;
+// directories are streamed as a tar.gz archive.
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	if !s.acquireSlot(u) {
+		w.Header().Set("Retry-After", "1")
+		writeLimitError(w, http.StatusTooManyRequests, api.CodeBusy, "too many concurrent transfers", "max_concurrent_transfers", int64(s.cfg.MaxConcurrentTransfers))
+		return
+	}
+	defer s.releaseSlot(u)
+	if !s.acquireTransfer(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, "canceled while queued for a transfer slot")
+		return
+	}
+	defer s.releaseTransfer()
+	rel := strings.TrimPrefix(r.URL.Path, "/download/")
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w = s.throttleResponse(w, r)
+	if fi.IsDir() {
+		s.handleDirectoryDownload(w, r, full)
+		return
+	}
+	if sum, err := s.hashFor(full, fi); err == nil {
+		w.Header().Set(api.FileHashHeader, sum)
+	}
+	if err := s.access.Record(full, time.Now().UTC()); err != nil {
+		s.logger.Errorf("update access index: %v", err)
+	}
+	s.notifyHook("download", path.Clean(namespacePath(u, rel)), fi.Size(), "")
+	http.ServeFile(w, r, full)
+}
+
+// hashFor returns the checksum of the file at full, served from the
+// hash index when the file is unchanged and computed (and cached)
+// otherwise.
+func (s *Server) hashFor(full string, fi os.FileInfo) (string, error) {
+	if sum, ok := s.hashes.Get(full, fi.Size(), fi.ModTime()); ok {
+		return sum, nil
+	}
+	sum, err := hash.HashFile(full)
+	if err != nil {
+		return "", err
+	}
+	if err := s.hashes.Put(full, sum, fi.Size(), fi.ModTime()); err != nil {
+		s.logger.Errorf("update hash index: %v", err)
+	}
+	return sum, nil
+}
+
+// treeHash returns the Merkle-style digest of the directory at full:
+// the per-file checksums (served from the hash index) combined over
+// sorted relative paths. It changes exactly when any file under the
+// directory changes, so one stat call can compare a whole folder
+// against a local tree hashed the same way.
+func (s *Server) treeHash(full string) (string, error) {
+	type treeFile struct {
+		rel  string
+		full string
+		fi   os.FileInfo
+	}
+	var list []treeFile
+	err := filepath.Walk(full, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == dataDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(full, p)
+		if err != nil {
+			return err
+		}
+		list = append(list, treeFile{rel: filepath.ToSlash(rel), full: p, fi: fi})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Cached entries come back from hashFor for free; a cold cache means
+	// hashing every file, which is independent work spread across cores.
+	files := make(map[string]string, len(list))
+	jobs := make(chan treeFile)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					continue
+				}
+				sum, err := s.hashFor(f.full, f.fi)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					files[f.rel] = sum
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, f := range list {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return hash.TreeHash(files), nil
+}
+
+// compressionFor picks the codec and gzip level for a directory
+// download: the compression query parameter wins, then a gzip-bearing
+// Accept-Encoding, then the configured default. explicit reports
+// whether the request named the codec itself, in which case the
+// compression policy leaves it alone. zstd is recognised but not built
+// into this server.
+func (s *Server) compressionFor(r *http.Request) (comp string, level int, explicit bool, err error) {
+	comp = r.URL.Query().Get("compression")
+	explicit = comp != ""
+	if comp == "" && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		comp = "gzip"
+	}
+	if comp == "" {
+		comp = s.cfg.DownloadCompression
+	}
+	if comp == "" {
+		comp = "gzip"
+	}
+	level = gzip.DefaultCompression
+	if s.cfg.CompressionLevel != 0 {
+		level = s.cfg.CompressionLevel
+	}
+	if ls := r.URL.Query().Get("level"); ls != "" {
+		n, err := strconv.Atoi(ls)
+		if err != nil || n < gzip.HuffmanOnly || n > gzip.BestCompression {
+			return "", 0, false, fmt.Errorf("invalid compression level %q", ls)
+		}
+		level = n
+	}
+	switch comp {
+	case "gzip", "none":
+		return comp, level, explicit, nil
+	case "zstd":
+		return "", 0, false, fmt.Errorf("zstd compression is not supported by this server")
+	default:
+		return "", 0, false, fmt.Errorf("unsupported compression %q", comp)
+	}
+}
+
+// handleDirectoryDownload streams dir as a tarball with paths relative
+// to the requested directory, compressed per compressionFor.
+func (s *Server) handleDirectoryDownload(w http.ResponseWriter, r *http.Request, dir string) {
+	comp, level, explicit, err := s.compressionFor(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Unless the request picked a codec itself, skip gzipping trees
+	// whose bytes are mostly in already-compressed formats.
+	if comp == "gzip" && !explicit && s.adviseTreeCompression(dir) == "none" {
+		s.logger.Infof("serving %s as plain tar: contents are mostly already compressed", dir)
+		comp = "none"
+	}
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if comp == "none" {
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=%q", filepath.Base(dir)+".tar"))
+		tw = tar.NewWriter(w)
+	} else {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=%q", filepath.Base(dir)+".tar.gz"))
+		gz, err = gzip.NewWriterLevel(w, level)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		tw = tar.NewWriter(gz)
+	}
+	// Each file is hashed as it streams; the sums become a trailing
+	// manifest member the client verifies its extracted files against.
+	manifest := make(map[string]string)
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fi.Name() == dataDirName {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		// Directory headers are included so empty directories survive
+		// the round trip.
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		th := hash.NewTeeHasher()
+		if _, err := io.Copy(tw, th.Reader(f)); err != nil {
+			return err
+		}
+		manifest[hdr.Name] = th.Sum()
+		return nil
+	})
+	if err == nil {
+		err = writeArchiveManifest(tw, manifest)
+	}
+	if err != nil {
+		// Headers are already sent; the truncated stream is the only
+		// signal left for the client.
+		s.logger.Errorf("directory download %s: %v", dir, err)
+		return
+	}
+	if err := tw.Close(); err == nil && gz != nil {
+		gz.Close()
+	}
+}
+
+// writeArchiveManifest appends the per-file checksum manifest as the
+// final member of a directory-download tar stream.
+func writeArchiveManifest(tw *tar.Writer, manifest map[string]string) error {
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    api.ArchiveManifestName,
+		Mode:    0o644,
+		Size:    int64(len(b)),
+		ModTime: time.Now().UTC(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+// handleList serves GET /api/list?path= returning one directory level.
+// With recursive=true the full tree below path is returned, and with
+// hash=true every file entry carries its checksum, which is what sync
+// and diff clients need to compare trees without downloading anything.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	rel := q.Get("path")
+	recursive := q.Get("recursive") == "true"
+	withHash := wantHash(q)
+
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var files []api.FileInfo
+	if recursive {
+		files, err = s.listTree(full, rel, withHash)
+	} else {
+		files, err = s.listDir(full, rel, withHash)
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	// Export roots are separate directories, so they do not show up when
+	// walking the storage root; surface them as directory entries at the
+	// top level for callers that can address them.
+	if !recursive && path.Clean(namespacePath(u, rel)) == "." {
+		for _, e := range s.cfg.Exports {
+			fi, err := os.Stat(e.Dir)
+			if err != nil {
+				continue
+			}
+			files = append(files, api.FileInfo{
+				Path:    e.Name,
+				Name:    e.Name,
+				ModTime: fi.ModTime().UTC(),
+				IsDir:   true,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// wantHash reports whether a listing request asked for checksums;
+// both hash=true and hashes=true are accepted.
+func wantHash(q url.Values) bool {
+	return q.Get("hash") == "true" || q.Get("hashes") == "true"
+}
+
+// handleStat serves GET /api/stat?path= returning the entry for a
+// single file or directory. With hash=true (or hashes=true) a file
+// entry carries its checksum, served from the hash index that uploads
+// seed, so hash-based sync needs no per-directory listing call.
+func (s *Server) handleStat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	rel := q.Get("path")
+	if rel == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	info := api.FileInfo{
+		Path:    path.Clean(filepath.ToSlash(rel)),
+		Name:    fi.Name(),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().UTC(),
+		IsDir:   fi.IsDir(),
+	}
+	if wantHash(q) {
+		if fi.IsDir() {
+			if sum, err := s.treeHash(full); err == nil {
+				info.Hash = sum
+			}
+		} else if sum, err := s.hashFor(full, fi); err == nil {
+			info.Hash = sum
+		}
+	}
+	s.attachAccess(&info, full)
+	writeJSON(w, http.StatusOK, info)
+}
+
+// handleCapabilities serves GET /api/capabilities: the server's limits
+// relevant to client tuning, so clients can clamp their own settings
+// instead of discovering the limits as 429s mid-transfer.
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if _, ok := s.userFor(w, r); !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, api.Capabilities{
+		MaxFileSize:          int64(s.cfg.MaxFileSize),
+		MaxParallelTransfers: s.cfg.MaxConcurrentTransfers,
+		MaxRequestsPerSecond: s.cfg.MaxRequestsPerSecond,
+	})
+}
+
+// attachAccess fills the download-count fields of info from the access
+// index; never-downloaded files keep the zero values, which the JSON
+// encoding omits.
+func (s *Server) attachAccess(info *api.FileInfo, full string) {
+	if a := s.access.Get(full); a.Count > 0 {
+		info.Downloads = a.Count
+		info.LastAccess = a.LastAccess
+	}
+}
+
+// listDir returns one directory level.
+func (s *Server) listDir(full, rel string, withHash bool) ([]api.FileInfo, error) {
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]api.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.Name() == dataDirName || strings.HasPrefix(e.Name(), uploadTmpPrefix) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		info := api.FileInfo{
+			Path:    filepath.ToSlash(filepath.Join(rel, e.Name())),
+			Name:    e.Name(),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime().UTC(),
+			IsDir:   fi.IsDir(),
+		}
+		if withHash && !fi.IsDir() {
+			if sum, err := s.hashFor(filepath.Join(full, e.Name()), fi); err == nil {
+				info.Hash = sum
+			}
+		}
+		s.attachAccess(&info, filepath.Join(full, e.Name()))
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// listTree returns every file and directory below full.
+func (s *Server) listTree(full, rel string, withHash bool) ([]api.FileInfo, error) {
+	var files []api.FileInfo
+	err := filepath.Walk(full, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == full {
+			return nil
+		}
+		if fi.IsDir() && fi.Name() == dataDirName {
+			return filepath.SkipDir
+		}
+		if !fi.IsDir() && strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		sub, err := filepath.Rel(full, p)
+		if err != nil {
+			return err
+		}
+		info := api.FileInfo{
+			Path:    filepath.ToSlash(filepath.Join(rel, sub)),
+			Name:    fi.Name(),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime().UTC(),
+			IsDir:   fi.IsDir(),
+		}
+		if withHash && !fi.IsDir() {
+			sum, err := s.hashFor(p, fi)
+			if err != nil {
+				return err
+			}
+			info.Hash = sum
+		}
+		s.attachAccess(&info, p)
+		files = append(files, info)
+		return nil
+	})
+	return files, err
+}
+
+// noteChange records a mutation of the user-visible path rel in both the
+// per-directory change index and the sequential journal; failures are
+// logged but do not fail the request that caused them.
+func (s *Server) noteChange(u *UserConfig, rel, op string, size int64) {
+	nsRel := path.Clean(namespacePath(u, rel))
+	now := time.Now().UTC()
+	if err := s.changes.Note(nsRel, now); err != nil {
+		s.logger.Errorf("update change index: %v", err)
+	}
+	if err := s.journal.Append(op, nsRel, size, now); err != nil {
+		s.logger.Errorf("append change journal: %v", err)
+	}
+	s.observePath(nsRel, op)
+	s.notifyHook(op, nsRel, size, "")
+}
+
+// handleChanges serves GET /api/changes in two modes. With since=<RFC3339>
+// it returns the files modified after the given instant, using the
+// per-directory change index so only touched subtrees are rescanned.
+// Otherwise it serves the sequential journal: cursor= resumes after a
+// previously returned cursor and wait= long-polls up to that duration
+// for new events, which is how mirrors stay current without periodic
+// full listings.
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr == "" {
+		s.handleChangeJournal(w, r, u)
+		return
+	}
+	since, err := time.Parse(time.RFC3339Nano, sinceStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "since must be RFC 3339")
+		return
+	}
+	prefix := path.Clean(namespacePath(u, ""))
+	if prefix == "." {
+		prefix = ""
+	}
+
+	files := []api.FileInfo{}
+	for _, dir := range s.changes.ChangedSince(since, prefix) {
+		full, err := s.resolvePath(dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(full)
+		if err != nil {
+			continue // directory deleted since it was marked
+		}
+		for _, e := range entries {
+			fi, err := e.Info()
+			if err != nil || e.IsDir() || !fi.ModTime().After(since) {
+				continue
+			}
+			if strings.HasPrefix(e.Name(), uploadTmpPrefix) {
+				continue
+			}
+			rel := path.Join(dir, e.Name())
+			if prefix != "" {
+				rel = strings.TrimPrefix(rel, prefix+"/")
+			}
+			files = append(files, api.FileInfo{
+				Path:    rel,
+				Name:    e.Name(),
+				Size:    fi.Size(),
+				ModTime: fi.ModTime().UTC(),
+			})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	writeJSON(w, http.StatusOK, files)
+}
+
+// maxChangesWait caps how long one /api/changes long-poll may block.
+const maxChangesWait = time.Minute
+
+// handleChangeJournal serves the cursor-based journal mode of
+// /api/changes.
+func (s *Server) handleChangeJournal(w http.ResponseWriter, r *http.Request, u *UserConfig) {
+	q := r.URL.Query()
+	var cursor uint64
+	if cs := q.Get("cursor"); cs != "" {
+		var err error
+		cursor, err = strconv.ParseUint(cs, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "cursor must be an integer")
+			return
+		}
+	}
+	var wait time.Duration
+	if ws := q.Get("wait"); ws != "" {
+		var err error
+		wait, err = time.ParseDuration(ws)
+		if err != nil || wait < 0 {
+			writeError(w, http.StatusBadRequest, "wait must be a duration")
+			return
+		}
+		if wait > maxChangesWait {
+			wait = maxChangesWait
+		}
+	}
+
+	events := s.journal.Wait(r.Context(), cursor, wait)
+	prefix := path.Clean(namespacePath(u, ""))
+	if prefix == "." {
+		prefix = ""
+	}
+	list := api.ChangeList{Cursor: cursor, Events: []api.ChangeEvent{}}
+	for _, e := range events {
+		// The cursor advances over every event examined, including those
+		// outside the caller's namespace, so polling makes progress.
+		list.Cursor = e.Seq
+		if prefix != "" {
+			if e.Path != prefix && !strings.HasPrefix(e.Path, prefix+"/") {
+				continue
+			}
+			e.Path = strings.TrimPrefix(e.Path, prefix+"/")
+		}
+		list.Events = append(list.Events, e)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleFiles serves DELETE /api/files?path=, removing a single file, or
+// a whole directory when recursive=true is given. It also answers
+// HEAD /api/files?path=&hash= content probes: 200 when the stored file
+// matches the given checksum, 412 when it exists with different content
+// and 404 when it is absent, letting clients skip uploads the server
+// already has.
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		s.handleContentProbe(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	if rel == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if fi.IsDir() && r.URL.Query().Get("recursive") != "true" {
+		writeError(w, http.StatusBadRequest, "path is a directory (use recursive=true)")
+		return
+	}
+	if err := s.deletePath(u, rel, full, fi); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logger.Infof("deleted %s", rel)
+	writeJSON(w, http.StatusOK, map[string]string{"deleted": rel})
+}
+
+// deletePath removes the file or directory tree at full, where rel is
+// its user-visible path, and emits the per-file delete events. With the
+// trash enabled deletes are diverted there instead of destroying data;
+// /api/trash can restore them until retention purges the entry.
+func (s *Server) deletePath(u *UserConfig, rel, full string, fi os.FileInfo) error {
+	rels := []string{rel}
+	if fi.IsDir() {
+		var err error
+		rels, _, err = s.filesUnder(full, rel)
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	switch {
+	case s.cfg.Trash:
+		err = s.moveToTrash(u, rel, full, fi)
+	case fi.IsDir():
+		err = os.RemoveAll(full)
+	default:
+		err = os.Remove(full)
+	}
+	if err != nil {
+		return err
+	}
+	s.hashes.Invalidate(full)
+	for _, fileRel := range rels {
+		s.noteChange(u, fileRel, api.ChangeDelete, 0)
+	}
+	return nil
+}
+
+// handleContentProbe answers the HEAD mode of /api/files.
+func (s *Server) handleContentProbe(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	rel, want := q.Get("path"), q.Get("hash")
+	if rel == "" || want == "" {
+		writeError(w, http.StatusBadRequest, "path and hash are required")
+		return
+	}
+	full, err := s.resolvePath(namespacePath(u, rel))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil || fi.IsDir() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sum, err := s.hashFor(full, fi)
+	if err != nil || sum != want {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleStatus serves the per-transfer status endpoints:
+//
+//	GET    /api/status/{id}          current status
+//	GET    /api/status/{id}/summary  immutable end-of-transfer record
+//	DELETE /api/status/{id}          cancel a running transfer
+//	POST   /api/status/{id}/pause    suspend at the next file boundary
+//	POST   /api/status/{id}/resume   continue a paused transfer
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/status/")
+	id, action, _ := strings.Cut(rest, "/")
+	t, ok := s.transfers.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown transfer")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && action == "":
+		writeJSON(w, http.StatusOK, t)
+	case r.Method == http.MethodGet && action == "summary":
+		sum, ok := s.summaries.Get(id)
+		if !ok {
+			writeError(w, http.StatusConflict, "transfer has not finished")
+			return
+		}
+		writeJSON(w, http.StatusOK, sum)
+	case r.Method == http.MethodDelete && action == "":
+		s.cancelTransfer(w, id)
+	case r.Method == http.MethodPost && (action == "pause" || action == "resume"):
+		ctrl, running := s.control(id)
+		if !running {
+			writeError(w, http.StatusConflict, "transfer is not running")
+			return
+		}
+		s.mu.Lock()
+		if action == "pause" {
+			ctrl.pause()
+			t.State = api.TransferPaused
+		} else {
+			ctrl.unpause()
+			t.State = api.TransferRunning
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, t)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// cancelTransfer cancels the running transfer with the given ID, shared
+// by the public status API and the admin surface.
+func (s *Server) cancelTransfer(w http.ResponseWriter, id string) {
+	ctrl, running := s.control(id)
+	if !running {
+		writeError(w, http.StatusConflict, "transfer is not running")
+		return
+	}
+	ctrl.cancel()
+	ctrl.unpause() // unblock a paused transfer so it can observe the cancel
+	writeJSON(w, http.StatusOK, map[string]string{"status": "canceling"})
+}
+
+// handleStatusList serves GET /api/status returning every known
+// transfer, newest first.
+func (s *Server) handleStatusList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.transfers.List())
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := map[string]any{"status": "ok"}
+	if free, err := freeSpace(s.cfg.UploadDir); err == nil {
+		health["free_bytes"] = free
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends the standard JSON error envelope with the code
+// derived from the status; writeErrorCode is for callers that know a
+// more specific class than the status implies.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorCode(w, status, codeForStatus(status), message)
+}
+
+func writeErrorCode(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, api.ErrorResponse{Code: code, Message: message})
+}
+
+// writeLimitError is writeErrorCode for failures caused by a configured
+// limit: the envelope names the config key and carries its value.
+func writeLimitError(w http.ResponseWriter, status int, code, message, limit string, value int64) {
+	writeJSON(w, status, api.ErrorResponse{Code: code, Message: message, Limit: limit, Value: value})
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return api.CodeUnauthorized
+	case http.StatusNotFound:
+		return api.CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return api.CodeMethodNotAllowed
+	case http.StatusConflict:
+		return api.CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return api.CodeTooLarge
+	case http.StatusUnsupportedMediaType:
+		return api.CodeUnsupportedMedia
+	case http.StatusUnprocessableEntity:
+		return api.CodeChecksumMismatch
+	case http.StatusTooManyRequests:
+		return api.CodeBusy
+	case http.StatusInsufficientStorage:
+		return api.CodeInsufficientStorage
+	case http.StatusInternalServerError:
+		return api.CodeInternal
+	default:
+		return api.CodeBadRequest
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashIndex caches the checksum of every stored file keyed by its
+// resolved filesystem path, so hash listings, download headers and dedup
+// checks do not re-hash multi-GB files on every request. An entry is
+// only trusted while the file's size and mtime still match; anything
+// else counts as a miss and is re-hashed.
+type hashIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]hashEntry
+}
+
+type hashEntry struct {
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// newHashIndex loads the index persisted at path, starting empty if the
+// file does not exist yet.
+func newHashIndex(path string) (*hashIndex, error) {
+	ix := &hashIndex{path: path, entries: make(map[string]hashEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ix.entries); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Get returns the cached checksum for full, but only when the file's
+// current size and mtime match what was recorded.
+func (ix *hashIndex) Get(full string, size int64, modTime time.Time) (string, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e, ok := ix.entries[full]
+	if !ok || e.Size != size || !e.ModTime.Equal(modTime) {
+		return "", false
+	}
+	return e.Hash, true
+}
+
+// entry returns the raw cached entry for full without the freshness
+// check, for the scrubber to compare against.
+func (ix *hashIndex) entry(full string) (hashEntry, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e, ok := ix.entries[full]
+	return e, ok
+}
+
+// Put records the checksum of full together with the size and mtime it
+// was computed at.
+func (ix *hashIndex) Put(full, sum string, size int64, modTime time.Time) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[full] = hashEntry{Hash: sum, Size: size, ModTime: modTime}
+	return ix.flush()
+}
+
+// Invalidate drops the entries for full and, when it was a directory,
+// everything below it. Stale entries are never served (Get checks size
+// and mtime); this just keeps the index from accumulating dead paths.
+func (ix *hashIndex) Invalidate(full string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.entries, full)
+	for p := range ix.entries {
+		if strings.HasPrefix(p, full+string(os.PathSeparator)) {
+			delete(ix.entries, p)
+		}
+	}
+	return ix.flush()
+}
+
+// flush persists the index atomically; callers hold ix.mu.
+func (ix *hashIndex) flush() error {
+	data, err := json.MarshalIndent(ix.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+func TestHashIndexValidatesSizeAndModTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.json")
+	ix, err := newHashIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().UTC()
+	if err := ix.Put("/srv/a.txt", "deadbeef", 10, at); err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := ix.Get("/srv/a.txt", 10, at); !ok || sum != "deadbeef" {
+		t.Errorf("Get = %q, %v", sum, ok)
+	}
+	if _, ok := ix.Get("/srv/a.txt", 11, at); ok {
+		t.Error("size change still served from index")
+	}
+	if _, ok := ix.Get("/srv/a.txt", 10, at.Add(time.Second)); ok {
+		t.Error("mtime change still served from index")
+	}
+
+	// Entries survive a reload from disk.
+	ix2, err := newHashIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := ix2.Get("/srv/a.txt", 10, at); !ok || sum != "deadbeef" {
+		t.Errorf("reloaded Get = %q, %v", sum, ok)
+	}
+
+	if err := ix.Invalidate("/srv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ix.Get("/srv/a.txt", 10, at); ok {
+		t.Error("entry survived prefix invalidation")
+	}
+}
+
+func TestStatReturnsTreeHashForDirectory(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "tree/a.txt", "alpha")
+	uploadFile(t, ts, "tree/sub/b.txt", "beta")
+
+	info := statFile(t, ts, "tree&hash=true")
+	if !info.IsDir {
+		t.Fatalf("stat tree = %+v, want a directory", info)
+	}
+	want, err := hash.HashDir(filepath.Join(s.cfg.UploadDir, "tree"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Hash != want {
+		t.Errorf("tree hash = %s, want %s", info.Hash, want)
+	}
+
+	// Changing any file changes the directory's hash.
+	uploadFile(t, ts, "tree/sub/b.txt", "gamma")
+	if again := statFile(t, ts, "tree&hash=true"); again.Hash == info.Hash {
+		t.Error("tree hash unchanged after a file changed")
+	}
+
+	// Without hash=true directories stat without the (potentially
+	// expensive) digest.
+	if plain := statFile(t, ts, "tree"); plain.Hash != "" {
+		t.Errorf("stat without hash=true carried hash %s", plain.Hash)
+	}
+}
+
+func TestDownloadCarriesHashHeader(t *testing.T) {
+	_, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "h/file.txt", "hash me")
+
+	resp, err := http.Get(ts.URL + "/download/h/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("X-File-Hash"); got != ur.Files[0].Hash {
+		t.Errorf("X-File-Hash = %q, want %q", got, ur.Files[0].Hash)
+	}
+}
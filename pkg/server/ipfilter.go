@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ipFilter holds the parsed allow/deny CIDR lists. A non-empty allow
+// list admits only matching addresses; deny entries reject matches even
+// when allowed.
+type ipFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// newIPFilter parses the configured lists, or returns nil when both are
+// empty.
+func newIPFilter(allow, deny []string) (*ipFilter, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	a, err := parseCIDRs("allow_ips", allow)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseCIDRs("deny_ips", deny)
+	if err != nil {
+		return nil, err
+	}
+	return &ipFilter{allow: a, deny: d}, nil
+}
+
+// parseCIDRs parses entries as CIDR blocks; a bare address counts as a
+// single-host block.
+func parseCIDRs(field string, entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, e := range entries {
+		spec := e
+		if !strings.Contains(spec, "/") {
+			if ip := net.ParseIP(spec); ip != nil && ip.To4() != nil {
+				spec += "/32"
+			} else {
+				spec += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q", field, e)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// allowed reports whether ip passes the filter.
+func (f *ipFilter) allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range f.deny {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, n := range f.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// banList tracks consecutive authentication failures per client IP and
+// temporarily bans addresses that keep failing, slowing down token
+// guessing on servers exposed beyond a trusted LAN.
+type banList struct {
+	mu     sync.Mutex
+	after  int
+	ttl    time.Duration
+	fails  map[string]int
+	banned map[string]time.Time
+}
+
+// newBanList returns a ban list that bans after the given failure
+// count for minutes (default 15), or nil when after is not positive.
+func newBanList(after, minutes int) *banList {
+	if after <= 0 {
+		return nil
+	}
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return &banList{
+		after:  after,
+		ttl:    time.Duration(minutes) * time.Minute,
+		fails:  make(map[string]int),
+		banned: make(map[string]time.Time),
+	}
+}
+
+// isBanned reports whether ip is currently banned, clearing the entry
+// once it expires.
+func (b *banList) isBanned(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.banned[ip]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(b.banned, ip)
+		return false
+	}
+	return true
+}
+
+// fail records one authentication failure from ip and reports whether
+// it crossed the threshold into a ban.
+func (b *banList) fail(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fails[ip]++
+	if b.fails[ip] < b.after {
+		return false
+	}
+	delete(b.fails, ip)
+	b.banned[ip] = time.Now().Add(b.ttl)
+	return true
+}
+
+// success clears ip's failure count; only consecutive failures ban.
+func (b *banList) success(ip string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.fails, ip)
+}
+
+// remoteIP extracts the client address from the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ipGuard rejects requests from denied or banned addresses before any
+// handler runs.
+func (s *Server) ipGuard(w http.ResponseWriter, r *http.Request) bool {
+	if s.ipfilter == nil && s.bans == nil {
+		return true
+	}
+	ip := remoteIP(r)
+	if s.ipfilter != nil && !s.ipfilter.allowed(net.ParseIP(ip)) {
+		writeError(w, http.StatusForbidden, "address not allowed")
+		return false
+	}
+	if s.bans != nil && s.bans.isBanned(ip) {
+		writeError(w, http.StatusForbidden, "temporarily banned after repeated authentication failures")
+		return false
+	}
+	return true
+}
+
+// noteAuthResult feeds an authentication outcome into the ban list,
+// logging and emitting an audit event when an address crosses into a
+// ban.
+func (s *Server) noteAuthResult(r *http.Request, ok bool) {
+	if s.bans == nil {
+		return
+	}
+	ip := remoteIP(r)
+	if ok {
+		s.bans.success(ip)
+		return
+	}
+	if s.bans.fail(ip) {
+		s.logger.Infof("banned %s for %s after %d authentication failures", ip, s.bans.ttl, s.bans.after)
+		s.notifyHook("auth_ban", ip, 0, "repeated authentication failures")
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getWithToken issues a GET with an X-Auth-Token header and returns the
+// status code.
+func getWithToken(t *testing.T, url, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("X-Auth-Token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestDenyListBlocksAddress(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		DenyIPs:   []string{"127.0.0.0/8"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	if got := getWithToken(t, ts.URL+"/health", ""); got != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", got)
+	}
+}
+
+func TestAllowListAdmitsOnlyMatching(t *testing.T) {
+	for _, tt := range []struct {
+		allow []string
+		want  int
+	}{
+		{[]string{"10.0.0.0/8"}, http.StatusForbidden},
+		{[]string{"10.0.0.0/8", "127.0.0.1"}, http.StatusOK},
+	} {
+		s, err := NewServer(ServerConfig{
+			UploadDir: t.TempDir(),
+			AllowIPs:  tt.allow,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := httptest.NewServer(s)
+		if got := getWithToken(t, ts.URL+"/health", ""); got != tt.want {
+			t.Errorf("allow %v: status = %d, want %d", tt.allow, got, tt.want)
+		}
+		ts.Close()
+	}
+}
+
+func TestRepeatedAuthFailuresBanAddress(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir:        t.TempDir(),
+		Users:            []UserConfig{{Name: "alice", Token: "right"}},
+		BanAfterFailures: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		if got := getWithToken(t, ts.URL+"/api/list", "wrong"); got != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, got)
+		}
+	}
+	// Banned now; even the right token is turned away.
+	if got := getWithToken(t, ts.URL+"/api/list", "right"); got != http.StatusForbidden {
+		t.Errorf("status after ban = %d, want 403", got)
+	}
+}
+
+func TestAuthSuccessResetsFailureCount(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir:        t.TempDir(),
+		Users:            []UserConfig{{Name: "alice", Token: "right"}},
+		BanAfterFailures: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	getWithToken(t, ts.URL+"/api/list", "wrong")
+	getWithToken(t, ts.URL+"/api/list", "right")
+	getWithToken(t, ts.URL+"/api/list", "wrong")
+	if got := getWithToken(t, ts.URL+"/api/list", "right"); got != http.StatusOK {
+		t.Errorf("status = %d, want 200 (failures were not consecutive)", got)
+	}
+}
+
+func TestNewServerRejectsBadCIDR(t *testing.T) {
+	_, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		AllowIPs:  []string{"not-a-network"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "allow_ips") {
+		t.Errorf("err = %v, want invalid allow_ips entry", err)
+	}
+}
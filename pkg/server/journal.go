@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// journalCap bounds how many events the journal retains; mirrors that
+// fall further behind than this must resynchronize with a full listing.
+const journalCap = 10000
+
+// changeJournal is the sequential record of create/modify/delete events
+// behind GET /api/changes?cursor=. Events carry monotonically increasing
+// sequence numbers so a mirror can resume exactly where it left off, and
+// waiters are woken as soon as something is appended.
+type changeJournal struct {
+	mu      sync.Mutex
+	path    string
+	events  []api.ChangeEvent
+	nextSeq uint64
+	wake    chan struct{}
+}
+
+// newChangeJournal loads the journal persisted at path, starting empty
+// if the file does not exist yet.
+func newChangeJournal(path string) (*changeJournal, error) {
+	j := &changeJournal{
+		path:    path,
+		nextSeq: 1,
+		wake:    make(chan struct{}),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &j.events); err != nil {
+		return nil, err
+	}
+	if n := len(j.events); n > 0 {
+		j.nextSeq = j.events[n-1].Seq + 1
+	}
+	return j, nil
+}
+
+// Append records one event and wakes every long-poll waiter.
+func (j *changeJournal) Append(op, rel string, size int64, at time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.events = append(j.events, api.ChangeEvent{
+		Seq:  j.nextSeq,
+		Op:   op,
+		Path: rel,
+		Size: size,
+		Time: at,
+	})
+	j.nextSeq++
+	if len(j.events) > journalCap {
+		j.events = j.events[len(j.events)-journalCap:]
+	}
+	close(j.wake)
+	j.wake = make(chan struct{})
+	return j.flush()
+}
+
+// After returns the events with sequence numbers greater than cursor.
+func (j *changeJournal) After(cursor uint64) []api.ChangeEvent {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, e := range j.events {
+		if e.Seq > cursor {
+			out := make([]api.ChangeEvent, len(j.events)-i)
+			copy(out, j.events[i:])
+			return out
+		}
+	}
+	return nil
+}
+
+// Wait returns the events after cursor, blocking up to wait for at
+// least one to arrive. It returns early when ctx is done.
+func (j *changeJournal) Wait(ctx context.Context, cursor uint64, wait time.Duration) []api.ChangeEvent {
+	deadline := time.Now().Add(wait)
+	for {
+		j.mu.Lock()
+		wake := j.wake
+		j.mu.Unlock()
+		if evs := j.After(cursor); len(evs) > 0 || wait <= 0 {
+			return evs
+		}
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(remain)
+		select {
+		case <-wake:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+		timer.Stop()
+	}
+}
+
+// flush persists the journal atomically; callers hold the lock.
+func (j *changeJournal) flush() error {
+	data, err := json.Marshal(j.events)
+	if err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func getChanges(t *testing.T, url string) api.ChangeList {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list api.ChangeList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestChangeJournalCursor(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "j/a.txt", "one")
+	uploadFile(t, ts, "j/b.txt", "two")
+
+	list := getChanges(t, ts.URL+"/api/changes")
+	if len(list.Events) != 2 || list.Cursor != 2 {
+		t.Fatalf("initial journal = %+v", list)
+	}
+	if list.Events[0].Op != api.ChangeCreate || list.Events[0].Path != "j/a.txt" {
+		t.Errorf("first event = %+v", list.Events[0])
+	}
+
+	// Overwriting is a modify; resuming from the cursor sees only it.
+	uploadFile(t, ts, "j/a.txt", "one again")
+	list = getChanges(t, ts.URL+"/api/changes?cursor=2")
+	if len(list.Events) != 1 || list.Events[0].Op != api.ChangeModify {
+		t.Errorf("after cursor 2 = %+v", list)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/files?path=j/b.txt", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	list = getChanges(t, ts.URL+"/api/changes?cursor=3")
+	if len(list.Events) != 1 || list.Events[0].Op != api.ChangeDelete || list.Events[0].Path != "j/b.txt" {
+		t.Errorf("delete event = %+v", list)
+	}
+}
+
+func TestChangeJournalLongPoll(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		uploadFile(t, ts, "late.txt", "arrives mid-poll")
+	}()
+
+	start := time.Now()
+	list := getChanges(t, ts.URL+"/api/changes?cursor=0&wait=5s")
+	if len(list.Events) != 1 || list.Events[0].Path != "late.txt" {
+		t.Fatalf("long poll returned %+v", list)
+	}
+	if time.Since(start) >= 5*time.Second {
+		t.Error("long poll did not wake on append")
+	}
+}
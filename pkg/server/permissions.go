@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// setupPermissions parses the configured file and directory modes plus
+// the optional ownership once at startup, so every store path can apply
+// them without re-validating.
+func (s *Server) setupPermissions() error {
+	s.uid, s.gid = -1, -1
+	var err error
+	if s.fileMode, err = parseMode(s.cfg.FileMode); err != nil {
+		return fmt.Errorf("file_mode: %w", err)
+	}
+	if s.dirMode, err = parseMode(s.cfg.DirMode); err != nil {
+		return fmt.Errorf("dir_mode: %w", err)
+	}
+	if s.cfg.Owner != "" {
+		u, err := user.Lookup(s.cfg.Owner)
+		if err != nil {
+			return fmt.Errorf("owner: unknown user %q", s.cfg.Owner)
+		}
+		if s.uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("owner: %w", err)
+		}
+	}
+	if s.cfg.Group != "" {
+		g, err := user.LookupGroup(s.cfg.Group)
+		if err != nil {
+			return fmt.Errorf("group: unknown group %q", s.cfg.Group)
+		}
+		if s.gid, err = strconv.Atoi(g.Gid); err != nil {
+			return fmt.Errorf("group: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseMode reads an octal permission string such as "0640"; empty
+// keeps the built-in default.
+func parseMode(v string) (os.FileMode, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(v, 8, 32)
+	if err != nil || n > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q", v)
+	}
+	return os.FileMode(n), nil
+}
+
+// dirPerm is the mode for directories the server creates inside the
+// storage tree.
+func (s *Server) dirPerm() os.FileMode {
+	if s.dirMode != 0 {
+		return s.dirMode
+	}
+	return 0o755
+}
+
+// applyPerms sets the configured mode and ownership on one stored path.
+// Chmod runs after creation so the process umask cannot strip
+// configured bits; failures are logged rather than failing the store,
+// since the content itself is already safely on disk.
+func (s *Server) applyPerms(path string, dir bool) {
+	mode := s.fileMode
+	if dir {
+		mode = s.dirMode
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			s.logger.Errorf("chmod %s: %v", path, err)
+		}
+	}
+	if s.uid >= 0 || s.gid >= 0 {
+		if err := os.Chown(path, s.uid, s.gid); err != nil {
+			s.logger.Errorf("chown %s: %v", path, err)
+		}
+	}
+}
+
+// mkdirAll is os.MkdirAll for stored content: every directory it
+// actually creates gets the configured mode and ownership applied.
+func (s *Server) mkdirAll(path string) error {
+	if fi, err := os.Stat(path); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := s.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(path, s.dirPerm()); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+	s.applyPerms(path, true)
+	return nil
+}
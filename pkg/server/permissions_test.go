@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoredContentGetsConfiguredModes(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		FileMode:  "0600",
+		DirMode:   "0750",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	uploadFile(t, ts, "locked/secret.txt", "for our eyes only")
+
+	fi, err := os.Stat(filepath.Join(s.cfg.UploadDir, "locked", "secret.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %o, want 0600", fi.Mode().Perm())
+	}
+	di, err := os.Stat(filepath.Join(s.cfg.UploadDir, "locked"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Mode().Perm() != 0o750 {
+		t.Errorf("dir mode = %o, want 0750", di.Mode().Perm())
+	}
+}
+
+func TestInvalidModeRejectedAtStartup(t *testing.T) {
+	for _, mode := range []string{"rwxr-x", "01777", "9"} {
+		if _, err := NewServer(ServerConfig{UploadDir: t.TempDir(), FileMode: mode}); err == nil {
+			t.Errorf("file_mode %q accepted", mode)
+		}
+	}
+	if _, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Owner: "no-such-user-here"}); err == nil {
+		t.Error("unknown owner accepted")
+	}
+}
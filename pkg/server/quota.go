@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// quotaFor returns the quota configured for the top-level directory
+// scope, or nil when it is unrestricted.
+func (s *Server) quotaFor(scope string) *QuotaConfig {
+	for i := range s.cfg.Quotas {
+		if s.cfg.Quotas[i].Path == scope {
+			return &s.cfg.Quotas[i]
+		}
+	}
+	return nil
+}
+
+// quotaScope reduces a stored (namespaced) path to the top-level
+// directory that quotas are keyed by.
+func quotaScope(storedRel string) string {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(strings.TrimPrefix(storedRel, "/"))))
+	scope, _, _ := strings.Cut(clean, "/")
+	if scope == "." {
+		return ""
+	}
+	return scope
+}
+
+// usageUnder totals the regular files below dir, skipping server state
+// and in-progress uploads like filesUnder does. A missing directory
+// counts as empty.
+func (s *Server) usageUnder(dir string) (bytes, files int64, err error) {
+	err = filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == dataDirName || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		bytes += fi.Size()
+		files++
+		return nil
+	})
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return bytes, files, err
+}
+
+// checkQuota verifies that storing n more bytes at dst (the resolved
+// form of the namespaced path storedRel) keeps its top-level directory
+// within any configured quota. Replacing an existing file only counts
+// the size difference. Usage is measured from disk at upload time, so
+// out-of-band writes are charged too.
+func (s *Server) checkQuota(storedRel, dst string, n int64) error {
+	scope := quotaScope(storedRel)
+	q := s.quotaFor(scope)
+	if q == nil {
+		return nil
+	}
+	dir, err := s.resolvePath(scope)
+	if err != nil {
+		return err
+	}
+	bytes, files, err := s.usageUnder(dir)
+	if err != nil {
+		// Enforcement is best-effort: a failed measurement should not
+		// take uploads down with it.
+		s.logger.Errorf("measure quota usage under %s: %v", scope, err)
+		return nil
+	}
+	if fi, serr := os.Stat(dst); serr == nil && fi.Mode().IsRegular() {
+		bytes -= fi.Size()
+		files--
+	}
+	if q.MaxBytes > 0 && bytes+n > int64(q.MaxBytes) {
+		return &quotaExceededError{scope: scope, what: "bytes", used: bytes + n, limit: int64(q.MaxBytes)}
+	}
+	if q.MaxFiles > 0 && files+1 > q.MaxFiles {
+		return &quotaExceededError{scope: scope, what: "files", used: files + 1, limit: q.MaxFiles}
+	}
+	return nil
+}
+
+// quotaExceededError reports an upload that would push a top-level
+// directory over its configured quota.
+type quotaExceededError struct {
+	scope string
+	what  string
+	used  int64
+	limit int64
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s: %d of %d %s", e.scope, e.used, e.limit, e.what)
+}
+
+// handleQuota serves GET /api/quota?path=: current usage of the
+// top-level directory containing path plus its configured limits, so
+// clients can show usage before hitting the limit. Non-admin users are
+// confined to their home directory, which path defaults to.
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	scope := quotaScope(namespacePath(u, r.URL.Query().Get("path")))
+	if scope == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	dir, err := s.resolvePath(scope)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bytes, files, err := s.usageUnder(dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	st := api.QuotaStatus{Path: scope, Bytes: bytes, Files: files}
+	if q := s.quotaFor(scope); q != nil {
+		st.MaxBytes, st.MaxFiles = int64(q.MaxBytes), q.MaxFiles
+	}
+	writeJSON(w, http.StatusOK, st)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func newQuotaServer(t *testing.T, quotas ...QuotaConfig) *httptest.Server {
+	t.Helper()
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Quotas: quotas})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestUploadRejectedOverByteQuota(t *testing.T) {
+	ts := newQuotaServer(t, QuotaConfig{Path: "proj", MaxBytes: 10})
+	uploadFile(t, ts, "proj/a.txt", "12345")
+
+	resp := uploadRaw(t, ts, "proj/b.txt", "123456789")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInsufficientStorage {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("over-quota upload status = %d (%s), want 507", resp.StatusCode, body)
+	}
+	var er api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&er); err != nil {
+		t.Fatal(err)
+	}
+	if er.Code != api.CodeQuotaExceeded {
+		t.Errorf("error code = %q, want %q", er.Code, api.CodeQuotaExceeded)
+	}
+	if er.Limit != "quota_max_bytes" || er.Value != 10 {
+		t.Errorf("error limit = %q/%d, want quota_max_bytes/10", er.Limit, er.Value)
+	}
+
+	// Replacing a file charges only the size difference, and other
+	// top-level directories are unaffected.
+	uploadFile(t, ts, "proj/a.txt", "1234567890")
+	uploadFile(t, ts, "other/big.txt", "plenty of room over here")
+}
+
+func TestUploadRejectedOverFileQuota(t *testing.T) {
+	ts := newQuotaServer(t, QuotaConfig{Path: "proj", MaxFiles: 1})
+	uploadFile(t, ts, "proj/a.txt", "first")
+	uploadFile(t, ts, "proj/a.txt", "replaced, not added")
+
+	resp := uploadRaw(t, ts, "proj/b.txt", "second")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInsufficientStorage {
+		t.Errorf("second file status = %d, want 507", resp.StatusCode)
+	}
+}
+
+func TestQuotaEndpointReportsUsage(t *testing.T) {
+	ts := newQuotaServer(t, QuotaConfig{Path: "proj", MaxBytes: 100, MaxFiles: 10})
+	uploadFile(t, ts, "proj/a.txt", "12345")
+	uploadFile(t, ts, "proj/sub/b.txt", "123")
+
+	resp, err := http.Get(ts.URL + "/api/quota?path=proj/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st api.QuotaStatus
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		t.Fatal(err)
+	}
+	want := api.QuotaStatus{Path: "proj", Bytes: 8, Files: 2, MaxBytes: 100, MaxFiles: 10}
+	if st != want {
+		t.Errorf("quota = %+v, want %+v", st, want)
+	}
+
+	// Directories without a configured quota still report usage, with
+	// zero (unlimited) limits.
+	uploadFile(t, ts, "free/c.txt", "1234")
+	resp, err = http.Get(ts.URL + "/api/quota?path=free")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var free api.QuotaStatus
+	if err := json.NewDecoder(resp.Body).Decode(&free); err != nil {
+		t.Fatal(err)
+	}
+	if free.Bytes != 4 || free.MaxBytes != 0 {
+		t.Errorf("unrestricted quota = %+v, want 4 bytes and no limit", free)
+	}
+}
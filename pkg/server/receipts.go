@@ -0,0 +1,99 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// receiptSigner signs upload receipts with the server's Ed25519 key,
+// generated on first start and persisted in the data directory.
+type receiptSigner struct {
+	keyID string
+	priv  ed25519.PrivateKey
+	pub   ed25519.PublicKey
+}
+
+// storedKey is the on-disk form of the signing key.
+type storedKey struct {
+	KeyID   string `json:"key_id"`
+	Private string `json:"private_key"`
+}
+
+// loadOrCreateSigner reads the signing key at path, generating and
+// persisting a fresh one when none exists yet.
+func loadOrCreateSigner(path string) (*receiptSigner, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var sk storedKey
+		if err := json.Unmarshal(data, &sk); err != nil {
+			return nil, err
+		}
+		priv, err := base64.StdEncoding.DecodeString(sk.Private)
+		if err != nil || len(priv) != ed25519.PrivateKeySize {
+			return nil, os.ErrInvalid
+		}
+		key := ed25519.PrivateKey(priv)
+		return &receiptSigner{keyID: sk.KeyID, priv: key, pub: key.Public().(ed25519.PublicKey)}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pub)
+	rs := &receiptSigner{keyID: hex.EncodeToString(sum[:8]), priv: priv, pub: pub}
+	data, err = json.MarshalIndent(storedKey{
+		KeyID:   rs.keyID,
+		Private: base64.StdEncoding.EncodeToString(priv),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Receipt signs a receipt for the stored file described by res.
+func (rs *receiptSigner) Receipt(res *api.UploadResult, at time.Time) *api.Receipt {
+	r := &api.Receipt{
+		Path:      res.Path,
+		Size:      res.Size,
+		Hash:      res.Hash,
+		Time:      at.UTC(),
+		KeyID:     rs.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(rs.pub),
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(rs.priv, r.Payload()))
+	return r
+}
+
+// handleKey serves GET /api/key so clients can cross-check the key a
+// receipt claims to be signed with.
+func (s *Server) handleKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, api.KeyInfo{
+		KeyID:     s.signer.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.signer.pub),
+	})
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestUploadReturnsVerifiableReceipt(t *testing.T) {
+	_, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "signed.txt", "prove it")
+
+	r := ur.Files[0].Receipt
+	if r == nil {
+		t.Fatal("upload response has no receipt")
+	}
+	if r.Path != "signed.txt" || r.Size != 8 || r.KeyID == "" {
+		t.Errorf("receipt = %+v", r)
+	}
+	if err := r.Verify(); err != nil {
+		t.Errorf("valid receipt failed verification: %v", err)
+	}
+
+	tampered := *r
+	tampered.Size++
+	if err := tampered.Verify(); err == nil {
+		t.Error("tampered receipt verified")
+	}
+}
+
+func TestSignerKeyPersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	s1, err := NewServer(ServerConfig{UploadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := NewServer(ServerConfig{UploadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.signer.keyID != s2.signer.keyID {
+		t.Errorf("key ID changed across restarts: %s vs %s", s1.signer.keyID, s2.signer.keyID)
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// reconciler tracks the last known on-disk state of every stored file so
+// periodic scans can detect files added, changed or removed out-of-band
+// (scp, NFS, manual cleanup) and feed them into the change index and
+// journal without manual reindexing.
+type reconciler struct {
+	mu    sync.Mutex
+	known map[string]fileState // client-visible slash path -> state
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// scanTree walks the storage root and every export, returning the state
+// of all stored files keyed by their client-visible path.
+func (s *Server) scanTree() (map[string]fileState, error) {
+	out := make(map[string]fileState)
+	scan := func(root, prefix string) error {
+		return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				if fi.Name() == dataDirName {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if prefix != "" {
+				rel = prefix + "/" + rel
+			}
+			out[rel] = fileState{size: fi.Size(), modTime: fi.ModTime()}
+			return nil
+		})
+	}
+	if err := scan(s.cfg.UploadDir, ""); err != nil {
+		return nil, err
+	}
+	for _, e := range s.cfg.Exports {
+		if err := scan(e.Dir, e.Name); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Reconcile runs one scan of the storage tree and records every
+// difference from the last known state as a change, so files touched
+// out-of-band show up in listings and the change feed. It runs
+// periodically when reconcile_seconds is configured and may also be
+// called directly.
+func (s *Server) Reconcile() {
+	current, err := s.scanTree()
+	if err != nil {
+		s.logger.Errorf("reconcile scan: %v", err)
+		return
+	}
+	type event struct {
+		rel  string
+		op   string
+		size int64
+	}
+	var events []event
+	s.recon.mu.Lock()
+	for rel, st := range current {
+		old, ok := s.recon.known[rel]
+		switch {
+		case !ok:
+			events = append(events, event{rel, api.ChangeCreate, st.size})
+		case old.size != st.size || !old.modTime.Equal(st.modTime):
+			events = append(events, event{rel, api.ChangeModify, st.size})
+		}
+	}
+	for rel := range s.recon.known {
+		if _, ok := current[rel]; !ok {
+			events = append(events, event{rel, api.ChangeDelete, 0})
+		}
+	}
+	s.recon.known = current
+	s.recon.mu.Unlock()
+	for _, e := range events {
+		s.logger.Infof("reconcile: %s %s", e.op, e.rel)
+		s.noteChange(nil, e.rel, e.op, e.size)
+	}
+}
+
+// observePath refreshes the reconciler's view of one path the server
+// itself just changed, so the next scan does not report it again.
+func (s *Server) observePath(nsRel, op string) {
+	s.recon.mu.Lock()
+	defer s.recon.mu.Unlock()
+	if op == api.ChangeDelete {
+		delete(s.recon.known, nsRel)
+		return
+	}
+	full, err := s.resolvePath(nsRel)
+	if err != nil {
+		return
+	}
+	fi, err := os.Stat(full)
+	if err != nil || fi.IsDir() {
+		return
+	}
+	s.recon.known[nsRel] = fileState{size: fi.Size(), modTime: fi.ModTime()}
+}
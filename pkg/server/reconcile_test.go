@@ -0,0 +1,70 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestReconcileDetectsOutOfBandChanges(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	// A file dropped into storage behind the server's back.
+	oob := filepath.Join(s.cfg.UploadDir, "dropped.txt")
+	if err := os.WriteFile(oob, []byte("via scp"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.Reconcile()
+	list := getChanges(t, ts.URL+"/api/changes")
+	if len(list.Events) != 1 || list.Events[0].Op != api.ChangeCreate || list.Events[0].Path != "dropped.txt" {
+		t.Fatalf("after create = %+v", list)
+	}
+
+	// Modified in place.
+	if err := os.WriteFile(oob, []byte("via scp, longer"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.Reconcile()
+	list = getChanges(t, ts.URL+"/api/changes?cursor=1")
+	if len(list.Events) != 1 || list.Events[0].Op != api.ChangeModify {
+		t.Fatalf("after modify = %+v", list)
+	}
+
+	// Removed out-of-band.
+	if err := os.Remove(oob); err != nil {
+		t.Fatal(err)
+	}
+	s.Reconcile()
+	list = getChanges(t, ts.URL+"/api/changes?cursor=2")
+	if len(list.Events) != 1 || list.Events[0].Op != api.ChangeDelete {
+		t.Fatalf("after delete = %+v", list)
+	}
+}
+
+func TestReconcileIgnoresServerWrites(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "own.txt", "written by the server")
+
+	s.Reconcile()
+	list := getChanges(t, ts.URL+"/api/changes?cursor=1")
+	if len(list.Events) != 0 {
+		t.Errorf("reconcile replayed the server's own write: %+v", list.Events)
+	}
+}
+
+func TestReconcileSeedsFromExistingTree(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pre.txt"), []byte("already there"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer(ServerConfig{UploadDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Reconcile()
+	if got := len(s.journal.After(0)); got != 0 {
+		t.Errorf("startup replayed %d pre-existing files as changes", got)
+	}
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// scheduler runs the server's periodic maintenance jobs and remembers
+// how each run went for GET /api/jobs. Every job gets its own
+// goroutine, so a slow job only delays itself.
+type scheduler struct {
+	mu   sync.Mutex
+	jobs []*schedJob
+}
+
+// schedJob is one registered job. jitter, when positive, adds a random
+// slice of itself to every wait so servers sharing storage do not fire
+// in lockstep; onStartup runs the job once immediately when the
+// scheduler starts, for work that must not wait a full interval after a
+// restart.
+type schedJob struct {
+	name      string
+	interval  time.Duration
+	jitter    time.Duration
+	onStartup bool
+	run       func() error
+
+	runs    int64
+	lastRun time.Time
+	lastErr string
+	nextRun time.Time
+}
+
+// add registers a job; call before start.
+func (sc *scheduler) add(name string, interval, jitter time.Duration, onStartup bool, run func() error) {
+	sc.jobs = append(sc.jobs, &schedJob{
+		name:      name,
+		interval:  interval,
+		jitter:    jitter,
+		onStartup: onStartup,
+		run:       run,
+	})
+}
+
+// start launches one goroutine per registered job.
+func (sc *scheduler) start() {
+	for _, j := range sc.jobs {
+		go sc.loop(j)
+	}
+}
+
+func (sc *scheduler) loop(j *schedJob) {
+	if j.onStartup {
+		sc.runJob(j)
+	}
+	for {
+		wait := j.interval
+		if j.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(j.jitter)))
+		}
+		sc.mu.Lock()
+		j.nextRun = time.Now().UTC().Add(wait)
+		sc.mu.Unlock()
+		time.Sleep(wait)
+		sc.runJob(j)
+	}
+}
+
+// runJob executes the job once and records the outcome.
+func (sc *scheduler) runJob(j *schedJob) {
+	err := j.run()
+	sc.mu.Lock()
+	j.runs++
+	j.lastRun = time.Now().UTC()
+	j.lastErr = ""
+	if err != nil {
+		j.lastErr = err.Error()
+	}
+	sc.mu.Unlock()
+}
+
+// runNow executes the named job once, outside its schedule, and reports
+// how the run went; ok is false when no such job is registered.
+func (sc *scheduler) runNow(name string) (api.JobStatus, bool) {
+	sc.mu.Lock()
+	var j *schedJob
+	for _, cand := range sc.jobs {
+		if cand.name == name {
+			j = cand
+			break
+		}
+	}
+	sc.mu.Unlock()
+	if j == nil {
+		return api.JobStatus{}, false
+	}
+	sc.runJob(j)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return api.JobStatus{
+		Name:      j.name,
+		Interval:  j.interval.String(),
+		Runs:      j.runs,
+		LastRun:   j.lastRun,
+		LastError: j.lastErr,
+		NextRun:   j.nextRun,
+	}, true
+}
+
+// status reports every job in registration order.
+func (sc *scheduler) status() []api.JobStatus {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	out := make([]api.JobStatus, 0, len(sc.jobs))
+	for _, j := range sc.jobs {
+		out = append(out, api.JobStatus{
+			Name:      j.name,
+			Interval:  j.interval.String(),
+			Runs:      j.runs,
+			LastRun:   j.lastRun,
+			LastError: j.lastErr,
+			NextRun:   j.nextRun,
+		})
+	}
+	return out
+}
+
+// registerJobs wires the enabled maintenance tasks into the scheduler;
+// startBackground actually runs them.
+func (s *Server) registerJobs() {
+	if s.cfg.ReconcileSeconds > 0 {
+		iv := time.Duration(s.cfg.ReconcileSeconds) * time.Second
+		s.sched.add("reconcile", iv, iv/10, false, func() error {
+			s.Reconcile()
+			return nil
+		})
+	}
+	if s.cfg.Trash {
+		retention := time.Duration(s.cfg.TrashRetentionHours) * time.Hour
+		s.sched.add("trash-purge", time.Hour, 5*time.Minute, true, func() error {
+			s.purgeTrash(time.Now().Add(-retention))
+			return nil
+		})
+	}
+	if s.cfg.ScrubIntervalHours > 0 {
+		iv := time.Duration(s.cfg.ScrubIntervalHours) * time.Hour
+		s.sched.add("scrub", iv, iv/10, false, s.scrub)
+	}
+	s.sched.add("expiry-reap", time.Minute, 5*time.Second, true, func() error {
+		s.reapExpired(time.Now().UTC())
+		return nil
+	})
+	// NewServer already swept temp files once; this catches what
+	// accumulates while the server runs.
+	s.sched.add("temp-clean", time.Hour, 5*time.Minute, false, s.cleanTempFiles)
+	s.sched.add("storage-probe", 30*time.Second, 5*time.Second, true, s.checkStorageHealth)
+}
+
+// handleJobs serves GET /api/jobs: the background maintenance jobs with
+// their intervals and how their last runs went. Server-global state, so
+// admin-only when users are configured.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	if u != nil && !u.Admin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.sched.status())
+}
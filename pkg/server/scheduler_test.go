@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestSchedulerRunsJobsOnInterval(t *testing.T) {
+	sc := &scheduler{}
+	sc.add("tick", 10*time.Millisecond, 0, true, func() error { return nil })
+	sc.add("broken", 10*time.Millisecond, 0, true, func() error { return errors.New("boom") })
+	sc.start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st := sc.status()
+		if st[0].Runs >= 2 && st[1].Runs >= 2 {
+			if st[0].LastError != "" {
+				t.Errorf("tick job error = %q, want none", st[0].LastError)
+			}
+			if st[1].LastError != "boom" {
+				t.Errorf("broken job error = %q, want boom", st[1].LastError)
+			}
+			if st[0].LastRun.IsZero() || st[0].Interval != "10ms" {
+				t.Errorf("tick status = %+v", st[0])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs never ran twice: %+v", st)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobsEndpointListsRegisteredJobs(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Trash: true, ReconcileSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var jobs []api.JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, j := range jobs {
+		names[j.Name] = true
+	}
+	for _, want := range []string{"reconcile", "trash-purge", "expiry-reap", "temp-clean"} {
+		if !names[want] {
+			t.Errorf("job %q missing from /api/jobs: %v", want, names)
+		}
+	}
+}
+
+func TestJobsEndpointIsAdminOnly(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Users: []UserConfig{
+			{Name: "alice", Token: "alice-token"},
+			{Name: "root", Token: "root-token", Admin: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(token string) int {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/jobs", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Auth-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get("alice-token"); code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want 403", code)
+	}
+	if code := get("root-token"); code != http.StatusOK {
+		t.Errorf("admin status = %d, want 200", code)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+// quarantineDirName is the reserved area under DataDir where corrupted
+// files are moved when scrub_quarantine is set.
+const quarantineDirName = "quarantine"
+
+// scrubState holds what the most recent bitrot scrub found, served by
+// GET /api/scrub.
+type scrubState struct {
+	mu      sync.Mutex
+	lastRun time.Time
+	checked int64
+	corrupt []api.CorruptFile
+}
+
+// scrub re-hashes every stored file whose hash-index entry is still
+// fresh and flags the ones whose content no longer matches: unchanged
+// size and mtime with different bytes is the signature of silent disk
+// corruption, not of an out-of-band edit (which is the reconciler's
+// business and skipped here). With scrub_quarantine set, corrupted
+// files are additionally moved out of the tree so they can no longer be
+// served.
+func (s *Server) scrub() error {
+	now := time.Now().UTC()
+	type candidate struct {
+		full, rel, expected string
+	}
+	var cands []candidate
+	scan := func(root, prefix string) error {
+		return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				if fi.Name() == dataDirName {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !fi.Mode().IsRegular() || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+				return nil
+			}
+			e, ok := s.hashes.entry(p)
+			if !ok || e.Size != fi.Size() || !e.ModTime.Equal(fi.ModTime()) {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if prefix != "" {
+				rel = prefix + "/" + rel
+			}
+			cands = append(cands, candidate{full: p, rel: rel, expected: e.Hash})
+			return nil
+		})
+	}
+	err := scan(s.cfg.UploadDir, "")
+	for _, e := range s.cfg.Exports {
+		if serr := scan(e.Dir, e.Name); err == nil {
+			err = serr
+		}
+	}
+
+	// Re-hashing dominates a scrub and the files are independent, so it
+	// is spread across all cores.
+	paths := make([]string, len(cands))
+	for i, c := range cands {
+		paths[i] = c.full
+	}
+	sums, herr := hash.HashFiles(paths, 0)
+	if err == nil {
+		err = herr
+	}
+
+	var corrupt []api.CorruptFile
+	for _, c := range cands {
+		sum, ok := sums[c.full]
+		if !ok || sum == c.expected {
+			continue
+		}
+		cf := api.CorruptFile{
+			Path:         c.rel,
+			ExpectedHash: c.expected,
+			ActualHash:   sum,
+			DetectedAt:   now,
+		}
+		s.logger.Errorf("scrub: %s is corrupt: hash %s, recorded %s", c.rel, sum, c.expected)
+		if s.cfg.ScrubQuarantine {
+			if err := s.quarantineFile(c.full); err != nil {
+				s.logger.Errorf("quarantine %s: %v", c.rel, err)
+			} else {
+				cf.Quarantined = true
+				s.hashes.Invalidate(c.full)
+				s.noteChange(nil, c.rel, api.ChangeDelete, 0)
+			}
+		}
+		corrupt = append(corrupt, cf)
+	}
+	s.lastScrub.mu.Lock()
+	s.lastScrub.lastRun = now
+	s.lastScrub.checked = int64(len(sums))
+	s.lastScrub.corrupt = corrupt
+	s.lastScrub.mu.Unlock()
+	return err
+}
+
+// quarantineFile moves full into the quarantine area under DataDir,
+// keeping the base name for identification.
+func (s *Server) quarantineFile(full string) error {
+	dir := filepath.Join(s.cfg.DataDir, quarantineDirName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return s.renameOrCopy(full, filepath.Join(dir, s.ids()+"-"+filepath.Base(full)))
+}
+
+// handleScrub serves GET /api/scrub: the outcome of the most recent
+// bitrot scrub. Server-global state, so admin-only when users are
+// configured.
+func (s *Server) handleScrub(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	if u != nil && !u.Admin {
+		writeError(w, http.StatusForbidden, "admin only")
+		return
+	}
+	s.lastScrub.mu.Lock()
+	report := api.ScrubReport{
+		LastRun: s.lastScrub.lastRun,
+		Checked: s.lastScrub.checked,
+		Corrupt: append([]api.CorruptFile{}, s.lastScrub.corrupt...),
+	}
+	s.lastScrub.mu.Unlock()
+	writeJSON(w, http.StatusOK, report)
+}
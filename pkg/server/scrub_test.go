@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// rot overwrites the stored file with same-length content and restores
+// the original mtime, mimicking silent disk corruption.
+func rot(t *testing.T, full, content string) {
+	t.Helper()
+	fi, err := os.Stat(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(full, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func scrubReport(t *testing.T, ts *httptest.Server) api.ScrubReport {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/scrub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report api.ScrubReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestScrubDetectsBitrot(t *testing.T) {
+	s, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "docs/good.txt", "pristine")
+	uploadFile(t, ts, "docs/fine.txt", "untouched")
+	full := filepath.Join(s.cfg.UploadDir, "docs", "good.txt")
+	rot(t, full, "BITROTTT")
+
+	if err := s.scrub(); err != nil {
+		t.Fatal(err)
+	}
+	report := scrubReport(t, ts)
+	if report.Checked != 2 || len(report.Corrupt) != 1 {
+		t.Fatalf("report = %+v, want 2 checked and 1 corrupt", report)
+	}
+	cf := report.Corrupt[0]
+	if cf.Path != "docs/good.txt" || cf.ExpectedHash != ur.Files[0].Hash || cf.ActualHash == cf.ExpectedHash {
+		t.Errorf("corrupt entry = %+v", cf)
+	}
+	if cf.Quarantined {
+		t.Error("file quarantined without scrub_quarantine")
+	}
+	if _, err := os.Stat(full); err != nil {
+		t.Errorf("flagged file removed without quarantine: %v", err)
+	}
+}
+
+func TestScrubSkipsOutOfBandEdits(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "edited.txt", "original")
+	// A changed size (and naturally newer mtime) is an out-of-band edit
+	// for the reconciler, not corruption.
+	full := filepath.Join(s.cfg.UploadDir, "edited.txt")
+	if err := os.WriteFile(full, []byte("legitimately longer content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.scrub(); err != nil {
+		t.Fatal(err)
+	}
+	if report := scrubReport(t, ts); len(report.Corrupt) != 0 {
+		t.Errorf("corrupt = %+v, want none", report.Corrupt)
+	}
+}
+
+func TestScrubQuarantinesCorruptFiles(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), ScrubQuarantine: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	uploadFile(t, ts, "q.txt", "doomed content")
+	full := filepath.Join(s.cfg.UploadDir, "q.txt")
+	rot(t, full, "XXXXXX content")
+
+	if err := s.scrub(); err != nil {
+		t.Fatal(err)
+	}
+	report := scrubReport(t, ts)
+	if len(report.Corrupt) != 1 || !report.Corrupt[0].Quarantined {
+		t.Fatalf("report = %+v, want one quarantined entry", report)
+	}
+	if _, err := os.Stat(full); !os.IsNotExist(err) {
+		t.Error("corrupt file still in the storage tree")
+	}
+	entries, err := os.ReadDir(filepath.Join(s.cfg.DataDir, quarantineDirName))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("quarantine dir = %v, %v, want one file", entries, err)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.DataDir, quarantineDirName, entries[0].Name()))
+	if err != nil || string(data) != "XXXXXX content" {
+		t.Errorf("quarantined content = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Defaults for the search limits; see ServerConfig.
+const (
+	defaultSearchMaxFileSize = 10 << 20
+	defaultSearchMaxMatches  = 1000
+)
+
+// errSearchTruncated stops the walk once the match cap is hit.
+var errSearchTruncated = errors.New("search truncated")
+
+// handleGrep scans text files below ?path= for the query ?q= and
+// returns the matching lines. No index is kept: each request walks the
+// subtree and reads the files, bounded by the configured size and match
+// caps, which suits the logs-and-configs trees the feature is meant
+// for. With ?regex=true the query is a Go regular expression, otherwise
+// a literal substring.
+func (s *Server) handleGrep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	if !s.cfg.Search {
+		writeError(w, http.StatusNotFound, "search is not enabled on this server")
+		return
+	}
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	var re *regexp.Regexp
+	if r.URL.Query().Get("regex") == "true" {
+		var err error
+		if re, err = regexp.Compile(q); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid regex: "+err.Error())
+			return
+		}
+	}
+	rel := path.Clean(r.URL.Query().Get("path"))
+	root, err := s.resolvePath(path.Clean(namespacePath(u, rel)))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	maxSize := int64(s.cfg.SearchMaxFileSize)
+	if maxSize <= 0 {
+		maxSize = defaultSearchMaxFileSize
+	}
+	maxMatches := s.cfg.SearchMaxMatches
+	if maxMatches <= 0 {
+		maxMatches = defaultSearchMaxMatches
+	}
+	result := api.GrepResult{Query: q, Matches: []api.GrepMatch{}}
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == dataDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() || fi.Size() > maxSize || strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			return nil
+		}
+		sub, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		result.Scanned++
+		return s.grepFile(p, path.Join(rel, filepath.ToSlash(sub)), q, re, maxMatches, &result)
+	})
+	if err != nil && !errors.Is(err, errSearchTruncated) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// grepFile scans one file line by line, appending matches to result.
+// Binary files (a NUL byte in the first block) and files with lines too
+// long to scan are skipped silently; a grep over mixed content should
+// surface the text hits, not fail on the rest.
+func (s *Server) grepFile(full, rel, q string, re *regexp.Regexp, maxMatches int, result *api.GrepResult) error {
+	f, err := os.Open(full)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		return nil
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return nil
+	}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if re != nil {
+			if !re.MatchString(text) {
+				continue
+			}
+		} else if !strings.Contains(text, q) {
+			continue
+		}
+		result.Matches = append(result.Matches, api.GrepMatch{Path: rel, Line: line, Text: text})
+		if len(result.Matches) >= maxMatches {
+			result.Truncated = true
+			return errSearchTruncated
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func newSearchServer(t *testing.T, cfg ServerConfig) *httptest.Server {
+	t.Helper()
+	cfg.UploadDir = t.TempDir()
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func grep(t *testing.T, ts *httptest.Server, query string) api.GrepResult {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/grep?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("grep returned %d", resp.StatusCode)
+	}
+	var res api.GrepResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestGrepFindsLinesInTextFiles(t *testing.T) {
+	ts := newSearchServer(t, ServerConfig{Search: true})
+	uploadFile(t, ts, "logs/app.log", "started\nerror: disk full\nstopped\n")
+	uploadFile(t, ts, "logs/other.txt", "all fine here\n")
+	uploadFile(t, ts, "logs/blob.bin", "binary\x00error: not really text\n")
+
+	res := grep(t, ts, "q="+url.QueryEscape("error:")+"&path=logs")
+	if len(res.Matches) != 1 {
+		t.Fatalf("got %d matches, want 1: %+v", len(res.Matches), res.Matches)
+	}
+	m := res.Matches[0]
+	if m.Path != "logs/app.log" || m.Line != 2 || m.Text != "error: disk full" {
+		t.Errorf("match = %+v", m)
+	}
+	// The binary file was skipped, not scanned for text.
+	if res.Scanned != 3 {
+		t.Errorf("scanned %d files, want 3", res.Scanned)
+	}
+
+	res = grep(t, ts, "q="+url.QueryEscape("disk|fine")+"&regex=true&path=logs")
+	if len(res.Matches) != 2 {
+		t.Errorf("regex got %d matches, want 2: %+v", len(res.Matches), res.Matches)
+	}
+}
+
+func TestGrepTruncatesAtMatchCap(t *testing.T) {
+	ts := newSearchServer(t, ServerConfig{Search: true, SearchMaxMatches: 2})
+	uploadFile(t, ts, "big.log", "hit one\nhit two\nhit three\n")
+
+	res := grep(t, ts, "q=hit")
+	if len(res.Matches) != 2 || !res.Truncated {
+		t.Errorf("got %d matches (truncated=%v), want 2 truncated", len(res.Matches), res.Truncated)
+	}
+}
+
+func TestGrepRequiresOptInAndQuery(t *testing.T) {
+	_, ts := newTestServer(t) // search not enabled
+	resp, err := http.Get(ts.URL + "/api/grep?q=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("disabled search returned %d, want 404", resp.StatusCode)
+	}
+
+	ts2 := newSearchServer(t, ServerConfig{Search: true})
+	resp, err = http.Get(ts2.URL + "/api/grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing q returned %d, want 400", resp.StatusCode)
+	}
+}
@@ -0,0 +1,581 @@
+// Package server implements the upload-http HTTP server: multipart file
+// uploads, single-file and directory downloads, listings and transfer
+// status tracking.
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// Server serves the upload-http API for a single storage directory.
+type Server struct {
+	cfg    ServerConfig
+	logger *utils.Logger
+	mux    *http.ServeMux
+
+	// mu guards in-place mutation of running transfer statuses and the
+	// control map; the store handles its own locking and persistence.
+	mu        sync.Mutex
+	transfers *transferStore
+	summaries *summaryStore
+	controls  map[string]*transferControl
+	active    map[string]int // concurrent uploads per user name
+	reserved  int64          // bytes declared by admitted in-flight uploads
+	readOnly  bool           // set via /admin/readonly; rejects mutating requests
+
+	// degraded is set by the storage probe while the backend is
+	// unhealthy; degradedErr is what the last failing probe reported.
+	degraded    bool
+	degradedErr string
+
+	// changes tracks which directories changed when, backing the
+	// incremental /api/changes?since= queries; journal is the sequential
+	// event feed behind /api/changes?cursor=.
+	changes *changeIndex
+	journal *changeJournal
+
+	// hashes caches file checksums so listings and downloads do not
+	// re-hash unchanged files.
+	hashes *hashIndex
+
+	// access records download counts and last-access times per file,
+	// surfaced in listings and stat for retention decisions.
+	access *accessIndex
+
+	// trash records deleted content awaiting restore or purge when the
+	// trash is enabled.
+	trash *trashIndex
+
+	// expiries records per-upload TTLs for the background reaper.
+	expiries *expiryIndex
+
+	// signer signs upload receipts with the server's Ed25519 key.
+	signer *receiptSigner
+
+	// recon holds the last known on-disk state for out-of-band change
+	// detection.
+	recon *reconciler
+
+	// bw is the global bandwidth limiter, nil when unlimited;
+	// per-connection limits get a fresh limiter per request.
+	bw *utils.RateLimiter
+
+	// bwRules holds the parsed bandwidth_rules blocks, index-aligned
+	// with the config entries.
+	bwRules []*net.IPNet
+
+	// reqs caps requests per second per client, nil when unlimited.
+	reqs *requestLimiter
+
+	// ipfilter restricts which client addresses may connect and bans
+	// tracks addresses that keep failing authentication; each is nil
+	// when not configured.
+	ipfilter *ipFilter
+	bans     *banList
+
+	// transferGate caps transfer requests in flight server-wide, nil
+	// when uncapped; metadata endpoints never pass through it.
+	transferGate chan struct{}
+
+	// sched runs the periodic maintenance jobs once startBackground
+	// fires; /api/jobs reports their status.
+	sched *scheduler
+
+	// lastScrub is what the most recent bitrot scrub found.
+	lastScrub scrubState
+
+	// webhooks delivers transfer events to external URLs and execHooks
+	// runs external commands on them; each is nil when none are
+	// configured. events streams the same events to connected
+	// /api/events WebSocket clients.
+	webhooks  *webhookNotifier
+	execHooks *execHookRunner
+	events    *eventHub
+
+	// compress decides per file type whether archive content is worth
+	// gzipping.
+	compress *compressionPolicy
+
+	// fileMode and dirMode are the parsed permission overrides for
+	// stored content (zero = default); uid and gid are the parsed
+	// ownership, -1 when unchanged.
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	uid, gid int
+
+	// ids mints request, transfer and artifact IDs; clock stamps
+	// transfer statuses and summaries. Tests swap them for
+	// deterministic JSON output; production uses newID and time.Now.
+	ids   func() string
+	clock func() time.Time
+}
+
+// NewServer returns a Server for cfg with defaults applied. The upload
+// directory is created if it does not exist.
+func NewServer(cfg ServerConfig) (*Server, error) {
+	cfg.applyDefaults()
+	if err := os.MkdirAll(cfg.UploadDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	// Non-admin users are jailed to a home directory; create them up
+	// front so first listings do not 404.
+	for _, u := range cfg.Users {
+		if u.Admin {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(cfg.UploadDir, u.Name), 0o755); err != nil {
+			return nil, fmt.Errorf("create home for %s: %w", u.Name, err)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, e := range cfg.Exports {
+		if e.Name == "" || e.Dir == "" || strings.ContainsAny(e.Name, `/\`) || e.Name == dataDirName || seen[e.Name] {
+			return nil, fmt.Errorf("invalid export %q", e.Name)
+		}
+		seen[e.Name] = true
+		if err := os.MkdirAll(e.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create export %s: %w", e.Name, err)
+		}
+		if e.TempDir != "" {
+			if err := os.MkdirAll(e.TempDir, 0o755); err != nil {
+				return nil, fmt.Errorf("create temp dir for export %s: %w", e.Name, err)
+			}
+		}
+	}
+	if cfg.TempDir != "" {
+		if err := os.MkdirAll(cfg.TempDir, 0o755); err != nil {
+			return nil, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+	var out io.Writer = os.Stderr
+	if cfg.EnableLogging && cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		out = f
+	}
+	transfers, err := newTransferStore(
+		filepath.Join(cfg.DataDir, "transfers.json"),
+		time.Duration(cfg.StatusRetentionHours)*time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("open transfer store: %w", err)
+	}
+	summaries, err := newSummaryStore(
+		filepath.Join(cfg.DataDir, "summaries.json"),
+		time.Duration(cfg.StatusRetentionHours)*time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("open summary store: %w", err)
+	}
+	changes, err := newChangeIndex(filepath.Join(cfg.DataDir, "changes.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open change index: %w", err)
+	}
+	journal, err := newChangeJournal(filepath.Join(cfg.DataDir, "journal.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open change journal: %w", err)
+	}
+	hashes, err := newHashIndex(filepath.Join(cfg.DataDir, "hashes.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open hash index: %w", err)
+	}
+	access, err := newAccessIndex(filepath.Join(cfg.DataDir, "access.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open access index: %w", err)
+	}
+	trash, err := newTrashIndex(filepath.Join(cfg.DataDir, "trash.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open trash index: %w", err)
+	}
+	expiries, err := newExpiryIndex(filepath.Join(cfg.DataDir, "expiry.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open expiry index: %w", err)
+	}
+	signer, err := loadOrCreateSigner(filepath.Join(cfg.DataDir, "receipt_key.json"))
+	if err != nil {
+		return nil, fmt.Errorf("load receipt key: %w", err)
+	}
+	s := &Server{
+		cfg:       cfg,
+		logger:    utils.NewLogger(out, cfg.LogFormat, cfg.Debug),
+		transfers: transfers,
+		summaries: summaries,
+		controls:  make(map[string]*transferControl),
+		active:    make(map[string]int),
+		changes:   changes,
+		journal:   journal,
+		hashes:    hashes,
+		access:    access,
+		trash:     trash,
+		expiries:  expiries,
+		signer:    signer,
+		bw:        utils.NewRateLimiter(int64(cfg.MaxBandwidth)),
+		reqs:      newRequestLimiter(cfg.MaxRequestsPerSecond),
+		bans:      newBanList(cfg.BanAfterFailures, cfg.BanMinutes),
+		events:    newEventHub(),
+		sched:     &scheduler{},
+		ids:       newID,
+		clock:     time.Now,
+	}
+	// The stores prune on the server's clock so that swapping it in
+	// tests keeps retention consistent with the stamped timestamps.
+	transfers.now = func() time.Time { return s.clock() }
+	summaries.now = func() time.Time { return s.clock() }
+	if s.ipfilter, err = newIPFilter(cfg.AllowIPs, cfg.DenyIPs); err != nil {
+		return nil, err
+	}
+	for _, b := range cfg.BandwidthRules {
+		nets, err := parseCIDRs("bandwidth_rules", []string{b.CIDR})
+		if err != nil {
+			return nil, err
+		}
+		s.bwRules = append(s.bwRules, nets[0])
+	}
+	if s.compress, err = newCompressionPolicy(cfg.CompressionRules); err != nil {
+		return nil, err
+	}
+	if cfg.MaxActiveTransfers > 0 {
+		s.transferGate = make(chan struct{}, cfg.MaxActiveTransfers)
+	}
+	s.webhooks = newWebhookNotifier(cfg.Webhooks, s.logger)
+	s.execHooks = newExecHookRunner(cfg.ExecHooks, s.logger)
+	if err := s.setupPermissions(); err != nil {
+		return nil, err
+	}
+	if err := s.startupRecovery(transfers.recovered); err != nil {
+		return nil, fmt.Errorf("clean temp files: %w", err)
+	}
+	// The initial scan seeds the reconciler without emitting events, so a
+	// restart does not replay the whole tree as changes.
+	known, err := s.scanTree()
+	if err != nil {
+		return nil, fmt.Errorf("scan storage tree: %w", err)
+	}
+	s.recon = &reconciler{known: known}
+	s.registerJobs()
+	s.mux = http.NewServeMux()
+	s.routes()
+	return s, nil
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("/upload", s.handleUpload)
+	s.mux.HandleFunc("/api/upload/archive", s.handleArchiveUpload)
+	s.mux.HandleFunc("/download/", s.handleDownload)
+	s.mux.HandleFunc("/api/list", s.handleList)
+	s.mux.HandleFunc("/api/archive", s.handleArchive)
+	s.mux.HandleFunc("/api/stat", s.handleStat)
+	s.mux.HandleFunc("/api/changes", s.handleChanges)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/files", s.handleFiles)
+	s.mux.HandleFunc("/api/files/", s.handleFileOps)
+	s.mux.HandleFunc("/api/copy", s.handleCopy)
+	s.mux.HandleFunc("/api/mkdir", s.handleMkdir)
+	s.mux.HandleFunc("/api/quota", s.handleQuota)
+	s.mux.HandleFunc("/api/capabilities", s.handleCapabilities)
+	s.mux.HandleFunc("/api/trash", s.handleTrash)
+	s.mux.HandleFunc("/api/trash/restore", s.handleTrashRestore)
+	s.mux.HandleFunc("/api/versions", s.handleVersions)
+	s.mux.HandleFunc("/api/versions/restore", s.handleRestore)
+	s.mux.HandleFunc("/api/jobs", s.handleJobs)
+	s.mux.HandleFunc("/api/scrub", s.handleScrub)
+	s.mux.HandleFunc("/api/grep", s.handleGrep)
+	s.mux.HandleFunc("/api/status", s.handleStatusList)
+	s.mux.HandleFunc("/api/status/", s.handleStatus)
+	s.mux.HandleFunc("/api/key", s.handleKey)
+	s.mux.HandleFunc("/admin/config", s.handleAdminConfig)
+	s.mux.HandleFunc("/admin/transfers", s.handleAdminTransfers)
+	s.mux.HandleFunc("/admin/transfers/", s.handleAdminTransfers)
+	s.mux.HandleFunc("/admin/jobs/run", s.handleAdminRunJob)
+	s.mux.HandleFunc("/admin/readonly", s.handleAdminReadOnly)
+	s.mux.HandleFunc("/admin/stats", s.handleAdminStats)
+	s.mux.HandleFunc("/health", s.handleHealth)
+	s.mux.HandleFunc("/readyz", s.handleReady)
+}
+
+// ServeHTTP implements http.Handler so the server can be driven by
+// httptest and wrapped in middleware. Every request is logged with a
+// generated ID, which is also returned in the X-Request-ID header.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	id := s.ids()
+	w.Header().Set("X-Request-ID", id)
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	if s.ipGuard(sw, r) && s.rateLimit(sw, r) && s.readOnlyGuard(sw, r) && s.degradedGuard(sw, r) {
+		s.mux.ServeHTTP(sw, r)
+	}
+	s.logger.Debug("request",
+		"id", id,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"remote", r.RemoteAddr,
+		"status", sw.status,
+		"bytes", sw.bytes,
+		"duration", time.Since(start))
+}
+
+// statusWriter records the status code and body size for request logs.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Hijack passes through to the underlying connection so /api/events can
+// upgrade to a WebSocket.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// NewHandler returns the upload API as a plain http.Handler so
+// applications can mount it on their own mux — under a path prefix via
+// http.StripPrefix, wrapped in middleware — instead of running the
+// standalone binary. Background maintenance such as the reconcile loop
+// is started here, since embedded handlers never call Start.
+func NewHandler(cfg ServerConfig) (http.Handler, error) {
+	s, err := NewServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.startBackground()
+	return s, nil
+}
+
+// startBackground starts the scheduler running the maintenance jobs
+// registered for this config.
+func (s *Server) startBackground() {
+	s.sched.start()
+}
+
+// Start listens on the configured port and serves until the listener
+// fails — over HTTPS when tls_cert is configured, plain HTTP
+// otherwise. Bind failures come back as *StartError with a remediation
+// hint.
+func (s *Server) Start() error {
+	tc, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	addr := fmt.Sprintf(":%d", s.cfg.Port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return startError(s.cfg.Port, err)
+	}
+	scheme := "http"
+	if tc != nil {
+		ln = tls.NewListener(ln, tc)
+		scheme = "https"
+	}
+	s.startBackground()
+	s.logger.Infof("listening on %s (%s), storing files in %s", addr, scheme, s.cfg.UploadDir)
+	return http.Serve(ln, s)
+}
+
+// StartError is a failure to bring the server up, carrying a hint with
+// the likely fix; cmd/server prints the hint under the error.
+type StartError struct {
+	Err  error
+	Hint string
+}
+
+func (e *StartError) Error() string { return e.Err.Error() }
+func (e *StartError) Unwrap() error { return e.Err }
+
+// startError classifies the common ways binding the listen port fails
+// so the operator gets told what to do, not just what errno came back.
+func startError(port int, err error) error {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		return &StartError{Err: err, Hint: fmt.Sprintf(
+			"port %d is already in use; stop the other process (try: lsof -i :%d) or pick another port with -port", port, port)}
+	case errors.Is(err, syscall.EACCES) && port < 1024:
+		return &StartError{Err: err, Hint: fmt.Sprintf(
+			"binding port %d needs elevated privileges; run as root, grant the binary CAP_NET_BIND_SERVICE, or use a port above 1023", port)}
+	}
+	return err
+}
+
+// connCap resolves the per-connection bandwidth cap for the request's
+// client: the first bandwidth rule whose block contains the address
+// wins, the server-wide cap otherwise. exempt reports that a zero-cap
+// rule matched, which lifts the global limit for this connection too.
+func (s *Server) connCap(r *http.Request) (limit int64, exempt bool) {
+	if ip := net.ParseIP(remoteIP(r)); ip != nil {
+		for i, n := range s.bwRules {
+			if n.Contains(ip) {
+				limit = int64(s.cfg.BandwidthRules[i].MaxBandwidthPerConn)
+				return limit, limit == 0
+			}
+		}
+	}
+	return int64(s.cfg.MaxBandwidthPerConn), false
+}
+
+// throttleBody wraps an upload body with the global and per-connection
+// bandwidth limiters; with no limits applying to this client the body
+// is returned unchanged.
+func (s *Server) throttleBody(r *http.Request, rc io.ReadCloser) io.ReadCloser {
+	limit, exempt := s.connCap(r)
+	if exempt || (s.cfg.MaxBandwidth <= 0 && limit <= 0) {
+		return rc
+	}
+	body := s.bw.Reader(rc)
+	body = utils.NewRateLimiter(limit).Reader(body)
+	return struct {
+		io.Reader
+		io.Closer
+	}{body, rc}
+}
+
+// throttleResponse wraps w so the response body is bandwidth limited
+// the same way upload bodies are.
+func (s *Server) throttleResponse(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	limit, exempt := s.connCap(r)
+	if exempt || (s.cfg.MaxBandwidth <= 0 && limit <= 0) {
+		return w
+	}
+	tw := s.bw.Writer(w)
+	tw = utils.NewRateLimiter(limit).Writer(tw)
+	return &throttledResponseWriter{ResponseWriter: w, w: tw}
+}
+
+// throttledResponseWriter routes body writes through the bandwidth
+// limiters while keeping the header and status API intact.
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+func (t *throttledResponseWriter) Write(p []byte) (int, error) { return t.w.Write(p) }
+
+// acquireSlot reserves a concurrent transfer slot for the user when caps
+// are enabled; callers must releaseSlot on success.
+func (s *Server) acquireSlot(u *UserConfig) bool {
+	if s.cfg.MaxConcurrentTransfers <= 0 {
+		return true
+	}
+	key := ""
+	if u != nil {
+		key = u.Name
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[key] >= s.cfg.MaxConcurrentTransfers {
+		return false
+	}
+	s.active[key]++
+	return true
+}
+
+func (s *Server) releaseSlot(u *UserConfig) {
+	if s.cfg.MaxConcurrentTransfers <= 0 {
+		return
+	}
+	key := ""
+	if u != nil {
+		key = u.Name
+	}
+	s.mu.Lock()
+	s.active[key]--
+	s.mu.Unlock()
+}
+
+// acquireTransfer waits for a server-wide transfer slot, returning
+// false when the request is abandoned first. Only the transfer
+// endpoints pass through the gate, so metadata requests are served
+// immediately even while transfers queue; callers must releaseTransfer
+// on success.
+func (s *Server) acquireTransfer(ctx context.Context) bool {
+	if s.transferGate == nil {
+		return true
+	}
+	select {
+	case s.transferGate <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) releaseTransfer() {
+	if s.transferGate == nil {
+		return
+	}
+	<-s.transferGate
+}
+
+// newTransfer registers a transfer in the running state and returns it.
+func (s *Server) newTransfer() *api.TransferStatus {
+	t := &api.TransferStatus{
+		ID:        s.ids(),
+		State:     api.TransferRunning,
+		StartedAt: s.clock().UTC(),
+	}
+	if err := s.transfers.Put(t); err != nil {
+		s.logger.Errorf("persist transfer %s: %v", t.ID, err)
+	}
+	return t
+}
+
+func (s *Server) finishTransfer(t *api.TransferStatus, err error) {
+	s.mu.Lock()
+	t.FinishedAt = s.clock().UTC()
+	switch {
+	case err == nil:
+		t.State = api.TransferCompleted
+	case errors.Is(err, context.Canceled):
+		t.State = api.TransferCanceled
+		t.Error = "canceled"
+	default:
+		t.State = api.TransferFailed
+		t.Error = err.Error()
+	}
+	s.mu.Unlock()
+	if err := s.transfers.Put(t); err != nil {
+		s.logger.Errorf("persist transfer %s: %v", t.ID, err)
+	}
+}
+
+func newID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,652 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+	"github.com/milktea736/upload-http/pkg/hash"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// uploadRaw posts one file part and returns the raw response for tests
+// that care about failure statuses.
+func uploadRaw(t *testing.T, ts *httptest.Server, name, content string) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, content)
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func uploadFile(t *testing.T, ts *httptest.Server, name, content string) *api.UploadResponse {
+	t.Helper()
+	resp := uploadRaw(t, ts, name, content)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("upload returned %d: %s", resp.StatusCode, body)
+	}
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		t.Fatal(err)
+	}
+	return &ur
+}
+
+func TestUploadStoresFile(t *testing.T) {
+	s, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "dir/test.txt", "test content")
+
+	if len(ur.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(ur.Files))
+	}
+	if ur.Files[0].Path != "dir/test.txt" {
+		t.Errorf("stored path = %q", ur.Files[0].Path)
+	}
+	if ur.Files[0].StoredPath != "dir/test.txt" {
+		t.Errorf("canonical stored path = %q", ur.Files[0].StoredPath)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "dir", "test.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "test content" {
+		t.Errorf("stored content = %q", data)
+	}
+}
+
+func TestUploadCommitTrailers(t *testing.T) {
+	_, ts := newTestServer(t)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, _ := mw.CreateFormFile("file", "t.txt")
+	io.WriteString(part, "12345")
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // trailers arrive after the body
+	if got := resp.Trailer.Get(api.CommitSizeTrailer); got != "5" {
+		t.Errorf("%s = %q, want 5", api.CommitSizeTrailer, got)
+	}
+	if got := resp.Trailer.Get(api.CommitHashTrailer); got == "" {
+		t.Errorf("%s missing", api.CommitHashTrailer)
+	}
+}
+
+func TestUploadRejectsTraversal(t *testing.T) {
+	_, ts := newTestServer(t)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, _ := mw.CreateFormFile("file", "../escape.txt")
+	io.WriteString(part, "nope")
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestBatchUploadReportsPerPartOutcomes(t *testing.T) {
+	s, ts := newTestServer(t)
+	// A directory squatting on one destination makes that part's final
+	// rename fail, standing in for a mid-batch disk error.
+	if err := os.MkdirAll(filepath.Join(s.cfg.UploadDir, "blocked.txt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"ok1.txt":     "first",
+		"blocked.txt": "cannot land",
+		"ok2.txt":     "third",
+	} {
+		part, err := mw.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(part, content)
+	}
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("partial batch returned %d: %s", resp.StatusCode, body)
+	}
+	var ur api.UploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ur); err != nil {
+		t.Fatal(err)
+	}
+	if len(ur.Files) != 3 {
+		t.Fatalf("outcomes = %+v", ur.Files)
+	}
+	var failed, stored int
+	for _, f := range ur.Files {
+		if f.Error != "" {
+			failed++
+			if f.Path != "blocked.txt" {
+				t.Errorf("unexpected failed part %+v", f)
+			}
+		} else {
+			stored++
+			if f.Hash == "" {
+				t.Errorf("stored part missing hash: %+v", f)
+			}
+		}
+	}
+	if failed != 1 || stored != 2 {
+		t.Errorf("failed = %d, stored = %d", failed, stored)
+	}
+
+	// Retrying only the failed part succeeds once the obstruction is
+	// gone.
+	if err := os.Remove(filepath.Join(s.cfg.UploadDir, "blocked.txt")); err != nil {
+		t.Fatal(err)
+	}
+	uploadFile(t, ts, "blocked.txt", "cannot land")
+}
+
+func TestUploadRejectsHashMismatch(t *testing.T) {
+	s, ts := newTestServer(t)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="claimed.txt"`)
+	h.Set(api.FileHashHeader, "0000000000000000000000000000000000000000000000000000000000000000")
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, "actual content")
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "claimed.txt")); !os.IsNotExist(err) {
+		t.Errorf("mismatched file was stored (err=%v)", err)
+	}
+}
+
+func TestDownloadRoundTrip(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "a/b.txt", "round trip")
+
+	resp, err := http.Get(ts.URL + "/download/a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "round trip" {
+		t.Errorf("downloaded %q", body)
+	}
+}
+
+func TestDirectoryDownloadCompressionModes(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "cdir/a.txt", "uncompressed tar me")
+
+	// compression=none returns a plain tarball.
+	resp, err := http.Get(ts.URL + "/download/cdir?compression=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-tar" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	tr := tar.NewReader(resp.Body)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != "a.txt" {
+		t.Errorf("tar entry = %+v, err %v", hdr, err)
+	}
+
+	// An explicit gzip level still produces a valid archive.
+	resp, err = http.Get(ts.URL + "/download/cdir?compression=gzip&level=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tar.NewReader(gz).Next(); err != nil {
+		t.Errorf("level=1 archive unreadable: %v", err)
+	}
+
+	// zstd is recognised but not built in.
+	resp, err = http.Get(ts.URL + "/download/cdir?compression=zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("zstd status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestDirectoryDownloadTrailingManifest(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "mdir/a.txt", "alpha")
+	uploadFile(t, ts, "mdir/sub/b.txt", "beta")
+
+	resp, err := http.Get(ts.URL + "/download/mdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var last string
+	var manifest map[string]string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = hdr.Name
+		if hdr.Name == api.ArchiveManifestName {
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if last != api.ArchiveManifestName {
+		t.Fatalf("last member = %q, want the manifest", last)
+	}
+	want, err := hash.HashReader(strings.NewReader("alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest["a.txt"] != want {
+		t.Errorf("manifest[a.txt] = %q, want %q", manifest["a.txt"], want)
+	}
+	if len(manifest) != 2 {
+		t.Errorf("manifest lists %d files, want 2: %v", len(manifest), manifest)
+	}
+}
+
+func TestRecursiveListingWithHashes(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "tree/a.txt", "alpha")
+	uploadFile(t, ts, "tree/sub/b.txt", "beta")
+
+	resp, err := http.Get(ts.URL + "/api/list?path=tree&recursive=true&hash=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+
+	byPath := make(map[string]api.FileInfo)
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+	if len(byPath) != 3 { // a.txt, sub, sub/b.txt
+		t.Fatalf("got %d entries: %+v", len(byPath), files)
+	}
+	a, ok := byPath["tree/a.txt"]
+	if !ok || a.Hash == "" || a.Size != 5 {
+		t.Errorf("a.txt entry = %+v", a)
+	}
+	if sub, ok := byPath["tree/sub"]; !ok || !sub.IsDir {
+		t.Errorf("sub dir entry = %+v", sub)
+	}
+	if b, ok := byPath["tree/sub/b.txt"]; !ok || b.Hash == "" {
+		t.Errorf("b.txt entry = %+v", b)
+	}
+}
+
+func TestStartupRemovesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, uploadTmpPrefix+"123")
+	if err := os.WriteFile(stale, []byte("half-written"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewServer(ServerConfig{UploadDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file still present (err=%v)", err)
+	}
+}
+
+func TestUploadsUseConfiguredTempDir(t *testing.T) {
+	tmp := t.TempDir()
+	stale := filepath.Join(tmp, uploadTmpPrefix+"999")
+	if err := os.WriteFile(stale, []byte("half-written"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), TempDir: tmp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Startup cleanup sweeps the configured temp dir too.
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temp file in temp_dir still present (err=%v)", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "dir/test.txt", "routed through temp_dir")
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "dir", "test.txt"))
+	if err != nil || string(data) != "routed through temp_dir" {
+		t.Errorf("stored content = %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temp_dir not empty after upload: %v", entries)
+	}
+}
+
+func TestConcurrentTransferCap(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MaxConcurrentTransfers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Hold one upload open by feeding its body through a pipe.
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), pr)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	part, err := mw.CreateFormFile("file", "slow.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, "first half")
+
+	// Wait for the handler to claim the slot before probing.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		claimed := s.active[""] == 1
+		s.mu.Unlock()
+		if claimed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first upload never claimed its slot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The slot is taken; a second upload must be told to wait.
+	resp, err := http.Post(ts.URL+"/upload", "multipart/form-data; boundary=x", bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second upload status = %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 response missing Retry-After")
+	}
+
+	mw.Close()
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// With the slot free again an upload succeeds.
+	uploadFile(t, ts, "after.txt", "ok")
+}
+
+func TestTransferGateKeepsMetadataResponsive(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MaxActiveTransfers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	uploadFile(t, ts, "existing.txt", "here first")
+
+	// Hold the single transfer slot with an upload fed through a pipe.
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	first := make(chan error, 1)
+	go func() {
+		resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), pr)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		first <- err
+	}()
+	part, err := mw.CreateFormFile("file", "slow.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, "first half")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(s.transferGate) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("first upload never took the transfer slot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Metadata endpoints bypass the gate: they must answer while the
+	// transfer slot is occupied.
+	fast := http.Client{Timeout: 2 * time.Second}
+	for _, p := range []string{"/api/list", "/api/stat?path=existing.txt", "/health"} {
+		resp, err := fast.Get(ts.URL + p)
+		if err != nil {
+			t.Fatalf("GET %s while transfer in flight: %v", p, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d, want 200", p, resp.StatusCode)
+		}
+	}
+
+	// A second transfer queues rather than failing, and proceeds once
+	// the slot frees up.
+	var buf bytes.Buffer
+	mw2 := multipart.NewWriter(&buf)
+	part2, err := mw2.CreateFormFile("file", "queued.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part2, "waited")
+	mw2.Close()
+	second := make(chan int, 1)
+	go func() {
+		resp, err := http.Post(ts.URL+"/upload", mw2.FormDataContentType(), &buf)
+		if err != nil {
+			second <- 0
+			return
+		}
+		resp.Body.Close()
+		second <- resp.StatusCode
+	}()
+	select {
+	case <-second:
+		t.Fatal("second upload finished while the slot was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mw.Close()
+	pw.Close()
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if code := <-second; code != http.StatusOK {
+		t.Errorf("queued upload status = %d, want 200", code)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "queued.txt"))
+	if err != nil || string(data) != "waited" {
+		t.Errorf("queued upload content = %q, %v", data, err)
+	}
+}
+
+func TestStartReportsPortConflict(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Start()
+	var se *StartError
+	if !errors.As(err, &se) {
+		t.Fatalf("Start on busy port returned %v, want *StartError", err)
+	}
+	if !strings.Contains(se.Hint, "already in use") {
+		t.Errorf("hint = %q, want port-in-use guidance", se.Hint)
+	}
+	if se.Unwrap() == nil {
+		t.Error("StartError does not unwrap to the bind error")
+	}
+}
+
+func TestStatReturnsHashFromIndex(t *testing.T) {
+	_, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "st/one.txt", "stat me")
+
+	resp, err := http.Get(ts.URL + "/api/stat?path=st/one.txt&hashes=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var info api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Path != "st/one.txt" || info.Size != 7 || info.Hash != ur.Files[0].Hash {
+		t.Errorf("stat = %+v", info)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/stat?path=st/absent.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing path status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestListDirectory(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "docs/readme.md", "hi")
+
+	resp, err := http.Get(ts.URL + "/api/list?path=docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var files []api.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "readme.md" {
+		t.Errorf("listing = %+v", files)
+	}
+}
+
+func TestDeterministicIDsAndClock(t *testing.T) {
+	s, ts := newTestServer(t)
+	var n int
+	s.ids = func() string { n++; return fmt.Sprintf("id-%d", n) }
+	s.clock = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+
+	ur := uploadFile(t, ts, "test.txt", "test content")
+
+	resp, err := http.Get(ts.URL + "/api/status/" + ur.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`{"id":%q,"state":"completed","total_files":1,"completed_files":1,"total_bytes":12,"bytes":12,"started_at":"2024-05-06T07:08:09Z","finished_at":"2024-05-06T07:08:09Z"}`+"\n", ur.TransferID)
+	if string(body) != want {
+		t.Errorf("status body = %s, want %s", body, want)
+	}
+}
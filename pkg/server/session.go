@@ -0,0 +1,70 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// uploadSession groups every temporary artifact of one upload request —
+// multipart temp files, archive staging directories — under hidden
+// per-session directories, one per temp root the request touches.
+// Failure or cancel tears the whole session down with one RemoveAll per
+// directory, and the uploadTmpPrefix on the directory names keeps them
+// out of listings and lets startup cleanup collect them after a crash.
+type uploadSession struct {
+	s    *Server
+	id   string
+	dirs map[string]string
+}
+
+// newUploadSession starts a session for one upload request; the caller
+// defers cleanup.
+func (s *Server) newUploadSession() *uploadSession {
+	return &uploadSession{s: s, id: s.ids(), dirs: make(map[string]string)}
+}
+
+// dirIn returns the session's directory under root, creating it on
+// first use.
+func (ses *uploadSession) dirIn(root string) (string, error) {
+	if dir, ok := ses.dirs[root]; ok {
+		return dir, nil
+	}
+	dir := filepath.Join(root, uploadTmpPrefix+ses.id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ses.dirs[root] = dir
+	return dir, nil
+}
+
+// tempFile creates the temp file for a write destined for dst, inside
+// the session's directory on dst's temp root so the final rename stays
+// on one filesystem (renameOrCopy absorbs a cross-device temp_dir).
+func (ses *uploadSession) tempFile(dst string) (*os.File, error) {
+	dir, err := ses.dirIn(ses.s.tempDirFor(dst))
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, uploadTmpPrefix+"*")
+}
+
+// stageDir creates the staging directory for a replace upload of
+// destFull, kept next to the destination so swapping it into place is a
+// rename on one filesystem.
+func (ses *uploadSession) stageDir(destFull string) (string, error) {
+	dir, err := ses.dirIn(filepath.Dir(destFull))
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(dir, "stage-*")
+}
+
+// cleanup removes the session's directories and whatever half-written
+// data is still inside; completed files were already renamed out.
+func (ses *uploadSession) cleanup() {
+	for _, dir := range ses.dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			ses.s.logger.Errorf("clean upload session %s: %v", ses.id, err)
+		}
+	}
+}
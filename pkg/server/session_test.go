@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// tempArtifactsUnder returns every path carrying the upload temp prefix
+// left anywhere below root.
+func tempArtifactsUnder(t *testing.T, root string) []string {
+	t.Helper()
+	var left []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fi.Name() == dataDirName {
+			return filepath.SkipDir
+		}
+		if strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			left = append(left, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return left
+}
+
+func TestFailedUploadLeavesNoTempArtifacts(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="bad.txt"`)
+	h.Set(api.FileHashHeader, "0000000000000000000000000000000000000000000000000000000000000000")
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, "rejected content")
+	mw.Close()
+
+	resp, err := http.Post(ts.URL+"/upload", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", resp.StatusCode)
+	}
+	if left := tempArtifactsUnder(t, s.cfg.UploadDir); len(left) != 0 {
+		t.Errorf("failed upload left temp artifacts: %v", left)
+	}
+}
+
+func TestSuccessfulUploadCleansSessionDirectory(t *testing.T) {
+	s, ts := newTestServer(t)
+	uploadFile(t, ts, "nested/keep.txt", "stored")
+
+	if left := tempArtifactsUnder(t, s.cfg.UploadDir); len(left) != 0 {
+		t.Errorf("upload left temp artifacts: %v", left)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "nested", "keep.txt")); err != nil {
+		t.Errorf("stored file missing: %v", err)
+	}
+}
+
+func TestStartupRecoveryLogsSummary(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, dataDirName)
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A transfer still "running" and a leftover session directory, as a
+	// crash would leave them.
+	interrupted := `[{"id":"t1","state":"running","started_at":"2026-01-01T00:00:00Z"}]`
+	if err := os.WriteFile(filepath.Join(dataDir, "transfers.json"), []byte(interrupted), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, uploadTmpPrefix+"crashed")
+	if err := os.MkdirAll(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, uploadTmpPrefix+"frag"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	logFile := filepath.Join(t.TempDir(), "server.log")
+	if _, err := NewServer(ServerConfig{
+		UploadDir:     dir,
+		EnableLogging: true,
+		LogFile:       logFile,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	log, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "recovery: marked 1 interrupted transfers failed, removed 0 temp files and 1 staging directories (7 bytes)"
+	if !strings.Contains(string(log), want) {
+		t.Errorf("log missing recovery summary %q:\n%s", want, log)
+	}
+}
+
+func TestStartupRemovesStaleSessionDirectories(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, uploadTmpPrefix+"deadbeef")
+	if err := os.MkdirAll(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, uploadTmpPrefix+"frag"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewServer(ServerConfig{UploadDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale session directory survived startup (err=%v)", err)
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// transferStore keeps transfer statuses in memory and mirrors them to a
+// JSON file so status survives server restarts. Completed and failed
+// entries are pruned after the retention period.
+type transferStore struct {
+	path      string
+	retention time.Duration
+
+	// now anchors retention pruning; the server points it at its
+	// injectable clock.
+	now func() time.Time
+
+	// recovered counts the transfers found still running on load and
+	// marked failed, for the startup recovery summary.
+	recovered int
+
+	mu sync.Mutex
+	m  map[string]*api.TransferStatus
+}
+
+// newTransferStore loads (or creates) the store persisted at path.
+func newTransferStore(path string, retention time.Duration) (*transferStore, error) {
+	st := &transferStore{
+		path:      path,
+		retention: retention,
+		now:       time.Now,
+		m:         make(map[string]*api.TransferStatus),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return nil, err
+	}
+	var list []*api.TransferStatus
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, t := range list {
+		// Transfers that were running when the server stopped can never
+		// finish; mark them failed on recovery.
+		if t.State == api.TransferRunning {
+			t.State = api.TransferFailed
+			t.Error = "interrupted by server restart"
+			t.FinishedAt = time.Now().UTC()
+			st.recovered++
+		}
+		st.m[t.ID] = t
+	}
+	st.prune()
+	return st, st.flush()
+}
+
+// Put registers or updates a transfer and persists the store.
+func (st *transferStore) Put(t *api.TransferStatus) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.m[t.ID] = t
+	st.prune()
+	return st.flush()
+}
+
+// Get returns the transfer with the given ID.
+func (st *transferStore) Get(id string) (*api.TransferStatus, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	t, ok := st.m[id]
+	return t, ok
+}
+
+// List returns all transfers, newest first.
+func (st *transferStore) List() []*api.TransferStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prune()
+	list := make([]*api.TransferStatus, 0, len(st.m))
+	for _, t := range st.m {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	return list
+}
+
+// prune drops finished transfers older than the retention period. The
+// caller must hold st.mu.
+func (st *transferStore) prune() {
+	cutoff := st.now().Add(-st.retention)
+	for id, t := range st.m {
+		if t.State != api.TransferRunning && !t.FinishedAt.IsZero() && t.FinishedAt.Before(cutoff) {
+			delete(st.m, id)
+		}
+	}
+}
+
+// flush writes the store atomically. The caller must hold st.mu.
+func (st *transferStore) flush() error {
+	list := make([]*api.TransferStatus, 0, len(st.m))
+	for _, t := range st.m {
+		list = append(list, t)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestTransferStoreSurvivesReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	st, err := newTransferStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := &api.TransferStatus{
+		ID: "t1", State: api.TransferCompleted,
+		StartedAt: time.Now(), FinishedAt: time.Now(),
+	}
+	running := &api.TransferStatus{ID: "t2", State: api.TransferRunning, StartedAt: time.Now()}
+	st.Put(done)
+	st.Put(running)
+
+	st2, err := newTransferStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st2.Get("t1"); !ok {
+		t.Error("completed transfer lost on reload")
+	}
+	got, ok := st2.Get("t2")
+	if !ok {
+		t.Fatal("running transfer lost on reload")
+	}
+	if got.State != api.TransferFailed {
+		t.Errorf("interrupted transfer state = %s, want failed", got.State)
+	}
+}
+
+func TestTransferStoreExpiresFinishedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.json")
+	st, err := newTransferStore(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	st.Put(&api.TransferStatus{
+		ID: "old", State: api.TransferCompleted,
+		StartedAt: old, FinishedAt: old,
+	})
+	if list := st.List(); len(list) != 0 {
+		t.Errorf("expired entry still listed: %+v", list)
+	}
+}
+
+func TestStatusListEndpoint(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "x.txt", "x")
+
+	resp, err := http.Get(ts.URL + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []api.TransferStatus
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].State != api.TransferCompleted {
+		t.Errorf("status list = %+v", list)
+	}
+}
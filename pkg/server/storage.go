@@ -0,0 +1,193 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// errInvalidPath is returned when a client-supplied path would escape the
+// storage directory.
+var errInvalidPath = errors.New("invalid path")
+
+// uploadTmpPrefix marks in-progress upload files and the per-session
+// directories holding them, kept on the destination's filesystem so the
+// final rename stays on one filesystem.
+const uploadTmpPrefix = ".uptmp-"
+
+// tempStats counts what a temp cleanup removed, for the startup
+// recovery summary.
+type tempStats struct {
+	files int
+	dirs  int
+	bytes int64
+}
+
+// cleanTempFiles removes upload temp files left behind by a crashed or
+// killed server so half-written data never masquerades as a stored file.
+func (s *Server) cleanTempFiles() error {
+	_, err := s.removeTempArtifacts()
+	return err
+}
+
+// removeTempArtifacts runs the temp cleanup over every storage and temp
+// root and reports what it removed.
+func (s *Server) removeTempArtifacts() (tempStats, error) {
+	roots := []string{s.cfg.UploadDir}
+	if s.cfg.TempDir != "" {
+		roots = append(roots, s.cfg.TempDir)
+	}
+	for _, e := range s.cfg.Exports {
+		roots = append(roots, e.Dir)
+		if e.TempDir != "" {
+			roots = append(roots, e.TempDir)
+		}
+	}
+	var stats tempStats
+	for _, root := range roots {
+		if err := s.cleanTempFilesIn(root, &stats); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func (s *Server) cleanTempFilesIn(root string, stats *tempStats) error {
+	return filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == dataDirName {
+				return filepath.SkipDir
+			}
+			// Session and staging directories from interrupted uploads
+			// carry the temp prefix too.
+			if strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+				s.logger.Infof("removing stale upload staging directory %s", p)
+				// usageUnder skips temp-prefixed names, so sum the
+				// directory's contents directly.
+				filepath.Walk(p, func(_ string, fi os.FileInfo, err error) error {
+					if err == nil && fi.Mode().IsRegular() {
+						stats.bytes += fi.Size()
+					}
+					return nil
+				})
+				if err := os.RemoveAll(p); err != nil {
+					return err
+				}
+				stats.dirs++
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(fi.Name(), uploadTmpPrefix) {
+			s.logger.Infof("removing stale upload temp file %s", p)
+			stats.files++
+			stats.bytes += fi.Size()
+			return os.Remove(p)
+		}
+		return nil
+	})
+}
+
+// startupRecovery cleans up what a crash or kill left behind — orphaned
+// temp data on disk plus transfers the status store found still running
+// and marked failed — and logs one summary line so operators can see at
+// a glance what the restart recovered.
+func (s *Server) startupRecovery(interrupted int) error {
+	stats, err := s.removeTempArtifacts()
+	if err != nil {
+		return err
+	}
+	if interrupted == 0 && stats.files == 0 && stats.dirs == 0 {
+		return nil
+	}
+	s.logger.Infof("recovery: marked %d interrupted transfers failed, removed %d temp files and %d staging directories (%d bytes)",
+		interrupted, stats.files, stats.dirs, stats.bytes)
+	return nil
+}
+
+// resolvePath maps a client-supplied relative path onto the storage
+// directory, rejecting absolute paths and directory traversal.
+func (s *Server) resolvePath(rel string) (string, error) {
+	rel = strings.TrimPrefix(rel, "/")
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || filepath.IsAbs(clean) {
+		return "", errInvalidPath
+	}
+	// The server state directory lives inside the storage root but is
+	// never addressable by clients.
+	if clean == dataDirName || strings.HasPrefix(clean, dataDirName+string(filepath.Separator)) {
+		return "", errInvalidPath
+	}
+	if e, rest, ok := s.exportFor(clean); ok {
+		return filepath.Join(e.Dir, rest), nil
+	}
+	return filepath.Join(s.cfg.UploadDir, clean), nil
+}
+
+// tempDirFor picks the directory for the temp file of a write destined
+// for dst: the configured temp_dir of the storage root holding dst when
+// one is set, otherwise the destination's own directory. Per-root
+// configuration keeps the temp file on the destination filesystem so
+// the final rename is atomic; if it is not, renameOrCopy absorbs the
+// EXDEV.
+func (s *Server) tempDirFor(dst string) string {
+	for _, e := range s.cfg.Exports {
+		if strings.HasPrefix(dst, filepath.Clean(e.Dir)+string(filepath.Separator)) {
+			if e.TempDir != "" {
+				return e.TempDir
+			}
+			return filepath.Dir(dst)
+		}
+	}
+	if s.cfg.TempDir != "" {
+		return s.cfg.TempDir
+	}
+	return filepath.Dir(dst)
+}
+
+// renameOrCopy renames oldpath to newpath, falling back to copy and
+// remove when the two sit on different filesystems (EXDEV), as with a
+// cross-device temp_dir. The copy goes through a temp file next to
+// newpath, so the rename that publishes it is atomic either way.
+func (s *Server) renameOrCopy(oldpath, newpath string) error {
+	err := os.Rename(oldpath, newpath)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := s.copyFile(oldpath, newpath); err != nil {
+		return err
+	}
+	return os.Remove(oldpath)
+}
+
+// exportFor matches the first segment of the cleaned path against the
+// configured export names and returns the export plus the remainder of
+// the path inside it.
+func (s *Server) exportFor(clean string) (ExportConfig, string, bool) {
+	first, rest, _ := strings.Cut(clean, string(filepath.Separator))
+	for _, e := range s.cfg.Exports {
+		if e.Name == first {
+			if rest == "" {
+				rest = "."
+			}
+			return e, rest, true
+		}
+	}
+	return ExportConfig{}, "", false
+}
+
+// maxSizeFor returns the file size limit that applies to the
+// user-visible path rel: the export's own limit when set, otherwise the
+// server-wide one.
+func (s *Server) maxSizeFor(rel string) int64 {
+	clean := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(rel, "/")))
+	if e, _, ok := s.exportFor(clean); ok && e.MaxFileSize > 0 {
+		return int64(e.MaxFileSize)
+	}
+	return int64(s.cfg.MaxFileSize)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The storage probe writes and removes a marker file under the data
+// directory on a short interval. When it fails — disk pulled, mount
+// gone, permissions lost — the server flips to degraded mode: writes
+// get 503, reads are served best-effort and /readyz reports not ready.
+// The next passing probe clears the mode again, so a transient outage
+// heals without a restart.
+
+// probeStorage verifies the storage backend still accepts writes.
+func (s *Server) probeStorage() error {
+	path := filepath.Join(s.cfg.DataDir, "probe")
+	if err := os.WriteFile(path, []byte("ok"), 0o644); err != nil {
+		return fmt.Errorf("storage probe write: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("storage probe remove: %w", err)
+	}
+	return nil
+}
+
+// checkStorageHealth runs the probe and flips degraded mode to match
+// the result, logging the transitions.
+func (s *Server) checkStorageHealth() error {
+	err := s.probeStorage()
+	s.mu.Lock()
+	was := s.degraded
+	s.degraded = err != nil
+	s.degradedErr = ""
+	if err != nil {
+		s.degradedErr = err.Error()
+	}
+	s.mu.Unlock()
+	switch {
+	case err != nil && !was:
+		s.logger.Errorf("storage unhealthy, entering degraded mode: %v", err)
+	case err == nil && was:
+		s.logger.Infof("storage healthy again, leaving degraded mode")
+	}
+	return err
+}
+
+// degradedGuard rejects mutating requests while the storage backend is
+// unhealthy. Reads stay best-effort, and transfer control and the admin
+// surface stay reachable, mirroring readOnlyGuard.
+func (s *Server) degradedGuard(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	bad := s.degraded
+	s.mu.Unlock()
+	if !bad || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	if strings.HasPrefix(r.URL.Path, "/admin/") || strings.HasPrefix(r.URL.Path, "/api/status/") {
+		return true
+	}
+	writeError(w, http.StatusServiceUnavailable, "storage backend is unhealthy")
+	return false
+}
+
+// handleReady serves GET /readyz for orchestrator readiness checks: 200
+// while the storage backend is healthy, 503 with the probe error while
+// degraded. /health keeps answering either way, since the process is
+// alive and still serving reads.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	bad, why := s.degraded, s.degradedErr
+	s.mu.Unlock()
+	if bad {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "degraded", "error": why})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDegradedModeBlocksWritesAndRecovers(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.mu.Lock()
+	s.degraded, s.degradedErr = true, "probe failed"
+	s.mu.Unlock()
+
+	up := uploadRaw(t, ts, "f.txt", "blocked")
+	defer up.Body.Close()
+	if up.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("upload status = %d, want 503", up.StatusCode)
+	}
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readyz status = %d, want 503", resp.StatusCode)
+	}
+	// Reads stay best-effort while degraded.
+	resp, err = http.Get(ts.URL + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("read status = %d, want 200", resp.StatusCode)
+	}
+
+	// A passing probe clears the mode without a restart.
+	if err := s.checkStorageHealth(); err != nil {
+		t.Fatalf("probe on healthy storage: %v", err)
+	}
+	resp, err = http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("readyz after recovery = %d, want 200", resp.StatusCode)
+	}
+	uploadFile(t, ts, "f.txt", "allowed")
+}
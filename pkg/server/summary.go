@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// summaryStore keeps the immutable end-of-transfer summaries, mirrored
+// to a JSON file the way the live status store is. Each entry is
+// written exactly once, when its transfer finishes, and pruned on the
+// same retention as statuses.
+type summaryStore struct {
+	path      string
+	retention time.Duration
+
+	// now anchors retention pruning; the server points it at its
+	// injectable clock.
+	now func() time.Time
+
+	mu sync.Mutex
+	m  map[string]*api.TransferSummary
+}
+
+// newSummaryStore loads (or creates) the store persisted at path.
+func newSummaryStore(path string, retention time.Duration) (*summaryStore, error) {
+	st := &summaryStore{
+		path:      path,
+		retention: retention,
+		now:       time.Now,
+		m:         make(map[string]*api.TransferSummary),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return nil, err
+	}
+	var list []*api.TransferSummary
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, sum := range list {
+		st.m[sum.ID] = sum
+	}
+	st.prune()
+	return st, st.flush()
+}
+
+// Put records one finished transfer and persists the store.
+func (st *summaryStore) Put(sum *api.TransferSummary) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.m[sum.ID] = sum
+	st.prune()
+	return st.flush()
+}
+
+// Get returns the summary for the given transfer ID.
+func (st *summaryStore) Get(id string) (*api.TransferSummary, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sum, ok := st.m[id]
+	return sum, ok
+}
+
+// prune drops summaries older than the retention period. The caller
+// must hold st.mu.
+func (st *summaryStore) prune() {
+	cutoff := st.now().Add(-st.retention)
+	for id, sum := range st.m {
+		if sum.FinishedAt.Before(cutoff) {
+			delete(st.m, id)
+		}
+	}
+}
+
+// flush writes the store atomically. The caller must hold st.mu.
+func (st *summaryStore) flush() error {
+	list := make([]*api.TransferSummary, 0, len(st.m))
+	for _, sum := range st.m {
+		list = append(list, sum)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path)
+}
+
+// finalizeSummary freezes the just-finished transfer t into its
+// summary record, attaching the per-file results when the transfer
+// produced any. finishTransfer must already have run, so the state,
+// error and finish time are settled.
+func (s *Server) finalizeSummary(t *api.TransferStatus, files []api.UploadResult) {
+	s.mu.Lock()
+	sum := &api.TransferSummary{
+		ID:          t.ID,
+		State:       t.State,
+		Files:       t.CompletedFiles,
+		Bytes:       t.Bytes,
+		WireBytes:   t.WireBytes,
+		StartedAt:   t.StartedAt,
+		FinishedAt:  t.FinishedAt,
+		DurationMS:  t.FinishedAt.Sub(t.StartedAt).Milliseconds(),
+		FileResults: files,
+		Error:       t.Error,
+	}
+	s.mu.Unlock()
+	if err := s.summaries.Put(sum); err != nil {
+		s.logger.Errorf("persist summary %s: %v", t.ID, err)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func TestTransferSummaryAfterUpload(t *testing.T) {
+	_, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "sdir/a.txt", "summary me")
+
+	resp, err := http.Get(ts.URL + "/api/status/" + ur.TransferID + "/summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("summary status = %d", resp.StatusCode)
+	}
+	var sum api.TransferSummary
+	if err := json.NewDecoder(resp.Body).Decode(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.State != api.TransferCompleted {
+		t.Errorf("state = %q", sum.State)
+	}
+	if sum.Files != 1 || sum.Bytes != int64(len("summary me")) {
+		t.Errorf("files = %d bytes = %d", sum.Files, sum.Bytes)
+	}
+	if len(sum.FileResults) != 1 || sum.FileResults[0].Hash == "" {
+		t.Errorf("file results = %+v, want one entry with a hash", sum.FileResults)
+	}
+	if sum.FinishedAt.Before(sum.StartedAt) || sum.DurationMS < 0 {
+		t.Errorf("timing: started %v finished %v duration %dms",
+			sum.StartedAt, sum.FinishedAt, sum.DurationMS)
+	}
+
+	// An unknown transfer is 404; the summary survives a store reload
+	// because it is persisted like the status itself.
+	resp, err = http.Get(ts.URL + "/api/status/nope/summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown transfer summary status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestSummaryStoreSurvivesReload(t *testing.T) {
+	s, ts := newTestServer(t)
+	ur := uploadFile(t, ts, "reload.txt", "x")
+
+	st, err := newSummaryStore(s.summaries.path, s.summaries.retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, ok := st.Get(ur.TransferID)
+	if !ok {
+		t.Fatal("summary missing after reload")
+	}
+	if sum.State != api.TransferCompleted || sum.Files != 1 {
+		t.Errorf("reloaded summary = %+v", sum)
+	}
+}
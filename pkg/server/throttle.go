@@ -0,0 +1,100 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// requestLimiter enforces a per-client requests-per-second cap with a
+// token bucket per client key, allowing a burst of one second's worth.
+// Buckets are created on first sight and pruned once idle long enough
+// to be full again, so the map stays bounded by active clients.
+type requestLimiter struct {
+	mu    sync.Mutex
+	rate  float64
+	seen  map[string]*reqBucket
+	sweep time.Time
+}
+
+type reqBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRequestLimiter returns a limiter at perSec requests per second, or
+// nil when perSec is not positive (unlimited).
+func newRequestLimiter(perSec int) *requestLimiter {
+	if perSec <= 0 {
+		return nil
+	}
+	return &requestLimiter{rate: float64(perSec), seen: make(map[string]*reqBucket), sweep: time.Now()}
+}
+
+// allow reports whether one more request from key may proceed, and if
+// not, how long the client should wait before retrying.
+func (l *requestLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b, ok := l.seen[key]
+	if !ok {
+		b = &reqBucket{tokens: l.rate}
+		l.seen[key] = b
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.rate {
+			b.tokens = l.rate
+		}
+	}
+	b.last = now
+	if now.Sub(l.sweep) > time.Minute {
+		for k, old := range l.seen {
+			if k != key && now.Sub(old.last) > time.Minute {
+				delete(l.seen, k)
+			}
+		}
+		l.sweep = now
+	}
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// clientKey identifies the caller for rate limiting: the bearer token
+// when one is presented, the remote IP otherwise, so clients behind a
+// shared NAT with their own tokens are limited individually.
+func clientKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return "token:" + strings.TrimPrefix(auth, "Bearer ")
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimit rejects the request with 429 and a Retry-After hint when
+// the caller is over its request budget. The health endpoint stays
+// exempt so probes keep working while a client is throttled.
+func (s *Server) rateLimit(w http.ResponseWriter, r *http.Request) bool {
+	if s.reqs == nil || r.URL.Path == "/health" {
+		return true
+	}
+	ok, wait := s.reqs.allow(clientKey(r))
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+		writeLimitError(w, http.StatusTooManyRequests, api.CodeBusy, "request rate limit exceeded", "max_requests_per_second", int64(s.cfg.MaxRequestsPerSecond))
+		return false
+	}
+	return true
+}
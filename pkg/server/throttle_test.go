@@ -0,0 +1,129 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestRateLimitReturns429(t *testing.T) {
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), MaxRequestsPerSecond: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	first, err := http.Get(ts.URL + "/api/list?path=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Body.Close()
+	if first.StatusCode != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", first.StatusCode)
+	}
+
+	second, err := http.Get(ts.URL + "/api/list?path=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Body.Close()
+	if second.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second request status = %d, want 429", second.StatusCode)
+	}
+	if second.Header.Get("Retry-After") == "" {
+		t.Error("429 response missing Retry-After")
+	}
+
+	// Health probes are exempt so monitoring keeps working while a
+	// client is throttled.
+	health, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	health.Body.Close()
+	if health.StatusCode != http.StatusOK {
+		t.Errorf("health status = %d, want 200", health.StatusCode)
+	}
+}
+
+func TestBandwidthRulesSelectPerClientCap(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir:           t.TempDir(),
+		MaxBandwidthPerConn: 5 << 20,
+		BandwidthRules: []BandwidthRule{
+			{CIDR: "10.0.0.0/8"}, // zero cap: LAN clients run unthrottled
+			{CIDR: "192.168.1.0/24", MaxBandwidthPerConn: 1 << 20},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func(addr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/download/x", nil)
+		r.RemoteAddr = addr
+		return r
+	}
+
+	cases := []struct {
+		addr   string
+		limit  int64
+		exempt bool
+	}{
+		{"10.1.2.3:4444", 0, true},
+		{"192.168.1.9:4444", 1 << 20, false},
+		{"203.0.113.7:4444", 5 << 20, false},
+	}
+	for _, c := range cases {
+		limit, exempt := s.connCap(req(c.addr))
+		if limit != c.limit || exempt != c.exempt {
+			t.Errorf("connCap(%s) = %d, %v; want %d, %v", c.addr, limit, exempt, c.limit, c.exempt)
+		}
+	}
+
+	// An exempt client's body passes through with no limiter at all.
+	rc := io.NopCloser(strings.NewReader("x"))
+	if got := s.throttleBody(req("10.1.2.3:4444"), rc); got != rc {
+		t.Error("exempt client's body was wrapped in a limiter")
+	}
+}
+
+func TestRequestRateLimitKeyedByToken(t *testing.T) {
+	s, err := NewServer(ServerConfig{
+		UploadDir:            t.TempDir(),
+		MaxRequestsPerSecond: 1,
+		Users: []UserConfig{
+			{Name: "alice", Token: "alice-token"},
+			{Name: "bob", Token: "bob-token"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/list?path=", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get("alice-token"); code != http.StatusOK {
+		t.Fatalf("alice first request = %d, want 200", code)
+	}
+	if code := get("alice-token"); code != http.StatusTooManyRequests {
+		t.Errorf("alice second request = %d, want 429", code)
+	}
+	// A throttled client does not consume bob's budget.
+	if code := get("bob-token"); code != http.StatusOK {
+		t.Errorf("bob request = %d, want 200", code)
+	}
+}
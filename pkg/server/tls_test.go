@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI is a throwaway CA with a server and a client certificate
+// signed by it, written to PEM files so the tls_* config fields can
+// point at them.
+type testPKI struct {
+	caFile                string
+	serverCert, serverKey string
+	clientCert, clientKey string
+	pool                  *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, caTmpl, caDER := testCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+	serverKey, _, serverDER := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caTmpl, caKey)
+	clientKey, _, clientDER := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "test client"},
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caTmpl, caKey)
+
+	pool := x509.NewCertPool()
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.AddCert(caCert)
+	p := &testPKI{
+		caFile:     filepath.Join(dir, "ca.pem"),
+		serverCert: filepath.Join(dir, "server.pem"),
+		serverKey:  filepath.Join(dir, "server.key"),
+		clientCert: filepath.Join(dir, "client.pem"),
+		clientKey:  filepath.Join(dir, "client.key"),
+		pool:       pool,
+	}
+	writePEM(t, p.caFile, "CERTIFICATE", caDER)
+	writePEM(t, p.serverCert, "CERTIFICATE", serverDER)
+	writeKey(t, p.serverKey, serverKey)
+	writePEM(t, p.clientCert, "CERTIFICATE", clientDER)
+	writeKey(t, p.clientKey, clientKey)
+	return p
+}
+
+// testCert makes a key pair and a certificate for tmpl signed by the
+// parent, or self-signed when parent is nil.
+func testCert(t *testing.T, tmpl, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, tmpl, der
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeKey(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, path, "EC PRIVATE KEY", der)
+}
+
+func TestMutualTLSRequiresClientCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	s, err := NewServer(ServerConfig{
+		UploadDir:   t.TempDir(),
+		TLSCert:     pki.serverCert,
+		TLSKey:      pki.serverKey,
+		TLSClientCA: pki.caFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := s.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("ClientAuth = %v, want RequireAndVerifyClientCert", tc.ClientAuth)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go http.Serve(tls.NewListener(ln, tc), s)
+	url := "https://" + ln.Addr().String() + "/health"
+
+	anon := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pki.pool}}}
+	if resp, err := anon.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("request without a client certificate succeeded")
+	}
+
+	cert, err := tls.LoadX509KeyPair(pki.clientCert, pki.clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authed := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      pki.pool,
+		Certificates: []tls.Certificate{cert},
+	}}}
+	resp, err := authed.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("health over mTLS = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestTLSClientAuthVerifyAdmitsBareTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	s, err := NewServer(ServerConfig{
+		UploadDir:     t.TempDir(),
+		TLSCert:       pki.serverCert,
+		TLSKey:        pki.serverKey,
+		TLSClientCA:   pki.caFile,
+		TLSClientAuth: "verify",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := s.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Errorf("ClientAuth = %v, want VerifyClientCertIfGiven", tc.ClientAuth)
+	}
+}
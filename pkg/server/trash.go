@@ -0,0 +1,265 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// trashDirName is the area inside DataDir holding trashed content, one
+// entry per opaque id. The metadata — original path, size, deletion
+// time — lives in the trash index next to it, so a restore knows where
+// everything goes back.
+const trashDirName = "trash"
+
+// trashIndex persists what the trash holds, keyed by entry id.
+type trashIndex struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]trashEntry
+}
+
+type trashEntry struct {
+	Path      string    `json:"path"` // stored (namespaced) path at deletion
+	Size      int64     `json:"size"`
+	IsDir     bool      `json:"is_dir"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// newTrashIndex loads the index persisted at path, starting empty if
+// the file does not exist yet.
+func newTrashIndex(path string) (*trashIndex, error) {
+	ix := &trashIndex{path: path, entries: make(map[string]trashEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &ix.entries); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Put records one trashed entry under id.
+func (ix *trashIndex) Put(id string, e trashEntry) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[id] = e
+	return ix.flush()
+}
+
+// Get returns the entry stored under id.
+func (ix *trashIndex) Get(id string) (trashEntry, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e, ok := ix.entries[id]
+	return e, ok
+}
+
+// Remove drops the entry stored under id.
+func (ix *trashIndex) Remove(id string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.entries, id)
+	return ix.flush()
+}
+
+// List returns a copy of all entries.
+func (ix *trashIndex) List() map[string]trashEntry {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	out := make(map[string]trashEntry, len(ix.entries))
+	for id, e := range ix.entries {
+		out[id] = e
+	}
+	return out
+}
+
+// flush persists the index atomically; callers hold ix.mu.
+func (ix *trashIndex) flush() error {
+	data, err := json.MarshalIndent(ix.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// trashPath returns where the content of trash entry id lives.
+func (s *Server) trashPath(id string) string {
+	return filepath.Join(s.cfg.DataDir, trashDirName, id)
+}
+
+// moveToTrash diverts the file or directory at full (the caller's path
+// rel) into the trash instead of deleting it, recording the original
+// path so a restore can put it back.
+func (s *Server) moveToTrash(u *UserConfig, rel, full string, fi os.FileInfo) error {
+	size := fi.Size()
+	if fi.IsDir() {
+		if b, _, err := s.usageUnder(full); err == nil {
+			size = b
+		}
+	}
+	id := s.ids()
+	dst := s.trashPath(id)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		// Directories move in one rename; DataDir defaults to inside
+		// the storage root, so this stays on one filesystem.
+		if err := os.Rename(full, dst); err != nil {
+			return err
+		}
+	} else if err := s.renameOrCopy(full, dst); err != nil {
+		return err
+	}
+	return s.trash.Put(id, trashEntry{
+		Path:      path.Clean(namespacePath(u, rel)),
+		Size:      size,
+		IsDir:     fi.IsDir(),
+		DeletedAt: time.Now().UTC(),
+	})
+}
+
+// userView maps a stored (namespaced) path back to the caller's view:
+// admins and anonymous callers see it as-is, other users only see their
+// own namespace with the home prefix stripped.
+func userView(u *UserConfig, nsRel string) (string, bool) {
+	if u == nil || u.Admin {
+		return nsRel, true
+	}
+	if nsRel == u.Name {
+		return ".", true
+	}
+	if strings.HasPrefix(nsRel, u.Name+"/") {
+		return strings.TrimPrefix(nsRel, u.Name+"/"), true
+	}
+	return "", false
+}
+
+// handleTrash serves GET /api/trash: the entries the caller may see,
+// oldest deletion first.
+func (s *Server) handleTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	entries := []api.TrashEntry{}
+	for id, e := range s.trash.List() {
+		rel, visible := userView(u, e.Path)
+		if !visible {
+			continue
+		}
+		entries = append(entries, api.TrashEntry{
+			ID:        id,
+			Path:      rel,
+			Size:      e.Size,
+			IsDir:     e.IsDir,
+			DeletedAt: e.DeletedAt,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].DeletedAt.Before(entries[j].DeletedAt)
+	})
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleTrashRestore serves POST /api/trash/restore?id=, moving the
+// entry's content back to its original path. An existing file there is
+// never overwritten; the caller has to move it out of the way first.
+func (s *Server) handleTrashRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	id := r.URL.Query().Get("id")
+	e, found := s.trash.Get(id)
+	rel, visible := "", false
+	if found {
+		rel, visible = userView(u, e.Path)
+	}
+	if !found || !visible {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	dst, err := s.resolvePath(e.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := os.Stat(dst); err == nil {
+		writeErrorCode(w, http.StatusConflict, api.CodeConflict, "destination exists")
+		return
+	}
+	if err := s.mkdirAll(filepath.Dir(dst)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := os.Rename(s.trashPath(id), dst); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.trash.Remove(id); err != nil {
+		s.logger.Errorf("update trash index: %v", err)
+	}
+	if e.IsDir {
+		if rels, sizes, err := s.filesUnder(dst, rel); err == nil {
+			for i, fileRel := range rels {
+				s.noteChange(u, fileRel, api.ChangeCreate, sizes[i])
+			}
+		}
+	} else {
+		s.noteChange(u, rel, api.ChangeCreate, e.Size)
+	}
+	s.logger.Infof("restored %s from trash", rel)
+	writeJSON(w, http.StatusOK, map[string]string{"restored": rel})
+}
+
+// purgeTrash permanently removes entries deleted before cutoff and
+// returns how many went.
+func (s *Server) purgeTrash(cutoff time.Time) int {
+	n := 0
+	for id, e := range s.trash.List() {
+		if !e.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(s.trashPath(id)); err != nil {
+			s.logger.Errorf("purge trash entry %s: %v", id, err)
+			continue
+		}
+		if err := s.trash.Remove(id); err != nil {
+			s.logger.Errorf("update trash index: %v", err)
+		}
+		n++
+	}
+	if n > 0 {
+		s.logger.Infof("purged %d expired trash entries", n)
+	}
+	return n
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func newTrashServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Trash: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func listTrash(t *testing.T, ts *httptest.Server) []api.TrashEntry {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/trash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var entries []api.TrashEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func deletePath(t *testing.T, ts *httptest.Server, query string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/api/files?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete %s status = %d, want 200", query, resp.StatusCode)
+	}
+}
+
+func TestDeleteMovesToTrashAndRestores(t *testing.T) {
+	s, ts := newTrashServer(t)
+	uploadFile(t, ts, "docs/keep.txt", "precious")
+	deletePath(t, ts, "path=docs/keep.txt")
+
+	if _, err := os.Stat(filepath.Join(s.cfg.UploadDir, "docs", "keep.txt")); !os.IsNotExist(err) {
+		t.Error("deleted file still at its original path")
+	}
+	entries := listTrash(t, ts)
+	if len(entries) != 1 || entries[0].Path != "docs/keep.txt" || entries[0].Size != 8 {
+		t.Fatalf("trash = %+v, want one 8-byte entry for docs/keep.txt", entries)
+	}
+
+	resp, err := http.Post(ts.URL+"/api/trash/restore?id="+entries[0].ID, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("restore status = %d, want 200", resp.StatusCode)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "docs", "keep.txt"))
+	if err != nil || string(data) != "precious" {
+		t.Errorf("restored content = %q, %v", data, err)
+	}
+	if entries := listTrash(t, ts); len(entries) != 0 {
+		t.Errorf("trash after restore = %+v, want empty", entries)
+	}
+}
+
+func TestTrashRestoreRefusesToOverwrite(t *testing.T) {
+	_, ts := newTrashServer(t)
+	uploadFile(t, ts, "f.txt", "old")
+	deletePath(t, ts, "path=f.txt")
+	uploadFile(t, ts, "f.txt", "new")
+
+	entries := listTrash(t, ts)
+	if len(entries) != 1 {
+		t.Fatalf("trash = %+v", entries)
+	}
+	resp, err := http.Post(ts.URL+"/api/trash/restore?id="+entries[0].ID, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("restore over existing file status = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestTrashHoldsDirectoriesWholesale(t *testing.T) {
+	s, ts := newTrashServer(t)
+	uploadFile(t, ts, "proj/a.txt", "aa")
+	uploadFile(t, ts, "proj/sub/b.txt", "bbb")
+	deletePath(t, ts, "path=proj&recursive=true")
+
+	entries := listTrash(t, ts)
+	if len(entries) != 1 || !entries[0].IsDir || entries[0].Size != 5 {
+		t.Fatalf("trash = %+v, want one 5-byte directory entry", entries)
+	}
+	resp, err := http.Post(ts.URL+"/api/trash/restore?id="+entries[0].ID, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "proj", "sub", "b.txt"))
+	if err != nil || string(data) != "bbb" {
+		t.Errorf("restored tree content = %q, %v", data, err)
+	}
+}
+
+func TestTrashPurgeRemovesExpiredEntries(t *testing.T) {
+	s, ts := newTrashServer(t)
+	uploadFile(t, ts, "f.txt", "doomed")
+	deletePath(t, ts, "path=f.txt")
+
+	// A cutoff in the future expires everything in the trash.
+	if n := s.purgeTrash(time.Now().Add(time.Hour)); n != 1 {
+		t.Errorf("purged %d entries, want 1", n)
+	}
+	if entries := listTrash(t, ts); len(entries) != 0 {
+		t.Errorf("trash after purge = %+v, want empty", entries)
+	}
+	dir, err := os.ReadDir(filepath.Join(s.cfg.DataDir, trashDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dir) != 0 {
+		t.Errorf("trash content dir not empty: %v", dir)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// versionsDirName is the area inside DataDir holding overwritten file
+// versions. It mirrors the storage tree with each version stored as
+// name@v<N>, so versions never show up in listings or downloads of the
+// tree itself.
+const versionsDirName = "versions"
+
+// versionDir returns the directory holding the archived versions of the
+// stored (namespaced) path nsRel.
+func (s *Server) versionDir(nsRel string) string {
+	return filepath.Join(s.cfg.DataDir, versionsDirName, filepath.FromSlash(path.Dir(nsRel)))
+}
+
+// versionFile returns the archive location for version v of nsRel.
+func (s *Server) versionFile(nsRel string, v int) string {
+	return filepath.Join(s.versionDir(nsRel), path.Base(nsRel)+"@v"+strconv.Itoa(v))
+}
+
+// versionsOf lists the archived versions of nsRel, oldest first. A file
+// that was never overwritten has none.
+func (s *Server) versionsOf(nsRel string) ([]api.VersionInfo, error) {
+	entries, err := os.ReadDir(s.versionDir(nsRel))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prefix := path.Base(nsRel) + "@v"
+	var versions []api.VersionInfo
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(e.Name(), prefix))
+		if err != nil || n < 1 {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		versions = append(versions, api.VersionInfo{Version: n, Size: fi.Size(), ModTime: fi.ModTime().UTC()})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// keepVersion archives the current content of dst, the resolved
+// location of nsRel, as its next numbered version just before an
+// overwrite replaces it. With MaxVersions set the oldest versions are
+// pruned to stay under the cap.
+func (s *Server) keepVersion(nsRel, dst string) error {
+	versions, err := s.versionsOf(nsRel)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+	if err := os.MkdirAll(s.versionDir(nsRel), 0o755); err != nil {
+		return err
+	}
+	target := s.versionFile(nsRel, next)
+	// DataDir may sit on a different filesystem; renameOrCopy handles
+	// the cross-device case.
+	if err := s.renameOrCopy(dst, target); err != nil {
+		return err
+	}
+	if max := s.cfg.MaxVersions; max > 0 && len(versions)+1 > max {
+		for _, v := range versions[:len(versions)+1-max] {
+			os.Remove(s.versionFile(nsRel, v.Version))
+		}
+	}
+	return nil
+}
+
+// handleVersions serves GET /api/versions?path=. Without version= it
+// lists the archived versions of the file; with version=N it streams
+// that version's content.
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	if rel == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	nsRel := path.Clean(namespacePath(u, rel))
+	if _, err := s.resolvePath(nsRel); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid version")
+			return
+		}
+		f, err := os.Open(s.versionFile(nsRel, n))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "version not found")
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := sendMember(w, path.Base(rel), fi.Size(), f); err != nil {
+			s.logger.Errorf("send version %d of %s: %v", n, rel, err)
+		}
+		return
+	}
+	versions, err := s.versionsOf(nsRel)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if versions == nil {
+		versions = []api.VersionInfo{}
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// handleRestore serves POST /api/versions/restore?path=&version=N,
+// putting that archived version back as the file's current content. The
+// content being replaced is archived first, so a restore can itself be
+// undone and never destroys data.
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	u, ok := s.userFor(w, r)
+	if !ok {
+		return
+	}
+	rel := r.URL.Query().Get("path")
+	n, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if rel == "" || err != nil || n < 1 {
+		writeError(w, http.StatusBadRequest, "path and version are required")
+		return
+	}
+	nsRel := path.Clean(namespacePath(u, rel))
+	dst, err := s.resolvePath(nsRel)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	src := s.versionFile(nsRel, n)
+	if _, err := os.Stat(src); err != nil {
+		writeError(w, http.StatusNotFound, "version not found")
+		return
+	}
+	op := api.ChangeCreate
+	if _, err := os.Stat(dst); err == nil {
+		op = api.ChangeModify
+		if err := s.keepVersion(nsRel, dst); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := s.mkdirAll(filepath.Dir(dst)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.copyFile(src, dst); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.hashes.Invalidate(dst)
+	var size int64
+	if fi, err := os.Stat(dst); err == nil {
+		size = fi.Size()
+	}
+	s.noteChange(u, rel, op, size)
+	s.logger.Infof("restored %s to version %d", rel, n)
+	writeJSON(w, http.StatusOK, map[string]any{"path": rel, "version": n})
+}
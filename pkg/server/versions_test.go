@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+func newVersioningServer(t *testing.T, maxVersions int) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := NewServer(ServerConfig{UploadDir: t.TempDir(), Versioning: true, MaxVersions: maxVersions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func listVersions(t *testing.T, ts *httptest.Server, rel string) []api.VersionInfo {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/api/versions?path=" + rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("versions %s status = %d, want 200", rel, resp.StatusCode)
+	}
+	var versions []api.VersionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		t.Fatal(err)
+	}
+	return versions
+}
+
+func TestOverwriteArchivesVersions(t *testing.T) {
+	_, ts := newVersioningServer(t, 0)
+	uploadFile(t, ts, "doc/report.txt", "one")
+	uploadFile(t, ts, "doc/report.txt", "two two")
+	uploadFile(t, ts, "doc/report.txt", "three")
+
+	versions := listVersions(t, ts, "doc/report.txt")
+	if len(versions) != 2 {
+		t.Fatalf("versions = %+v, want 2", versions)
+	}
+	if versions[0].Version != 1 || versions[0].Size != 3 {
+		t.Errorf("v1 = %+v, want version 1 of 3 bytes", versions[0])
+	}
+	if versions[1].Version != 2 || versions[1].Size != 7 {
+		t.Errorf("v2 = %+v, want version 2 of 7 bytes", versions[1])
+	}
+
+	resp, err := http.Get(ts.URL + "/api/versions?path=doc/report.txt&version=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "one" {
+		t.Errorf("version 1 content = %q", body)
+	}
+}
+
+func TestRestorePutsVersionBack(t *testing.T) {
+	s, ts := newVersioningServer(t, 0)
+	uploadFile(t, ts, "doc/report.txt", "one")
+	uploadFile(t, ts, "doc/report.txt", "two")
+
+	resp, err := http.Post(ts.URL+"/api/versions/restore?path=doc/report.txt&version=1", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("restore status = %d, want 200", resp.StatusCode)
+	}
+	data, err := os.ReadFile(filepath.Join(s.cfg.UploadDir, "doc", "report.txt"))
+	if err != nil || string(data) != "one" {
+		t.Errorf("restored content = %q, %v", data, err)
+	}
+	// The replaced content became a version itself, so the restore can
+	// be undone.
+	if versions := listVersions(t, ts, "doc/report.txt"); len(versions) != 2 {
+		t.Errorf("versions after restore = %+v, want 2", versions)
+	}
+
+	resp, err = http.Post(ts.URL+"/api/versions/restore?path=doc/report.txt&version=9", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("restore of missing version status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMaxVersionsPrunesOldest(t *testing.T) {
+	_, ts := newVersioningServer(t, 2)
+	for _, content := range []string{"a", "bb", "ccc", "dddd"} {
+		uploadFile(t, ts, "f.txt", content)
+	}
+	versions := listVersions(t, ts, "f.txt")
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 3 {
+		t.Errorf("versions = %+v, want v2 and v3", versions)
+	}
+}
+
+func TestVersioningOffKeepsNothing(t *testing.T) {
+	_, ts := newTestServer(t)
+	uploadFile(t, ts, "f.txt", "one")
+	uploadFile(t, ts, "f.txt", "two")
+	if versions := listVersions(t, ts, "f.txt"); len(versions) != 0 {
+		t.Errorf("versions = %+v, want none", versions)
+	}
+}
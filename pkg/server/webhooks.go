@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/milktea736/upload-http/internal/utils"
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the delivery
+// body, computed with the endpoint's configured secret.
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookEventHeader names the event type of a delivery, so receivers
+// can route without parsing the body.
+const WebhookEventHeader = "X-Webhook-Event"
+
+// webhookNotifier delivers events to the configured endpoints from a
+// background goroutine with retries and backoff, so a slow or down
+// receiver never stalls the request that caused the event.
+type webhookNotifier struct {
+	logger *utils.Logger
+	hooks  []WebhookConfig
+	ch     chan api.WebhookEvent
+	client *http.Client
+
+	// retries and backoff are fixed for deliveries; overridable in
+	// tests to keep them fast.
+	retries int
+	backoff time.Duration
+}
+
+// newWebhookNotifier returns a running notifier, or nil when no
+// webhooks are configured.
+func newWebhookNotifier(hooks []WebhookConfig, logger *utils.Logger) *webhookNotifier {
+	if len(hooks) == 0 {
+		return nil
+	}
+	n := &webhookNotifier{
+		logger:  logger,
+		hooks:   hooks,
+		ch:      make(chan api.WebhookEvent, 256),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retries: 3,
+		backoff: time.Second,
+	}
+	go n.run()
+	return n
+}
+
+// notify queues ev for delivery without blocking; when the queue is
+// full the event is dropped with a log line, trading completeness for
+// never stalling a transfer.
+func (n *webhookNotifier) notify(ev api.WebhookEvent) {
+	select {
+	case n.ch <- ev:
+	default:
+		n.logger.Errorf("webhook queue full, dropping %s event for %s", ev.Event, ev.Path)
+	}
+}
+
+func (n *webhookNotifier) run() {
+	for ev := range n.ch {
+		n.deliver(ev)
+	}
+}
+
+// deliver posts ev to every endpoint whose filter admits it, retrying
+// each with doubling backoff before giving up.
+func (n *webhookNotifier) deliver(ev api.WebhookEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		n.logger.Errorf("encode webhook event: %v", err)
+		return
+	}
+	for _, h := range n.hooks {
+		if !h.wants(ev.Event) {
+			continue
+		}
+		var lastErr error
+		for attempt, wait := 0, n.backoff; attempt <= n.retries; attempt, wait = attempt+1, wait*2 {
+			if attempt > 0 {
+				time.Sleep(wait)
+			}
+			if lastErr = n.post(h, ev.Event, body); lastErr == nil {
+				break
+			}
+		}
+		if lastErr != nil {
+			n.logger.Errorf("webhook %s: %s event not delivered: %v", h.URL, ev.Event, lastErr)
+		}
+	}
+}
+
+func (n *webhookNotifier) post(h WebhookConfig, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return nil
+}
+
+// wants reports whether the endpoint's event filter admits event; an
+// empty filter admits everything.
+func (h WebhookConfig) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyHook emits an event to the configured webhooks and exec hooks,
+// and to connected /api/events listeners.
+func (s *Server) notifyHook(event, path string, size int64, errText string) {
+	ev := api.WebhookEvent{
+		Event: event,
+		Path:  path,
+		Size:  size,
+		Error: errText,
+		Time:  time.Now().UTC(),
+	}
+	if s.webhooks != nil {
+		s.webhooks.notify(ev)
+	}
+	if s.execHooks != nil {
+		s.execHooks.notify(ev)
+	}
+	s.events.broadcast(ev)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/milktea736/upload-http/pkg/api"
+)
+
+// delivery is one webhook POST as the receiver saw it.
+type delivery struct {
+	event     api.WebhookEvent
+	header    string
+	signature string
+	body      []byte
+}
+
+// newWebhookReceiver returns a test endpoint and the channel its
+// deliveries arrive on.
+func newWebhookReceiver(t *testing.T) (*httptest.Server, chan delivery) {
+	t.Helper()
+	ch := make(chan delivery, 16)
+	recv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var ev api.WebhookEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			t.Errorf("bad webhook body %q: %v", body, err)
+		}
+		ch <- delivery{
+			event:     ev,
+			header:    r.Header.Get(WebhookEventHeader),
+			signature: r.Header.Get(WebhookSignatureHeader),
+			body:      body,
+		}
+	}))
+	t.Cleanup(recv.Close)
+	return recv, ch
+}
+
+func nextDelivery(t *testing.T, ch chan delivery) delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook delivery arrived")
+		return delivery{}
+	}
+}
+
+func TestWebhooksDeliverSignedEvents(t *testing.T) {
+	recv, ch := newWebhookReceiver(t)
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Webhooks:  []WebhookConfig{{URL: recv.URL, Secret: "s3cret"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "hook/f.txt", "notify me")
+	d := nextDelivery(t, ch)
+	if d.event.Event != api.ChangeCreate || d.event.Path != "hook/f.txt" || d.event.Size != 9 {
+		t.Errorf("upload event = %+v", d.event)
+	}
+	if d.header != api.ChangeCreate {
+		t.Errorf("event header = %q", d.header)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(d.body)
+	if want := hex.EncodeToString(mac.Sum(nil)); d.signature != want {
+		t.Errorf("signature = %q, want %q", d.signature, want)
+	}
+
+	resp, err := http.Get(ts.URL + "/download/hook/f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	d = nextDelivery(t, ch)
+	if d.event.Event != "download" || d.event.Path != "hook/f.txt" {
+		t.Errorf("download event = %+v", d.event)
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	recv, ch := newWebhookReceiver(t)
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Webhooks:  []WebhookConfig{{URL: recv.URL, Events: []string{api.ChangeDelete}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "only-deletes.txt", "x")
+	deletePath(t, ts, "path=only-deletes.txt")
+
+	// The create must have been filtered out, so the first delivery is
+	// the delete.
+	d := nextDelivery(t, ch)
+	if d.event.Event != api.ChangeDelete || d.event.Path != "only-deletes.txt" {
+		t.Errorf("first delivery = %+v, want the delete", d.event)
+	}
+}
+
+func TestWebhookRetriesFailedDeliveries(t *testing.T) {
+	var calls atomic.Int32
+	ch := make(chan delivery, 1)
+	recv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var ev api.WebhookEvent
+		json.Unmarshal(body, &ev)
+		ch <- delivery{event: ev}
+	}))
+	defer recv.Close()
+
+	s, err := NewServer(ServerConfig{
+		UploadDir: t.TempDir(),
+		Webhooks:  []WebhookConfig{{URL: recv.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.webhooks.backoff = time.Millisecond
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	uploadFile(t, ts, "retry.txt", "eventually")
+	d := nextDelivery(t, ch)
+	if d.event.Event != api.ChangeCreate || d.event.Path != "retry.txt" {
+		t.Errorf("delivered event = %+v", d.event)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("receiver called %d times, want 3", got)
+	}
+}